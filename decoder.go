@@ -0,0 +1,602 @@
+package astjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/wundergraph/astjson/fastfloat"
+)
+
+// TokenKind represents the kind of a JSON token.
+type TokenKind int
+
+const (
+	// TokenBeginObject is the opening '{' of JSON object.
+	TokenBeginObject TokenKind = 1
+
+	// TokenEndObject is the closing '}' of JSON object.
+	TokenEndObject TokenKind = 2
+
+	// TokenBeginArray is the opening '[' of JSON array.
+	TokenBeginArray TokenKind = 3
+
+	// TokenEndArray is the closing ']' of JSON array.
+	TokenEndArray TokenKind = 4
+
+	// TokenKey is JSON object key.
+	TokenKey TokenKind = 5
+
+	// TokenString is JSON string.
+	TokenString TokenKind = 6
+
+	// TokenNumber is JSON number.
+	TokenNumber TokenKind = 7
+
+	// TokenBool is JSON true or false.
+	TokenBool TokenKind = 8
+
+	// TokenNull is JSON null.
+	TokenNull TokenKind = 9
+)
+
+// String returns string representation of k.
+func (k TokenKind) String() string {
+	switch k {
+	case TokenBeginObject:
+		return "begin object"
+	case TokenEndObject:
+		return "end object"
+	case TokenBeginArray:
+		return "begin array"
+	case TokenEndArray:
+		return "end array"
+	case TokenKey:
+		return "key"
+	case TokenString:
+		return "string"
+	case TokenNumber:
+		return "number"
+	case TokenBool:
+		return "bool"
+	case TokenNull:
+		return "null"
+	default:
+		panic(fmt.Errorf("BUG: unknown TokenKind: %d", k))
+	}
+}
+
+// Token is a single JSON token returned by Decoder.
+//
+// The token is valid until the next call to Decoder.Next or Decoder.Skip.
+type Token struct {
+	kind TokenKind
+
+	// s contains the raw token contents. Strings and keys
+	// are stored without quotes and escape sequences are left as is.
+	s string
+}
+
+// Kind returns the kind of the t.
+func (t Token) Kind() TokenKind {
+	return t.kind
+}
+
+// Raw returns the raw contents of the t.
+//
+// Strings and keys are returned without quotes. Escape sequences
+// are left as is. Use StringBytes for obtaining the unescaped string.
+func (t Token) Raw() []byte {
+	return s2b(t.s)
+}
+
+// StringBytes returns the unescaped string for TokenString and TokenKey.
+//
+// nil is returned for other token kinds.
+func (t Token) StringBytes() []byte {
+	if t.kind != TokenString && t.kind != TokenKey {
+		return nil
+	}
+	return s2b(unescapeStringBestEffort(nil, t.s))
+}
+
+// Bool returns the value of TokenBool.
+//
+// false is returned for other token kinds.
+func (t Token) Bool() bool {
+	return t.kind == TokenBool && t.s == "true"
+}
+
+// Float64 returns the value of TokenNumber.
+func (t Token) Float64() (float64, error) {
+	if t.kind != TokenNumber {
+		return 0, fmt.Errorf("token isn't a number; it is %s", t.kind)
+	}
+	return fastfloat.Parse(t.s)
+}
+
+// Int64 returns the value of TokenNumber.
+func (t Token) Int64() (int64, error) {
+	if t.kind != TokenNumber {
+		return 0, fmt.Errorf("token isn't a number; it is %s", t.kind)
+	}
+	return fastfloat.ParseInt64(t.s)
+}
+
+// decoderState is the syntactic element expected next by Decoder.
+type decoderState uint8
+
+const (
+	// stateValue expects a value.
+	stateValue decoderState = iota
+
+	// stateFirstElem expects the first array value or ']'.
+	stateFirstElem
+
+	// stateFirstKey expects the first object key or '}'.
+	stateFirstKey
+
+	// stateKey expects an object key.
+	stateKey
+
+	// stateColon expects ':' after object key.
+	stateColon
+
+	// stateComma expects ',' or the end of the current object or array.
+	stateComma
+)
+
+// errShortInput is returned by Decoder.next when the current token
+// isn't complete and more input may arrive.
+var errShortInput = errors.New("short input")
+
+// Decoder reads JSON tokens one by one without building Value trees.
+//
+// Decoder may read a series of JSON values delimited by whitespace.
+// Values are validated in the same way as Parser does.
+//
+// Decoder may be re-used for subsequent decoding.
+// The zero Decoder limits the nesting depth to MaxDepth.
+// Use NewDecoder for other limits.
+//
+// Decoder cannot be used from concurrent goroutines.
+type Decoder struct {
+	// b contains the input passed to Init or the bytes read from r.
+	b []byte
+
+	// s points to the unconsumed input.
+	s string
+
+	// eof is set when no more input will be available after s.
+	eof bool
+
+	// r is the reader passed to InitReader.
+	r io.Reader
+
+	// stack contains the opening chars of the enclosing objects and arrays.
+	stack []byte
+
+	// frames contains the positions in the enclosing objects and arrays
+	// for ParseError.Path. keys contains their last raw keys.
+	frames []decoderFrame
+	keys   []byte
+
+	// off is the offset in the input of the start of b.
+	// line is the number of newlines before b, and col is the number
+	// of bytes between the last of them and b.
+	off  int
+	line int
+	col  int
+
+	// l contains the limits passed to NewDecoder.
+	l limits
+
+	state decoderState
+
+	// last is the kind of the last returned token.
+	last TokenKind
+
+	// pending is the length of the prefix of s known not to contain
+	// the closing quote of the incomplete string at the start of s.
+	//
+	// It prevents rescanning long strings, which span many chunks,
+	// from the beginning on every chunk.
+	pending int
+
+	// err contains the last error.
+	err error
+}
+
+// decoderFrame is the position in an enclosing object or array.
+type decoderFrame struct {
+	// n is the number of the parsed array items.
+	n int
+
+	// k is the start of the last object key in Decoder.keys.
+	k int
+}
+
+// NewDecoder returns a Decoder, which enforces the given opts.
+//
+// Only MaxDepth, MaxStringLength, MaxNumberLength and Strict are enforced.
+func NewDecoder(opts ParserOptions) *Decoder {
+	return &Decoder{
+		l: limits{
+			opts: opts,
+		},
+	}
+}
+
+// Init initializes d with the given s.
+//
+// s may contain multiple JSON values, which may be delimited by whitespace.
+func (d *Decoder) Init(s string) {
+	d.reset()
+	d.b = append(d.b[:0], s...)
+	d.s = b2s(d.b)
+	d.eof = true
+}
+
+// InitBytes initializes d with the given b.
+//
+// b may contain multiple JSON values, which may be delimited by whitespace.
+func (d *Decoder) InitBytes(b []byte) {
+	d.Init(b2s(b))
+}
+
+// InitReader initializes d with the given r.
+//
+// r may contain multiple JSON values, which may be delimited by whitespace.
+// r is read in fixed-size chunks, and only the bytes of the current token
+// are kept in memory.
+func (d *Decoder) InitReader(r io.Reader) {
+	d.reset()
+	d.b = d.b[:0]
+	d.r = r
+}
+
+func (d *Decoder) reset() {
+	d.s = ""
+	d.eof = false
+	d.r = nil
+	d.stack = d.stack[:0]
+	d.frames = d.frames[:0]
+	d.keys = d.keys[:0]
+	d.off = 0
+	d.line = 0
+	d.col = 0
+	d.state = stateValue
+	d.last = 0
+	d.pending = 0
+	d.err = nil
+}
+
+// Depth returns the number of objects and arrays enclosing the next token.
+func (d *Decoder) Depth() int {
+	return len(d.stack)
+}
+
+// Next returns the next token.
+//
+// io.EOF is returned when all the values have been read.
+// The returned token is valid until the next call to Next or Skip.
+func (d *Decoder) Next() (Token, error) {
+	if d.err != nil {
+		return Token{}, d.err
+	}
+	for {
+		t, err := d.next()
+		if err == errShortInput && d.r != nil {
+			d.fill()
+			continue
+		}
+		if err != nil {
+			d.err = err
+			return Token{}, err
+		}
+		d.last = t.kind
+		return t, nil
+	}
+}
+
+// Skip skips the current subtree.
+//
+// If the last token returned by Next begins an object or array, Skip skips
+// the rest of it including the matching end token. Otherwise Skip skips
+// the next value, so calling Skip after TokenKey skips the key's value.
+// If the next token is TokenKey, both the key and its value are skipped.
+// If there is no next value in the current object or array, Skip
+// consumes its end token.
+func (d *Decoder) Skip() error {
+	depth := 0
+	if d.last == TokenBeginObject || d.last == TokenBeginArray {
+		depth = 1
+	}
+	for {
+		t, err := d.Next()
+		if err != nil {
+			return err
+		}
+		switch t.kind {
+		case TokenBeginObject, TokenBeginArray:
+			depth++
+		case TokenEndObject, TokenEndArray:
+			depth--
+		}
+		if depth <= 0 && t.kind != TokenKey {
+			return nil
+		}
+	}
+}
+
+// fill reads the next chunk from d.r.
+func (d *Decoder) fill() {
+	d.discard()
+	b, err := readChunk(d.r, d.b, d.s)
+	d.b = b
+	d.s = b2s(b)
+	if err == io.EOF {
+		d.eof = true
+		return
+	}
+	if err != nil {
+		// Report the read error instead of a syntax error on the next call.
+		d.err = err
+		d.eof = true
+	}
+}
+
+// discard accounts for the consumed input at the start of d.b,
+// which is about to be dropped.
+func (d *Decoder) discard() {
+	consumed := d.b[:len(d.b)-len(d.s)]
+	d.off += len(consumed)
+	if n := bytes.LastIndexByte(consumed, '\n'); n >= 0 {
+		d.line += bytes.Count(consumed, []byte{'\n'})
+		d.col = len(consumed) - n - 1
+	} else {
+		d.col += len(consumed)
+	}
+}
+
+func (d *Decoder) next() (Token, error) {
+	t, err := d.nextToken()
+	if err != errShortInput {
+		d.pending = 0
+	}
+	return t, err
+}
+
+func (d *Decoder) nextToken() (Token, error) {
+	if d.err != nil {
+		return Token{}, d.err
+	}
+	for {
+		d.s = skipWS(d.s)
+		if len(d.s) == 0 {
+			if !d.eof {
+				return Token{}, errShortInput
+			}
+			if len(d.stack) == 0 && d.state == stateValue {
+				return Token{}, io.EOF
+			}
+			// The missing value is a member of the innermost object or array
+			// only after ':' or ','.
+			return Token{}, d.syntaxErrorAt(fmt.Errorf("unexpected end of JSON"), d.state == stateValue)
+		}
+
+		c := d.s[0]
+		switch d.state {
+		case stateColon:
+			if c != ':' {
+				return Token{}, d.syntaxError(fmt.Errorf("missing ':' after object key"))
+			}
+			d.s = d.s[1:]
+			d.state = stateValue
+			continue
+		case stateComma:
+			top := d.stack[len(d.stack)-1]
+			if c == ',' {
+				d.s = d.s[1:]
+				if top == '{' {
+					d.state = stateKey
+				} else {
+					d.state = stateValue
+				}
+				continue
+			}
+			if top == '{' {
+				if c == '}' {
+					return d.end(TokenEndObject), nil
+				}
+				return Token{}, d.syntaxError(fmt.Errorf("missing ',' after object value"))
+			}
+			if c == ']' {
+				return d.end(TokenEndArray), nil
+			}
+			return Token{}, d.syntaxError(fmt.Errorf("missing ',' after array value"))
+		case stateFirstKey, stateKey:
+			if c == '}' && d.state == stateFirstKey {
+				return d.end(TokenEndObject), nil
+			}
+			if c != '"' {
+				return Token{}, d.syntaxError(fmt.Errorf(`cannot find opening '"" for object key`))
+			}
+			if d.stringIncomplete() {
+				return Token{}, errShortInput
+			}
+			k, tail, err := parseRawKey(d.s[1:])
+			if err != nil {
+				return Token{}, d.incomplete(tail, fmt.Errorf("cannot parse object key: %s", err))
+			}
+			if err := d.l.checkString(k); err != nil {
+				return Token{}, d.syntaxError(fmt.Errorf("cannot parse object key: %w", err))
+			}
+			f := &d.frames[len(d.frames)-1]
+			d.keys = append(d.keys[:f.k], k...)
+			d.s = tail
+			d.state = stateColon
+			return Token{kind: TokenKey, s: k}, nil
+		case stateFirstElem:
+			if c == ']' {
+				return d.end(TokenEndArray), nil
+			}
+		}
+		return d.value(c)
+	}
+}
+
+func (d *Decoder) value(c byte) (Token, error) {
+	if len(d.stack) >= d.l.maxDepth() {
+		return Token{}, d.syntaxError(limitError(ErrMaxDepth, d.l.maxDepth()))
+	}
+	switch c {
+	case '{', '[':
+		d.stack = append(d.stack, c)
+		d.frames = append(d.frames, decoderFrame{k: len(d.keys)})
+		d.s = d.s[1:]
+		if c == '{' {
+			d.state = stateFirstKey
+			return Token{kind: TokenBeginObject}, nil
+		}
+		d.state = stateFirstElem
+		return Token{kind: TokenBeginArray}, nil
+	case '"':
+		if d.stringIncomplete() {
+			return Token{}, errShortInput
+		}
+		ss, tail, err := parseRawString(d.s[1:])
+		if err != nil {
+			return Token{}, d.incomplete(tail, fmt.Errorf("cannot parse string: %s", err))
+		}
+		if err := d.l.checkString(ss); err != nil {
+			return Token{}, d.syntaxError(fmt.Errorf("cannot parse string: %w", err))
+		}
+		d.s = tail
+		d.afterValue()
+		return Token{kind: TokenString, s: ss}, nil
+	case 't', 'f', 'n':
+		if len(d.s) < len("false") && !d.eof {
+			// The literal may be incomplete.
+			return Token{}, errShortInput
+		}
+		for _, lit := range [...]string{"true", "false", "null"} {
+			if len(d.s) >= len(lit) && d.s[:len(lit)] == lit {
+				d.s = d.s[len(lit):]
+				d.afterValue()
+				if lit == "null" {
+					return Token{kind: TokenNull}, nil
+				}
+				return Token{kind: TokenBool, s: lit}, nil
+			}
+		}
+		if len(d.s) >= 3 && strings.EqualFold(d.s[:3], "nan") {
+			ns := d.s[:3]
+			if err := d.l.checkNumber(ns); err != nil {
+				return Token{}, d.syntaxError(fmt.Errorf("cannot parse number: %w", err))
+			}
+			d.s = d.s[3:]
+			d.afterValue()
+			return Token{kind: TokenNumber, s: ns}, nil
+		}
+		return Token{}, d.syntaxError(fmt.Errorf("unexpected value found: %q", startEndString(d.s)))
+	default:
+		ns, tail, err := parseRawNumber(d.s)
+		if (len(tail) == 0 || err != nil && len(d.s) < len("-Inf")) && !d.eof {
+			// The number may continue in the next chunk.
+			return Token{}, errShortInput
+		}
+		if err != nil {
+			return Token{}, d.syntaxError(fmt.Errorf("cannot parse number: %s", err))
+		}
+		if err := d.l.checkNumber(ns); err != nil {
+			return Token{}, d.syntaxError(fmt.Errorf("cannot parse number: %w", err))
+		}
+		d.s = tail
+		d.afterValue()
+		return Token{kind: TokenNumber, s: ns}, nil
+	}
+}
+
+// stringIncomplete returns true if the string at the start of d.s
+// cannot be complete, since it has no closing quote yet.
+func (d *Decoder) stringIncomplete() bool {
+	if d.eof {
+		return false
+	}
+	if strings.IndexByte(d.s[max(d.pending, 1):], '"') >= 0 {
+		return false
+	}
+	d.pending = len(d.s)
+	return true
+}
+
+// end pops the current object or array.
+func (d *Decoder) end(kind TokenKind) Token {
+	d.s = d.s[1:]
+	d.stack = d.stack[:len(d.stack)-1]
+	d.keys = d.keys[:d.frames[len(d.frames)-1].k]
+	d.frames = d.frames[:len(d.frames)-1]
+	d.afterValue()
+	return Token{kind: kind}
+}
+
+func (d *Decoder) afterValue() {
+	if len(d.stack) == 0 {
+		d.state = stateValue
+		return
+	}
+	d.frames[len(d.frames)-1].n++
+	d.state = stateComma
+}
+
+// incomplete returns errShortInput if more input may arrive.
+// Otherwise err is returned for the failure point at the start of tail.
+func (d *Decoder) incomplete(tail string, err error) error {
+	if !d.eof {
+		return errShortInput
+	}
+	d.s = tail
+	return d.syntaxError(err)
+}
+
+// syntaxError returns ParseError for the err, which occurred at the start of d.s.
+func (d *Decoder) syntaxError(err error) error {
+	return d.syntaxErrorAt(err, d.state == stateValue || d.state == stateFirstElem)
+}
+
+// syntaxErrorAt is like syntaxError. inValue must be set if the failure point
+// is inside a member of the innermost object or array.
+func (d *Decoder) syntaxErrorAt(err error, inValue bool) error {
+	consumed := b2s(d.b[:len(d.b)-len(d.s)])
+	line, column := lineColumn(consumed, len(consumed))
+	if line == 1 {
+		column += d.col
+	}
+	return newParseErrorAt(d.off+len(consumed), d.line+line, column, d.s, err, d.errorPath(inValue))
+}
+
+// errorPath returns the path to the start of d.s in reverse order.
+//
+// The innermost object or array is included only if inValue is set.
+func (d *Decoder) errorPath(inValue bool) errorPath {
+	var path errorPath
+	for i := len(d.frames) - 1; i >= 0; i-- {
+		if i == len(d.frames)-1 && !inValue {
+			continue
+		}
+		f := d.frames[i]
+		if d.stack[i] == '[' {
+			path.pushIndex(f.n)
+			continue
+		}
+		end := len(d.keys)
+		if i+1 < len(d.frames) {
+			end = d.frames[i+1].k
+		}
+		path.pushRawKey(b2s(d.keys[f.k:end]))
+	}
+	return path
+}
@@ -0,0 +1,227 @@
+package astjson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func decodeTokens(d *Decoder) (string, error) {
+	var sb strings.Builder
+	for {
+		t, err := d.Next()
+		if err == io.EOF {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return sb.String(), err
+		}
+		switch t.Kind() {
+		case TokenBeginObject:
+			sb.WriteString("{")
+		case TokenEndObject:
+			sb.WriteString("}")
+		case TokenBeginArray:
+			sb.WriteString("[")
+		case TokenEndArray:
+			sb.WriteString("]")
+		case TokenKey:
+			fmt.Fprintf(&sb, "k:%s ", t.StringBytes())
+		case TokenString:
+			fmt.Fprintf(&sb, "s:%s ", t.StringBytes())
+		case TokenNumber:
+			fmt.Fprintf(&sb, "n:%s ", t.Raw())
+		case TokenBool:
+			fmt.Fprintf(&sb, "b:%v ", t.Bool())
+		case TokenNull:
+			sb.WriteString("null ")
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	const input = ` {"foo": [1, -2.5e3, "bar\n"], "baz" : {"x": true, "y": false, "z": null}, "e": [], "o": {}} 42 "tail" `
+	const expected = `{k:foo [n:1 n:-2.5e3 s:bar` + "\n" + ` ]k:baz {k:x b:true k:y b:false k:z null }k:e []k:o {}}n:42 s:tail `
+
+	t.Run("bytes", func(t *testing.T) {
+		var d Decoder
+		d.InitBytes([]byte(input))
+		s, err := decodeTokens(&d)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if s != expected {
+			t.Fatalf("unexpected tokens; got %q; want %q", s, expected)
+		}
+	})
+
+	t.Run("reader", func(t *testing.T) {
+		var d Decoder
+		d.InitReader(iotest.OneByteReader(strings.NewReader(input)))
+		s, err := decodeTokens(&d)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if s != expected {
+			t.Fatalf("unexpected tokens; got %q; want %q", s, expected)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		f := func(s string) {
+			t.Helper()
+
+			var d Decoder
+			d.Init(s)
+			if _, err := decodeTokens(&d); err == nil {
+				t.Fatalf("expecting non-nil error when decoding %q", s)
+			}
+			d.InitReader(iotest.OneByteReader(strings.NewReader(s)))
+			_, err := decodeTokens(&d)
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expecting ParseError when decoding %q from reader; got %v", s, err)
+			}
+		}
+
+		f(`{`)
+		f(`[1,`)
+		f(`[1 2]`)
+		f(`{"foo" 1}`)
+		f(`{"foo":1,}`)
+		f(`{foo:1}`)
+		f(`[1}`)
+		f(`{"a":1]`)
+		f(`"unclosed`)
+		f(`tru`)
+		f(`nul`)
+		f(`[xyz]`)
+		f(`}`)
+		f(strings.Repeat("[", MaxDepth+1))
+	})
+
+	t.Run("error positions", func(t *testing.T) {
+		f := func(s string) {
+			t.Helper()
+			_, err := Parse(s)
+			var expected *ParseError
+			if !errors.As(err, &expected) {
+				t.Fatalf("expecting ParseError when parsing %q; got %v", s, err)
+			}
+
+			var d Decoder
+			for _, init := range []func(){
+				func() { d.Init(s) },
+				func() { d.InitReader(iotest.OneByteReader(strings.NewReader(s))) },
+			} {
+				init()
+				_, err := decodeTokens(&d)
+				var pe *ParseError
+				if !errors.As(err, &pe) {
+					t.Fatalf("expecting ParseError when decoding %q; got %v", s, err)
+				}
+				if pe.Offset != expected.Offset || pe.Line != expected.Line || pe.Column != expected.Column {
+					t.Fatalf("unexpected position when decoding %q; got %d (%d:%d); want %d (%d:%d)",
+						s, pe.Offset, pe.Line, pe.Column, expected.Offset, expected.Line, expected.Column)
+				}
+				if ps, eps := pe.PathString(), expected.PathString(); ps != eps {
+					t.Fatalf("unexpected path when decoding %q; got %s; want %s", s, ps, eps)
+				}
+				if pe.Kind != expected.Kind {
+					t.Fatalf("unexpected kind when decoding %q; got %s; want %s", s, pe.Kind, expected.Kind)
+				}
+			}
+		}
+
+		f(`[`)
+		f(`[1,`)
+		f(`[1 2]`)
+		f("{\n  \"a\": [1, 2,\n    {\"b\": x}]}")
+		f("{\"a\": {\"b\": 1},\n \"c\\u0041\": [tru]}")
+		f(`{"a": 1 "b": 2}`)
+		f(`{"a" 1}`)
+		f(`{"a": [[], {}], "b": [1, 2, -]}`)
+		f("[\n\n  \"foo")
+		f(strings.Repeat("[", MaxDepth+1))
+	})
+
+	t.Run("options", func(t *testing.T) {
+		f := func(opts ParserOptions, s string, expectedErr error) {
+			t.Helper()
+			d := NewDecoder(opts)
+			d.Init(s)
+			_, err := decodeTokens(d)
+			if !errors.Is(err, expectedErr) {
+				t.Fatalf("unexpected error when decoding %q; got %v; want %v", s, err, expectedErr)
+			}
+		}
+
+		f(ParserOptions{MaxDepth: 2}, `[[1]]`, ErrMaxDepth)
+		f(ParserOptions{MaxDepth: 2}, `[[]] [1]`, nil)
+		f(ParserOptions{MaxDepth: MaxDepth + 10}, strings.Repeat("[", MaxDepth+5)+strings.Repeat("]", MaxDepth+5), nil)
+		f(ParserOptions{MaxStringLength: 2}, `{"abc": 1}`, ErrMaxStringLength)
+		f(ParserOptions{MaxStringLength: 2}, `["abc"]`, ErrMaxStringLength)
+		f(ParserOptions{MaxNumberLength: 2}, `[123]`, ErrMaxNumberLength)
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		var d Decoder
+		d.InitReader(io.MultiReader(strings.NewReader(`[1, 2`), iotest.ErrReader(iotest.ErrTimeout)))
+		_, err := decodeTokens(&d)
+		if !errors.Is(err, iotest.ErrTimeout) {
+			t.Fatalf("unexpected error; got %v; want %v", err, iotest.ErrTimeout)
+		}
+	})
+}
+
+func TestDecoderSkip(t *testing.T) {
+	var d Decoder
+	d.Init(`{"skip": {"a": [1, {"b": "]}"}]}, "keep": [1, 2], "rest": [3, 4]} 5`)
+
+	next := func(expected TokenKind) Token {
+		t.Helper()
+		tok, err := d.Next()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if tok.Kind() != expected {
+			t.Fatalf("unexpected token; got %s; want %s", tok.Kind(), expected)
+		}
+		return tok
+	}
+	skip := func() {
+		t.Helper()
+		if err := d.Skip(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	next(TokenBeginObject)
+	if k := next(TokenKey).StringBytes(); string(k) != "skip" {
+		t.Fatalf("unexpected key; got %q; want %q", k, "skip")
+	}
+	skip()
+	if k := next(TokenKey).StringBytes(); string(k) != "keep" {
+		t.Fatalf("unexpected key; got %q; want %q", k, "keep")
+	}
+	next(TokenBeginArray)
+	if d.Depth() != 2 {
+		t.Fatalf("unexpected depth; got %d; want %d", d.Depth(), 2)
+	}
+	skip()
+	if d.Depth() != 1 {
+		t.Fatalf("unexpected depth; got %d; want %d", d.Depth(), 1)
+	}
+	// Skips both the "rest" key and its value.
+	skip()
+	next(TokenEndObject)
+	if n, err := next(TokenNumber).Int64(); err != nil || n != 5 {
+		t.Fatalf("unexpected number; got %d, %v; want %d", n, err, 5)
+	}
+	if _, err := d.Next(); err != io.EOF {
+		t.Fatalf("unexpected error; got %v; want %v", err, io.EOF)
+	}
+}
@@ -0,0 +1,448 @@
+package astjson
+
+import (
+	"strings"
+
+	"github.com/wundergraph/go-arena"
+)
+
+// Document is a parsed JSON, JSONC or JSON5 document, which preserves
+// the original formatting when marshaled.
+//
+// Edit the document via Set, Del and SetArrayItem on the values obtained
+// from Root. MarshalTo copies the unchanged parts of the document verbatim
+// including whitespace, comments, the original spelling of numbers and strings
+// and trailing commas. Only the changed objects and arrays are re-serialized
+// member by member, so the comments attached to the remaining members
+// are preserved. Inserted members are indented like their siblings.
+// The comments preceding deleted members are deleted with them,
+// while the comments following the remaining members on the same line are kept.
+//
+// Document cannot be used from concurrent goroutines.
+type Document struct {
+	src  string
+	root *Value
+
+	// nodes contains the original positions of the parsed values.
+	nodes map[*Value]*docNode
+
+	// kvRefs and itemRefs map the parsed object members and array items
+	// to their original positions in the parent objects and arrays.
+	kvRefs   map[*kv]docRef
+	itemRefs map[*Value]docRef
+
+	// indent is the indentation unit inferred from src.
+	indent string
+}
+
+// docNode is the original position of a parsed value.
+type docNode struct {
+	sp Span
+
+	// members contains the original members of objects and items of arrays.
+	members []docMember
+}
+
+// docMember is the original position of an object member or an array item.
+type docMember struct {
+	// key is the span of the object key. It equals value for array items.
+	key   Span
+	value Span
+}
+
+// docRef is the original position of a member in the parent.
+type docRef struct {
+	parent *Value
+	i      int
+}
+
+// ParseDocument parses s containing JSON, JSONC or JSON5 into a Document.
+//
+// Values are allocated from a. The returned Document references s.
+func ParseDocument(a arena.Arena, s string) (*Document, error) {
+	p := NewParser(ParserOptions{
+		JSON5: true,
+		Spans: true,
+	})
+	v, err := p.ParseWithArena(a, s)
+	if err != nil {
+		return nil, err
+	}
+	d := &Document{
+		src:      s,
+		root:     v,
+		nodes:    make(map[*Value]*docNode),
+		kvRefs:   make(map[*kv]docRef),
+		itemRefs: make(map[*Value]docRef),
+		indent:   inferIndent(s),
+	}
+	d.index(v)
+	return d, nil
+}
+
+// ParseDocumentBytes parses b containing JSON, JSONC or JSON5 into a Document.
+//
+// See ParseDocument for details.
+func ParseDocumentBytes(a arena.Arena, b []byte) (*Document, error) {
+	return ParseDocument(a, b2s(b))
+}
+
+// Root returns the top-level value of the d.
+func (d *Document) Root() *Value {
+	return d.root
+}
+
+// MarshalTo appends the d with the original formatting to dst and returns the result.
+func (d *Document) MarshalTo(dst []byte) []byte {
+	n := d.nodes[d.root]
+	dst = append(dst, d.src[:n.sp.Start]...)
+	dst = d.appendValue(dst, d.root)
+	return append(dst, d.src[n.sp.End:]...)
+}
+
+// String returns the d with the original formatting.
+func (d *Document) String() string {
+	b := d.MarshalTo(nil)
+	// It is safe converting b to string without allocation, since b is no longer
+	// reachable after this line.
+	return b2s(b)
+}
+
+// index records the original positions of v and its nested values.
+func (d *Document) index(v *Value) {
+	n := &docNode{
+		sp: *v.x.ext.sp,
+	}
+	d.nodes[v] = n
+	switch v.t {
+	case TypeObject:
+		n.members = make([]docMember, len(v.x.o.kvs))
+		for i, kv := range v.x.o.kvs {
+			n.members[i] = docMember{
+				key:   *kv.v.x.ext.ksp,
+				value: *kv.v.x.ext.sp,
+			}
+			d.kvRefs[kv] = docRef{parent: v, i: i}
+			d.index(kv.v)
+		}
+	case TypeArray:
+		n.members = make([]docMember, len(v.x.a))
+		for i, item := range v.x.a {
+			n.members[i] = docMember{
+				key:   *item.x.ext.sp,
+				value: *item.x.ext.sp,
+			}
+			d.itemRefs[item] = docRef{parent: v, i: i}
+			d.index(item)
+		}
+	}
+}
+
+// appendValue appends v to dst.
+//
+// The values parsed by ParseDocument are copied from d.src if possible.
+func (d *Document) appendValue(dst []byte, v *Value) []byte {
+	n, ok := d.nodes[v]
+	if !ok {
+		return d.appendNewValue(dst, v)
+	}
+	switch v.t {
+	case TypeObject, TypeArray:
+		return d.appendContainer(dst, v, n)
+	default:
+		return append(dst, d.src[n.sp.Start:n.sp.End]...)
+	}
+}
+
+// appendNewValue appends v, which hasn't been parsed by ParseDocument, to dst.
+//
+// v is marshaled like Value.MarshalTo does, but the nested values
+// parsed by ParseDocument keep their original formatting.
+func (d *Document) appendNewValue(dst []byte, v *Value) []byte {
+	if v.lazy() {
+		return v.MarshalTo(dst)
+	}
+	switch v.t {
+	case TypeObject:
+		dst = append(dst, '{')
+		for i, kv := range v.x.o.kvs {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendKey(dst, kv)
+			dst = append(dst, ':')
+			dst = d.appendValue(dst, kv.v)
+		}
+		return append(dst, '}')
+	case TypeArray:
+		dst = append(dst, '[')
+		for i, item := range v.x.a {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = d.appendValue(dst, item)
+		}
+		return append(dst, ']')
+	default:
+		return v.MarshalTo(dst)
+	}
+}
+
+// appendContainer appends the object or array v parsed by ParseDocument to dst.
+//
+// Every member is preceded by its leading whitespace and comments and followed
+// by the comma and the comments up to the end of its line in the original.
+func (d *Document) appendContainer(dst []byte, v *Value, n *docNode) []byte {
+	open, end := n.sp.Start, n.sp.End-1
+	multiline := strings.IndexByte(d.src[open:end], '\n') >= 0
+
+	count := len(v.x.a)
+	if v.t == TypeObject {
+		count = len(v.x.o.kvs)
+	}
+	dst = append(dst, d.src[open])
+
+	// last is the gap written after the last member.
+	var last string
+	for j := 0; j < count; j++ {
+		i := d.originalIndex(v, j)
+		if i < 0 {
+			// The member has been inserted.
+			dst = append(dst, d.newLead(n, j, multiline)...)
+			dst = d.appendMember(dst, v, j, n, i)
+			if j < count-1 {
+				dst = append(dst, ',')
+			}
+			last = ""
+			continue
+		}
+
+		dst = append(dst, d.lead(n, i)...)
+		dst = d.appendMember(dst, v, j, n, i)
+		tail, comma, _ := splitGap(d.gapAfter(n, i))
+		switch {
+		case j < count-1 && comma < 0:
+			// The member has been the last one.
+			dst = append(dst, ',')
+			dst = append(dst, tail...)
+		case j == count-1 && comma >= 0 && i < len(n.members)-1:
+			// The following members have been deleted.
+			dst = append(dst, tail[:comma]...)
+			dst = append(dst, tail[comma+1:]...)
+		default:
+			dst = append(dst, tail...)
+		}
+		last = tail
+	}
+
+	switch {
+	case count == 0 && len(n.members) > 0:
+		// All the members have been deleted.
+	case len(n.members) > 0:
+		_, _, closing := splitGap(d.gapAfter(n, len(n.members)-1))
+		if len(closing) == 0 && endsInLineComment(last) {
+			// The line comment after the last member would comment out
+			// the closing bracket, since the following members have been deleted.
+			dst = append(dst, '\n')
+			dst = append(dst, lineIndent(d.src, open)...)
+		}
+		dst = append(dst, closing...)
+	case count == 0:
+		dst = append(dst, d.src[open+1:end]...)
+	case strings.IndexByte(d.src, '\n') >= 0:
+		dst = append(dst, '\n')
+		dst = append(dst, lineIndent(d.src, open)...)
+	}
+	return append(dst, d.src[end])
+}
+
+// appendMember appends the j-th member of the object or array v to dst.
+//
+// i is the original index of the member or -1 if the member has been inserted.
+func (d *Document) appendMember(dst []byte, v *Value, j int, n *docNode, i int) []byte {
+	if v.t == TypeArray {
+		return d.appendValue(dst, v.x.a[j])
+	}
+	kv := v.x.o.kvs[j]
+	switch {
+	case i >= 0:
+		// The original key followed by the original colon.
+		m := n.members[i]
+		dst = append(dst, d.src[m.key.Start:m.value.Start]...)
+	case len(n.members) > 0:
+		// The colon is formatted like the colon of the first member.
+		dst = appendKey(dst, kv)
+		m := n.members[0]
+		dst = append(dst, d.src[m.key.End:m.value.Start]...)
+	default:
+		dst = appendKey(dst, kv)
+		dst = append(dst, ": "...)
+	}
+	return d.appendValue(dst, kv.v)
+}
+
+// originalIndex returns the original index of the j-th member of v.
+//
+// -1 is returned if the member has been inserted.
+func (d *Document) originalIndex(v *Value, j int) int {
+	var ref docRef
+	var ok bool
+	if v.t == TypeObject {
+		ref, ok = d.kvRefs[v.x.o.kvs[j]]
+	} else {
+		ref, ok = d.itemRefs[v.x.a[j]]
+	}
+	if !ok || ref.parent != v {
+		return -1
+	}
+	return ref.i
+}
+
+// gapAfter returns the original text between the end of the i-th member
+// and the start of the next member or the closing bracket.
+func (d *Document) gapAfter(n *docNode, i int) string {
+	end := n.sp.End - 1
+	if i+1 < len(n.members) {
+		end = n.members[i+1].key.Start
+	}
+	return d.src[n.members[i].value.End:end]
+}
+
+// lead returns the original whitespace and comments preceding the i-th member.
+func (d *Document) lead(n *docNode, i int) string {
+	if i == 0 {
+		return d.src[n.sp.Start+1 : n.members[0].key.Start]
+	}
+	_, _, lead := splitGap(d.gapAfter(n, i-1))
+	return lead
+}
+
+// newLead returns the whitespace preceding the member inserted at the j-th position.
+//
+// The whitespace is inferred from the original members.
+func (d *Document) newLead(n *docNode, j int, multiline bool) string {
+	switch {
+	case len(n.members) > 0 && multiline:
+		return "\n" + lineIndent(d.src, n.members[len(n.members)-1].key.Start)
+	case len(n.members) > 0:
+		if j == 0 {
+			return d.lead(n, 0)
+		}
+		if len(n.members) > 1 {
+			return d.lead(n, 1)
+		}
+		return " "
+	case strings.IndexByte(d.src, '\n') >= 0:
+		return "\n" + lineIndent(d.src, n.sp.Start) + d.indent
+	case j > 0:
+		return " "
+	default:
+		return ""
+	}
+}
+
+// appendKey appends the key of kv as JSON string to dst.
+func appendKey(dst []byte, kv *kv) []byte {
+	if kv.keyUnescaped {
+		return escapeString(dst, kv.k)
+	}
+	dst = append(dst, '"')
+	dst = append(dst, kv.k...)
+	return append(dst, '"')
+}
+
+// splitGap splits the gap g between a member and the next member
+// or the closing bracket.
+//
+// tail contains the comma and the comments up to the end of the line.
+// comma is the index of the comma in tail or -1 if there is no comma.
+// lead contains the rest of g.
+func splitGap(g string) (string, int, string) {
+	comma := indexGap(g, 0, ',')
+	from := comma + 1
+	nl := indexGap(g, from, '\n')
+	if nl < 0 {
+		return g[:from], comma, g[from:]
+	}
+	return g[:nl], comma, g[nl:]
+}
+
+// indexGap returns the index of the first c in g starting from the given
+// index, which isn't inside a comment.
+//
+// -1 is returned if c isn't found.
+func indexGap(g string, from int, c byte) int {
+	for i := from; i < len(g); i++ {
+		switch {
+		case g[i] == c:
+			return i
+		case g[i] == '/' && i+1 < len(g) && g[i+1] == '/':
+			n := strings.IndexByte(g[i:], '\n')
+			if n < 0 {
+				return -1
+			}
+			// The line comment ends at the newline.
+			i += n - 1
+		case g[i] == '/' && i+1 < len(g) && g[i+1] == '*':
+			n := strings.Index(g[i+2:], "*/")
+			if n < 0 {
+				return -1
+			}
+			i += n + len("/**/") - 1
+		}
+	}
+	return -1
+}
+
+// endsInLineComment returns true if the gap g ends inside a line comment.
+func endsInLineComment(g string) bool {
+	for i := 0; i < len(g); i++ {
+		switch {
+		case g[i] == '/' && i+1 < len(g) && g[i+1] == '/':
+			n := strings.IndexByte(g[i:], '\n')
+			if n < 0 {
+				return true
+			}
+			i += n
+		case g[i] == '/' && i+1 < len(g) && g[i+1] == '*':
+			n := strings.Index(g[i+2:], "*/")
+			if n < 0 {
+				return false
+			}
+			i += n + len("/**/") - 1
+		}
+	}
+	return false
+}
+
+// lineIndent returns the whitespace at the start of the line,
+// which contains the given offset in s.
+func lineIndent(s string, offset int) string {
+	start := strings.LastIndexByte(s[:offset], '\n') + 1
+	n := start
+	for n < offset && (s[n] == ' ' || s[n] == '\t') {
+		n++
+	}
+	return s[start:n]
+}
+
+// inferIndent returns the indentation unit used in s.
+//
+// The indentation of the first indented line is returned.
+// Two spaces are returned if there are no indented lines.
+func inferIndent(s string) string {
+	for {
+		n := strings.IndexByte(s, '\n')
+		if n < 0 {
+			return "  "
+		}
+		s = s[n+1:]
+		i := 0
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		if i > 0 && i < len(s) && s[i] != '\n' && s[i] != '\r' {
+			return s[:i]
+		}
+	}
+}
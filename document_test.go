@@ -0,0 +1,232 @@
+package astjson
+
+import (
+	"testing"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestDocumentUnchanged(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		d, err := ParseDocument(nil, s)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		if result := d.String(); result != s {
+			t.Fatalf("unexpected result; got\n%s\nwant\n%s", result, s)
+		}
+	}
+
+	f(`{}`)
+	f(`[]`)
+	f(` 1.50 `)
+	f(`{"a":1,"b":[1,2,3]}`)
+	f("// config\n{\n  \"a\": 0x10, // hex\n  /* b */ \"b\": 'single',\n  c: [1e3, +1, .5,],\n}\n")
+	f("[\n  \"\\u0041\",\n\n  {}, // empty\n]")
+}
+
+func TestDocumentEdit(t *testing.T) {
+	f := func(s string, edit func(a arena.Arena, v *Value), expected string) {
+		t.Helper()
+
+		a := arena.NewMonotonicArena(arena.WithMinBufferSize(1024))
+		d, err := ParseDocument(a, s)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		edit(a, d.Root())
+		result := d.String()
+		if result != expected {
+			t.Fatalf("unexpected result for %q; got\n%s\nwant\n%s", s, result, expected)
+		}
+		if _, err := ParseDocument(nil, result); err != nil {
+			t.Fatalf("cannot parse the result for %q: %s\n%s", s, err, result)
+		}
+	}
+
+	const config = `{
+  // The server settings.
+  "server": {
+    "host": "localhost", // default host
+    "port": 8080
+  },
+  "features": [
+    "a",
+    "b"
+  ],
+  "debug": false,
+}
+`
+
+	// Replace a value.
+	f(config, func(a arena.Arena, v *Value) {
+		v.Get("server").Set(a, "port", MustParse(`9090`))
+	}, `{
+  // The server settings.
+  "server": {
+    "host": "localhost", // default host
+    "port": 9090
+  },
+  "features": [
+    "a",
+    "b"
+  ],
+  "debug": false,
+}
+`)
+
+	// Insert a member after the last member.
+	f(config, func(a arena.Arena, v *Value) {
+		v.Get("server").Set(a, "tls", MustParse(`{"enabled":true}`))
+	}, `{
+  // The server settings.
+  "server": {
+    "host": "localhost", // default host
+    "port": 8080,
+    "tls": {"enabled":true}
+  },
+  "features": [
+    "a",
+    "b"
+  ],
+  "debug": false,
+}
+`)
+
+	// Insert a member after the last member with trailing comma.
+	f(config, func(a arena.Arena, v *Value) {
+		v.Set(a, "verbose", MustParse(`true`))
+	}, `{
+  // The server settings.
+  "server": {
+    "host": "localhost", // default host
+    "port": 8080
+  },
+  "features": [
+    "a",
+    "b"
+  ],
+  "debug": false,
+  "verbose": true
+}
+`)
+
+	// Delete members.
+	f(config, func(a arena.Arena, v *Value) {
+		v.Get("server").Del("host")
+		v.Del("debug")
+	}, `{
+  // The server settings.
+  "server": {
+    "port": 8080
+  },
+  "features": [
+    "a",
+    "b"
+  ]
+}
+`)
+	f(config, func(a arena.Arena, v *Value) {
+		v.Get("server").Del("port")
+	}, `{
+  // The server settings.
+  "server": {
+    "host": "localhost" // default host
+  },
+  "features": [
+    "a",
+    "b"
+  ],
+  "debug": false,
+}
+`)
+	f(config, func(a arena.Arena, v *Value) {
+		v.Get("server").Del("port")
+		v.Get("server").Del("host")
+	}, `{
+  // The server settings.
+  "server": {},
+  "features": [
+    "a",
+    "b"
+  ],
+  "debug": false,
+}
+`)
+
+	// Edit arrays.
+	f(config, func(a arena.Arena, v *Value) {
+		v.Get("features").SetArrayItem(a, 2, MustParse(`"c"`))
+		v.Get("features").SetArrayItem(a, 0, MustParse(`"x"`))
+	}, `{
+  // The server settings.
+  "server": {
+    "host": "localhost", // default host
+    "port": 8080
+  },
+  "features": [
+    "x",
+    "b",
+    "c"
+  ],
+  "debug": false,
+}
+`)
+	f(config, func(a arena.Arena, v *Value) {
+		v.Get("features").Del("0")
+	}, `{
+  // The server settings.
+  "server": {
+    "host": "localhost", // default host
+    "port": 8080
+  },
+  "features": [
+    "b"
+  ],
+  "debug": false,
+}
+`)
+
+	// Delete the last members after line comments.
+	f("[1, // x\n 2]", func(a arena.Arena, v *Value) {
+		v.Del("1")
+	}, "[1 // x\n]")
+	f("{\"a\": 1, // x\n \"b\": 2}", func(a arena.Arena, v *Value) {
+		v.Del("b")
+	}, "{\"a\": 1 // x\n}")
+	f("[\n  1, /* x */\n  // about 2\n  2 // two\n]", func(a arena.Arena, v *Value) {
+		v.Del("1")
+	}, "[\n  1 /* x */\n]")
+	f("{\"a\": 1, /* x */ \"b\": 2}", func(a arena.Arena, v *Value) {
+		v.Del("b")
+	}, "{\"a\": 1}")
+
+	// Insert into empty objects and arrays.
+	f("{\n  \"a\": {},\n  \"b\": []\n}", func(a arena.Arena, v *Value) {
+		v.Get("a").Set(a, "x", MustParse(`1`))
+		v.Get("b").SetArrayItem(a, 0, MustParse(`2`))
+	}, "{\n  \"a\": {\n    \"x\": 1\n  },\n  \"b\": [\n    2\n  ]\n}")
+	f(`{"a": {}, "b": []}`, func(a arena.Arena, v *Value) {
+		v.Get("a").Set(a, "x", MustParse(`1`))
+		v.Get("a").Set(a, "y", MustParse(`2`))
+		v.Get("b").SetArrayItem(a, 1, MustParse(`2`))
+	}, `{"a": {"x": 1, "y": 2}, "b": [null, 2]}`)
+
+	// Single-line objects.
+	f(`{"a":1,"b":2}`, func(a arena.Arena, v *Value) {
+		v.Set(a, "c", MustParse(`3`))
+	}, `{"a":1,"b":2,"c":3}`)
+	f(`{"a": 1}`, func(a arena.Arena, v *Value) {
+		v.Set(a, "c", MustParse(`3`))
+	}, `{"a": 1, "c": 3}`)
+
+	// Move values keeping their formatting.
+	f("{\"a\": [1,   2], \"b\": 0x1F}", func(a arena.Arena, v *Value) {
+		o := MustParse(`{}`)
+		o.Set(a, "moved", v.Get("a"))
+		v.Set(a, "c", o)
+		v.Set(a, "a", v.Get("b"))
+	}, `{"a": 0x1F, "b": 0x1F, "c": {"moved":[1,   2]}}`)
+}
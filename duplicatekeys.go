@@ -0,0 +1,185 @@
+package astjson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DuplicateKeyPolicy determines how the parser handles duplicate object keys.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeysKeepAll keeps all the members with duplicate keys.
+	//
+	// Object.Get returns the value of the first member.
+	// Use Object.GetAll for obtaining the values of all the members.
+	DuplicateKeysKeepAll DuplicateKeyPolicy = iota
+
+	// DuplicateKeysFirstWins keeps only the first member with the given key.
+	DuplicateKeysFirstWins
+
+	// DuplicateKeysLastWins keeps the value of the last member with the given key
+	// at the position of the first member, like Object.Set does.
+	DuplicateKeysLastWins
+
+	// DuplicateKeysError rejects objects with duplicate keys with ErrDuplicateKey.
+	DuplicateKeysError
+)
+
+// ErrDuplicateKey is returned for objects with duplicate keys
+// if ParserOptions.DuplicateKeys is DuplicateKeysError.
+//
+// The error message contains the duplicate key.
+var ErrDuplicateKey = errors.New("duplicate object key")
+
+// GetAll returns the values of all the members with the given key in the o.
+//
+// Returns nil if the value for the given key isn't found.
+//
+// The returned values are valid until Parse is called on the Parser returned o.
+func (o *Object) GetAll(key string) []*Value {
+	if o == nil {
+		return nil
+	}
+
+	var vs []*Value
+	for _, kv := range o.kvs {
+		if !kv.keyUnescaped {
+			o.unescapeKey(nil, kv)
+		}
+		if kv.k == key {
+			vs = append(vs, kv.v)
+		}
+	}
+	return vs
+}
+
+// maxLinearDuplicateKeys is the maximum number of object members,
+// which are checked for duplicates without a map.
+const maxLinearDuplicateKeys = 16
+
+// duplicateKeys finds duplicate object keys.
+type duplicateKeys struct {
+	// keys contains the unescaped keys of the objects being skipped or validated.
+	//
+	// The keys of nested objects follow the keys of their parents.
+	keys []string
+
+	// m maps keys to their indexes in large objects.
+	m map[string]int
+}
+
+func (dk *duplicateKeys) reset() {
+	dk.keys = dk.keys[:0]
+}
+
+// find returns the index of the previous occurrence of the i-th key
+// among the first i keys.
+//
+// -1 is returned if the i-th key is seen for the first time.
+// The keys must be passed in order starting from i = 0.
+func (dk *duplicateKeys) find(key func(i int) string, i, n int) int {
+	k := key(i)
+	if n <= maxLinearDuplicateKeys {
+		for j := 0; j < i; j++ {
+			if key(j) == k {
+				return j
+			}
+		}
+		return -1
+	}
+	if i == 0 {
+		if dk.m == nil {
+			dk.m = make(map[string]int, n)
+		} else {
+			clear(dk.m)
+		}
+	}
+	if j, ok := dk.m[k]; ok {
+		return j
+	}
+	dk.m[k] = i
+	return -1
+}
+
+// pushKey records the raw key k of the object being skipped or validated
+// if duplicate keys must be rejected.
+func (l *limits) pushKey(k string) {
+	if l.opts.DuplicateKeys != DuplicateKeysError {
+		return
+	}
+	if strings.IndexByte(k, '\\') >= 0 {
+		k = unescapeStringBestEffort(nil, k)
+	}
+	l.dk.keys = append(l.dk.keys, k)
+}
+
+// pushUnescapedKey is like pushKey, but k must be unescaped already.
+func (l *limits) pushUnescapedKey(k string) {
+	if l.opts.DuplicateKeys != DuplicateKeysError {
+		return
+	}
+	l.dk.keys = append(l.dk.keys, k)
+}
+
+// checkKeys returns ErrDuplicateKey if the keys recorded by pushKey
+// starting from start contain duplicates. The keys are dropped.
+func (l *limits) checkKeys(start int) error {
+	if l.opts.DuplicateKeys != DuplicateKeysError {
+		return nil
+	}
+	keys := l.dk.keys[start:]
+	l.dk.keys = l.dk.keys[:start]
+	key := func(i int) string {
+		return keys[i]
+	}
+	for i := range keys {
+		if l.dk.find(key, i, len(keys)) >= 0 {
+			return duplicateKeyError(keys[i])
+		}
+	}
+	return nil
+}
+
+// dedupKeys applies the DuplicateKeys policy to the members of the parsed o.
+func (p *Parser) dedupKeys(o *Object) error {
+	if p.l.opts.DuplicateKeys == DuplicateKeysKeepAll || len(o.kvs) < 2 {
+		return nil
+	}
+
+	kvs := o.kvs
+	for _, kv := range kvs {
+		if !kv.keyUnescaped && strings.IndexByte(kv.k, '\\') >= 0 {
+			kv.k = p.unescapeString(kv.k)
+			kv.keyUnescaped = true
+		}
+	}
+
+	// Unique members are moved to the start of kvs, so the previous
+	// occurrences of the keys are looked up among kvs[:n].
+	n := 0
+	key := func(i int) string {
+		return kvs[i].k
+	}
+	for _, kv := range kvs {
+		kvs[n] = kv
+		j := p.l.dk.find(key, n, len(kvs))
+		if j < 0 {
+			n++
+			continue
+		}
+		switch p.l.opts.DuplicateKeys {
+		case DuplicateKeysError:
+			return duplicateKeyError(kv.k)
+		case DuplicateKeysLastWins:
+			kvs[j].v = kv.v
+		}
+	}
+	o.kvs = kvs[:n]
+	return nil
+}
+
+func duplicateKeyError(key string) error {
+	return fmt.Errorf("%w %q", ErrDuplicateKey, key)
+}
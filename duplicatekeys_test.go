@@ -0,0 +1,158 @@
+package astjson
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestDuplicateKeys(t *testing.T) {
+	f := func(t *testing.T, policy DuplicateKeyPolicy, s, expected string) {
+		t.Helper()
+
+		opts := ParserOptions{
+			DuplicateKeys: policy,
+		}
+		p := NewParser(opts)
+		a := arena.NewMonotonicArena(arena.WithMinBufferSize(1024))
+		parsers := map[string]func() (*Value, error){
+			"Parse": func() (*Value, error) {
+				return p.Parse(s)
+			},
+			"ParseWithArena": func() (*Value, error) {
+				return p.ParseWithArena(a, s)
+			},
+			"ParseLazy": func() (*Value, error) {
+				return p.ParseLazy(nil, s)
+			},
+			"ParseWithProjection": func() (*Value, error) {
+				return p.ParseWithProjection(nil, s, CompileProjection([]string{ProjectionWildcard}))
+			},
+			"ParseIndexed": func() (*Value, error) {
+				return p.ParseIndexed(nil, s)
+			},
+			"JSON5": func() (*Value, error) {
+				p := NewParser(ParserOptions{
+					DuplicateKeys: policy,
+					JSON5:         true,
+				})
+				return p.Parse(s)
+			},
+		}
+		for name, parse := range parsers {
+			v, err := parse()
+			if policy == DuplicateKeysError && expected == "" {
+				if !errors.Is(err, ErrDuplicateKey) {
+					t.Fatalf("%s: unexpected error for %q; got %v; want %v", name, s, err, ErrDuplicateKey)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: unexpected error for %q: %s", name, s, err)
+			}
+			materializeAll(v)
+			if result := v.String(); result != expected {
+				t.Fatalf("%s: unexpected result for %q; got %s; want %s", name, s, result, expected)
+			}
+		}
+
+		err := ValidateWithOptions(s, opts)
+		if policy == DuplicateKeysError && expected == "" {
+			if !errors.Is(err, ErrDuplicateKey) {
+				t.Fatalf("ValidateWithOptions: unexpected error for %q; got %v; want %v", s, err, ErrDuplicateKey)
+			}
+		} else if err != nil {
+			t.Fatalf("ValidateWithOptions: unexpected error for %q: %s", s, err)
+		}
+	}
+
+	s := `{"a": 1, "b": {"c": 2, "c": 3}, "a": 4, "a": 5, "d": 6}`
+
+	t.Run("keep all", func(t *testing.T) {
+		f(t, DuplicateKeysKeepAll, s, `{"a":1,"b":{"c":2,"c":3},"a":4,"a":5,"d":6}`)
+		f(t, DuplicateKeysKeepAll, `{}`, `{}`)
+	})
+
+	t.Run("first wins", func(t *testing.T) {
+		f(t, DuplicateKeysFirstWins, s, `{"a":1,"b":{"c":2},"d":6}`)
+		f(t, DuplicateKeysFirstWins, `{"a": 1, "b": 2}`, `{"a":1,"b":2}`)
+	})
+
+	t.Run("last wins", func(t *testing.T) {
+		f(t, DuplicateKeysLastWins, s, `{"a":5,"b":{"c":3},"d":6}`)
+		f(t, DuplicateKeysLastWins, `[{"a": 1, "a": 2}, {"a": 3}]`, `[{"a":2},{"a":3}]`)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		f(t, DuplicateKeysError, s, "")
+		f(t, DuplicateKeysError, `[{"a": 1}, {"b": {"x": 1, "x": 2}}]`, "")
+		f(t, DuplicateKeysError, `{"a\"b": 1, "a\u0022b": 2}`, "")
+		f(t, DuplicateKeysError, `{"a": {"b": 1}, "b": {"a": 1}}`, `{"a":{"b":1},"b":{"a":1}}`)
+		f(t, DuplicateKeysError, `[{"a": 1}, {"a": 2}]`, `[{"a":1},{"a":2}]`)
+	})
+
+	t.Run("large objects", func(t *testing.T) {
+		var keys []string
+		for i := 0; i < 3*maxLinearDuplicateKeys; i++ {
+			keys = append(keys, fmt.Sprintf(`"k%d": %d`, i%(2*maxLinearDuplicateKeys), i))
+		}
+		s := "{" + strings.Join(keys, ",") + "}"
+
+		var first, last []string
+		for i := 0; i < 2*maxLinearDuplicateKeys; i++ {
+			first = append(first, fmt.Sprintf(`"k%d":%d`, i, i))
+			if i < maxLinearDuplicateKeys {
+				last = append(last, fmt.Sprintf(`"k%d":%d`, i, i+2*maxLinearDuplicateKeys))
+			} else {
+				last = append(last, fmt.Sprintf(`"k%d":%d`, i, i))
+			}
+		}
+		f(t, DuplicateKeysFirstWins, s, "{"+strings.Join(first, ",")+"}")
+		f(t, DuplicateKeysLastWins, s, "{"+strings.Join(last, ",")+"}")
+		f(t, DuplicateKeysError, s, "")
+		f(t, DuplicateKeysError, "{"+strings.Join(keys[:2*maxLinearDuplicateKeys], ",")+"}", "{"+strings.Join(first, ",")+"}")
+	})
+
+	t.Run("error message", func(t *testing.T) {
+		p := NewParser(ParserOptions{
+			DuplicateKeys: DuplicateKeysError,
+		})
+		_, err := p.Parse(`{"foo": {"bar": 1, "bar": 2}}`)
+		expected := `cannot parse JSON: line 1, column 28 at $.foo: duplicate object key "bar"; unparsed tail: "}}"`
+		if err == nil || err.Error() != expected {
+			t.Fatalf("unexpected error; got %v; want %q", err, expected)
+		}
+	})
+}
+
+func TestObjectGetAll(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"a": 1, "b": 2, "a": 3, "a": [4]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	o := v.GetObject()
+
+	vs := o.GetAll("a")
+	var result []string
+	for _, v := range vs {
+		result = append(result, v.String())
+	}
+	if s := strings.Join(result, ","); s != "1,3,[4]" {
+		t.Fatalf("unexpected values; got %q; want %q", s, "1,3,[4]")
+	}
+	if vs := o.GetAll("b"); len(vs) != 1 || vs[0].GetInt() != 2 {
+		t.Fatalf("unexpected values for b: %v", vs)
+	}
+	if vs := o.GetAll("c"); vs != nil {
+		t.Fatalf("expecting nil values for missing key; got %v", vs)
+	}
+
+	o = nil
+	if vs := o.GetAll("a"); vs != nil {
+		t.Fatalf("expecting nil values for nil object; got %v", vs)
+	}
+}
@@ -0,0 +1,309 @@
+package astjson
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrorKind is the kind of ParseError.
+type ErrorKind int
+
+const (
+	// ErrorKindOther is the kind of errors created with NewParseError.
+	ErrorKindOther ErrorKind = iota
+
+	// ErrorKindSyntax means an unexpected char in place of a value,
+	// a separator or a delimiter.
+	ErrorKindSyntax
+
+	// ErrorKindUnexpectedEOF means the input ends in the middle of a value.
+	ErrorKindUnexpectedEOF
+
+	// ErrorKindUnexpectedTail means non-whitespace input after the parsed value.
+	ErrorKindUnexpectedTail
+
+	// ErrorKindBadString means a control char or invalid UTF-8 in a string.
+	ErrorKindBadString
+
+	// ErrorKindBadEscape means an invalid escape sequence in a string.
+	ErrorKindBadEscape
+
+	// ErrorKindBadNumber means an invalid number.
+	ErrorKindBadNumber
+
+	// ErrorKindDepthExceeded means the input exceeds ParserOptions.MaxDepth.
+	ErrorKindDepthExceeded
+
+	// ErrorKindLimitExceeded means the input exceeds other ParserOptions limits.
+	ErrorKindLimitExceeded
+
+	// ErrorKindDuplicateKey means a duplicate object key rejected
+	// by ParserOptions.DuplicateKeys.
+	ErrorKindDuplicateKey
+)
+
+// String returns string representation of the k.
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindOther:
+		return "other"
+	case ErrorKindSyntax:
+		return "syntax error"
+	case ErrorKindUnexpectedEOF:
+		return "unexpected EOF"
+	case ErrorKindUnexpectedTail:
+		return "unexpected tail"
+	case ErrorKindBadString:
+		return "bad string"
+	case ErrorKindBadEscape:
+		return "bad escape sequence"
+	case ErrorKindBadNumber:
+		return "bad number"
+	case ErrorKindDepthExceeded:
+		return "depth exceeded"
+	case ErrorKindLimitExceeded:
+		return "limit exceeded"
+	case ErrorKindDuplicateKey:
+		return "duplicate key"
+	default:
+		return "ErrorKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// PathElement is an object key or an array index in ParseError.Path.
+type PathElement struct {
+	// Key is the unescaped object key if Index is negative.
+	Key string
+
+	// Index is the array index. It is -1 for object keys.
+	Index int
+}
+
+// ParseError is returned by Parse*, Validate* and Scanner on invalid input.
+type ParseError struct {
+	// Err is the underlying error.
+	Err error
+
+	// Kind is the kind of the error.
+	Kind ErrorKind
+
+	// Offset is the byte offset of the failure point in the input.
+	Offset int
+
+	// Line and Column are the 1-based position of the failure point.
+	// Column is counted in bytes. They are zero for errors created with NewParseError.
+	Line   int
+	Column int
+
+	// Path contains the object keys and array indexes from the top-level
+	// value to the failure point.
+	Path []PathElement
+
+	// tail is the unparsed input starting from the failure point.
+	tail string
+}
+
+func (p *ParseError) Error() string {
+	if p == nil {
+		return ""
+	}
+	if p.Line == 0 {
+		return p.Err.Error()
+	}
+	return fmt.Sprintf("cannot parse JSON: line %d, column %d at %s: %s; unparsed tail: %q", p.Line, p.Column, p.PathString(), p.Err, p.tail)
+}
+
+// Unwrap returns the underlying error.
+func (p *ParseError) Unwrap() error {
+	return p.Err
+}
+
+// PathString returns p.Path in JSONPath notation such as $.items[3].price.
+func (p *ParseError) PathString() string {
+	b := []byte{'$'}
+	for _, e := range p.Path {
+		if e.Index >= 0 {
+			b = append(b, '[')
+			b = strconv.AppendInt(b, int64(e.Index), 10)
+			b = append(b, ']')
+		} else if isPathIdentifier(e.Key) {
+			b = append(b, '.')
+			b = append(b, e.Key...)
+		} else {
+			b = append(b, '[')
+			b = escapeString(b, e.Key)
+			b = append(b, ']')
+		}
+	}
+	return b2s(b)
+}
+
+// isPathIdentifier returns true if the key k may be written after a dot in JSONPath.
+func isPathIdentifier(k string) bool {
+	if len(k) == 0 {
+		return false
+	}
+	for i := 0; i < len(k); i++ {
+		c := k[i]
+		if c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func NewParseError(err error) *ParseError {
+	if err == nil {
+		return nil
+	}
+
+	return &ParseError{Err: err}
+}
+
+// newParseError returns ParseError for the err, which occurred at the start
+// of the tail of the input s.
+//
+// path must contain the path to the failure point in reverse order.
+func newParseError(s, tail string, err error, path errorPath) *ParseError {
+	offset := len(s) - len(tail)
+	line, column := lineColumn(s, offset)
+	return newParseErrorAt(offset, line, column, tail, err, path)
+}
+
+// newParseErrorAt returns ParseError for the err, which occurred at the given
+// position in the input. tail is the input starting from this position.
+//
+// path must contain the path to the failure point in reverse order.
+func newParseErrorAt(offset, line, column int, tail string, err error, path errorPath) *ParseError {
+	pe := &ParseError{
+		Err:    err,
+		Kind:   errorKind(err),
+		Offset: offset,
+		Line:   line,
+		Column: column,
+		// The tail is copied, since the input may be re-used after the error.
+		tail: strings.Clone(startEndString(tail)),
+	}
+	if len(tail) == 0 {
+		switch pe.Kind {
+		case ErrorKindSyntax, ErrorKindBadString, ErrorKindBadNumber:
+			pe.Kind = ErrorKindUnexpectedEOF
+		}
+	}
+	if len(path) > 0 {
+		// Copy all the keys into a single buffer.
+		n := 0
+		for _, e := range path {
+			n += len(e.Key)
+		}
+		b := make([]byte, 0, n)
+		pe.Path = make([]PathElement, len(path))
+		for i, e := range path {
+			start := len(b)
+			b = append(b, e.Key...)
+			e.Key = b2s(b[start:])
+			pe.Path[len(path)-1-i] = e
+		}
+	}
+	return pe
+}
+
+// lineColumn returns the 1-based line and column of the byte at the given offset in s.
+//
+// The column is counted in bytes.
+func lineColumn(s string, offset int) (int, int) {
+	s = s[:offset]
+	line := strings.Count(s, "\n") + 1
+	column := offset - strings.LastIndexByte(s, '\n')
+	return line, column
+}
+
+// errorKind returns the kind of the err returned from the failure point.
+func errorKind(err error) ErrorKind {
+	switch {
+	case errors.Is(err, ErrMaxDepth):
+		return ErrorKindDepthExceeded
+	case errors.Is(err, ErrDuplicateKey):
+		return ErrorKindDuplicateKey
+	case errors.Is(err, ErrMaxNodes), errors.Is(err, ErrMaxObjectMembers), errors.Is(err, ErrMaxArrayLength),
+		errors.Is(err, ErrMaxStringLength), errors.Is(err, ErrMaxNumberLength):
+		return ErrorKindLimitExceeded
+	}
+	var ke *kindError
+	if errors.As(err, &ke) {
+		return ke.kind
+	}
+	return ErrorKindSyntax
+}
+
+// kindError is an error of the given kind.
+//
+// Errors without kind are syntax errors.
+type kindError struct {
+	kind ErrorKind
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+// errorf returns an error of the given kind with the message formatted by fmt.Errorf.
+func errorf(kind ErrorKind, format string, args ...any) error {
+	return &kindError{
+		kind: kind,
+		err:  fmt.Errorf(format, args...),
+	}
+}
+
+// Errors returned from the failure point.
+//
+// They are pre-allocated, so the failure path doesn't allocate.
+var (
+	errUnexpectedTail     = &kindError{kind: ErrorKindUnexpectedTail, err: errors.New("unexpected tail")}
+	errEmptyString        = errors.New("cannot parse empty string")
+	errMissingArrayEnd    = errors.New("missing ']'")
+	errArrayEnd           = errors.New("unexpected end of array")
+	errMissingArrayComma  = errors.New("missing ',' after array value")
+	errMissingObjectEnd   = errors.New("missing '}'")
+	errObjectEnd          = errors.New("unexpected end of object")
+	errMissingObjectComma = errors.New("missing ',' after object value")
+	errMissingKeyQuote    = errors.New(`cannot find opening '"" for object key`)
+	errMissingColon       = errors.New("missing ':' after object key")
+	errMissingQuote       = errors.New(`missing closing '"'`)
+)
+
+// errorPath collects the path to the failure point in reverse order
+// while the error propagates from nested values to the top-level value.
+type errorPath []PathElement
+
+func (ep *errorPath) reset() {
+	*ep = (*ep)[:0]
+}
+
+// pushIndex records the index of the array item containing the failure point.
+func (ep *errorPath) pushIndex(i int) {
+	*ep = append(*ep, PathElement{
+		Index: i,
+	})
+}
+
+// pushKey records the unescaped key of the object member containing the failure point.
+func (ep *errorPath) pushKey(k string) {
+	*ep = append(*ep, PathElement{
+		Key:   k,
+		Index: -1,
+	})
+}
+
+// pushRawKey is like pushKey, but accepts the raw key as it appears in JSON.
+func (ep *errorPath) pushRawKey(k string) {
+	ep.pushKey(unescapeStringBestEffort(nil, k))
+}
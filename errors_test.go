@@ -0,0 +1,179 @@
+package astjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseErrorPosition(t *testing.T) {
+	type testCase struct {
+		s      string
+		opts   ParserOptions
+		kind   ErrorKind
+		offset int
+		line   int
+		column int
+		path   string
+	}
+	f := func(tc testCase) {
+		t.Helper()
+
+		check := func(name string, err error) {
+			t.Helper()
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("%s: expecting ParseError for %q; got %v", name, tc.s, err)
+			}
+			if pe.Kind != tc.kind {
+				t.Fatalf("%s: unexpected kind for %q; got %s; want %s", name, tc.s, pe.Kind, tc.kind)
+			}
+			if pe.Offset != tc.offset || pe.Line != tc.line || pe.Column != tc.column {
+				t.Fatalf("%s: unexpected position for %q; got offset %d, line %d, column %d; want offset %d, line %d, column %d",
+					name, tc.s, pe.Offset, pe.Line, pe.Column, tc.offset, tc.line, tc.column)
+			}
+			if path := pe.PathString(); path != tc.path {
+				t.Fatalf("%s: unexpected path for %q; got %s; want %s", name, tc.s, path, tc.path)
+			}
+			if !strings.Contains(pe.Error(), " at "+tc.path+": ") {
+				t.Fatalf("%s: the error message for %q doesn't contain the path %s: %s", name, tc.s, tc.path, pe)
+			}
+		}
+
+		p := NewParser(tc.opts)
+		_, err := p.Parse(tc.s)
+		check("Parse", err)
+		_, err = p.ParseLazy(nil, tc.s)
+		check("ParseLazy", err)
+		_, err = p.ParseWithProjection(nil, tc.s, CompileProjection([]string{"a"}))
+		check("ParseWithProjection", err)
+		_, err = p.ParseIndexed(nil, tc.s)
+		check("ParseIndexed", err)
+		check("ValidateWithOptions", ValidateWithOptions(tc.s, tc.opts))
+	}
+
+	f(testCase{
+		s:      `{"items": [1, 2, 3, {"price": tru}]}`,
+		kind:   ErrorKindSyntax,
+		offset: 30, line: 1, column: 31,
+		path: "$.items[3].price",
+	})
+	f(testCase{
+		s:      "{\n  \"a\": [\n    1,\n    x\n  ]\n}",
+		kind:   ErrorKindSyntax,
+		offset: 22, line: 4, column: 5,
+		path: "$.a[1]",
+	})
+	f(testCase{
+		s:      `{"a b\"": [x]}`,
+		kind:   ErrorKindSyntax,
+		offset: 11, line: 1, column: 12,
+		path: `$["a b\""][0]`,
+	})
+	f(testCase{
+		s:      `{"a": [1, 2`,
+		kind:   ErrorKindUnexpectedEOF,
+		offset: 11, line: 1, column: 12,
+		path: "$.a",
+	})
+	f(testCase{
+		s:      `{"a": "foo`,
+		kind:   ErrorKindUnexpectedEOF,
+		offset: 10, line: 1, column: 11,
+		path: "$.a",
+	})
+	f(testCase{
+		s:      ` [1] x`,
+		kind:   ErrorKindUnexpectedTail,
+		offset: 5, line: 1, column: 6,
+		path: "$",
+	})
+	f(testCase{
+		s:      `{"a": ["b\x"]}`,
+		opts:   ParserOptions{Strict: true},
+		kind:   ErrorKindBadEscape,
+		offset: 7, line: 1, column: 8,
+		path: "$.a[0]",
+	})
+	f(testCase{
+		s:      "[\"a\x01\"]",
+		opts:   ParserOptions{Strict: true},
+		kind:   ErrorKindBadString,
+		offset: 1, line: 1, column: 2,
+		path: "$[0]",
+	})
+	f(testCase{
+		s:      `{"n": 01}`,
+		opts:   ParserOptions{Strict: true},
+		kind:   ErrorKindBadNumber,
+		offset: 6, line: 1, column: 7,
+		path: "$.n",
+	})
+	f(testCase{
+		s:      `[[[1]]]`,
+		opts:   ParserOptions{MaxDepth: 2},
+		kind:   ErrorKindDepthExceeded,
+		offset: 2, line: 1, column: 3,
+		path: "$[0][0]",
+	})
+	f(testCase{
+		s:      `{"k": ["abc"]}`,
+		opts:   ParserOptions{MaxStringLength: 2},
+		kind:   ErrorKindLimitExceeded,
+		offset: 7, line: 1, column: 8,
+		path: "$.k[0]",
+	})
+	f(testCase{
+		s:      `{"a": {"b": 1, "b": 2}}`,
+		opts:   ParserOptions{DuplicateKeys: DuplicateKeysError},
+		kind:   ErrorKindDuplicateKey,
+		offset: 21, line: 1, column: 22,
+		path: "$.a",
+	})
+	f(testCase{
+		s:      "// comment\n{a: [1, 'x\\y', 0x]}",
+		opts:   ParserOptions{JSON5: true},
+		kind:   ErrorKindBadNumber,
+		offset: 26, line: 2, column: 16,
+		path: "$.a[2]",
+	})
+}
+
+func TestParseErrorDeepNesting(t *testing.T) {
+	s := strings.Repeat(`{"a":[`, MaxDepth) + "x"
+	var p Parser
+	_, err := p.Parse(s)
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		t.Fatalf("expecting ParseError; got %v", err)
+	}
+	if !errors.Is(err, ErrMaxDepth) || pe.Kind != ErrorKindDepthExceeded {
+		t.Fatalf("unexpected error; got %v with kind %s; want %v", err, pe.Kind, ErrMaxDepth)
+	}
+	if n := len(pe.Path); n != MaxDepth {
+		t.Fatalf("unexpected path length; got %d; want %d", n, MaxDepth)
+	}
+	if path := pe.PathString(); !strings.HasPrefix(path, "$.a[0].a[0].a[0]") {
+		t.Fatalf("unexpected path %s", path)
+	}
+
+	// The path is copied, so it survives the next Parse call.
+	if _, err := p.Parse(`[x]`); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	if e := pe.Path[0]; e.Key != "a" || e.Index != -1 {
+		t.Fatalf("unexpected path element %+v", e)
+	}
+}
+
+func TestParseErrorKindString(t *testing.T) {
+	if s := ErrorKindUnexpectedEOF.String(); s != "unexpected EOF" {
+		t.Fatalf("unexpected string; got %q; want %q", s, "unexpected EOF")
+	}
+	if s := ErrorKind(100).String(); s != "ErrorKind(100)" {
+		t.Fatalf("unexpected string; got %q; want %q", s, "ErrorKind(100)")
+	}
+	if k := NewParseError(errors.New("foo")).Kind; k != ErrorKindOther {
+		t.Fatalf("unexpected kind; got %s; want %s", k, ErrorKindOther)
+	}
+}
@@ -1,6 +1,8 @@
 package astjson
 
 import (
+	"io"
+
 	"github.com/wundergraph/go-arena"
 )
 
@@ -157,6 +159,14 @@ func ParseBytesWithArena(a arena.Arena, b []byte) (*Value, error) {
 	return p.ParseBytesWithArena(a, b)
 }
 
+// ParseReader reads the whole r and parses it as a single JSON value.
+//
+// See Parser.ParseReader for details.
+func ParseReader(a arena.Arena, r io.Reader, maxBytes int) (*Value, error) {
+	var p Parser
+	return p.ParseReader(a, r, maxBytes)
+}
+
 // MustParseBytes parses b containing json.
 //
 // The function panics if b cannot be parsed.
@@ -0,0 +1,334 @@
+package astjson
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/wundergraph/go-arena"
+)
+
+// ParseIndexed parses s containing JSON with the help of a structural index.
+//
+// The index of all the structural chars in s is built in a separate pass
+// over s, which uses AVX2 on amd64 if it is available. The parser then
+// jumps between the indexed positions instead of scanning s byte by byte.
+//
+// The result and the returned errors are identical to Parse.
+//
+// The returned value is valid until the next call to Parse*.
+func (p *Parser) ParseIndexed(a arena.Arena, s string) (*Value, error) {
+	idx, ok := buildStructuralIndex(p.idx[:0], s)
+	if ok && p.l.checkBytes(s) == nil && !p.l.opts.JSON5 && !p.l.opts.Spans && p.l.opts.MinPackedArrayLength <= 0 {
+		// The trailing position simplifies bounds checks for the last token.
+		idx = append(idx, uint32(len(s)))
+		p.a = a
+		p.c.reset()
+		p.l.reset()
+		v, i, ok := p.parseIndexedValue(s, idx, 0, 0)
+		p.a = nil
+		if ok && i == len(idx)-1 {
+			p.idx = idx
+			return v, nil
+		}
+	}
+	p.idx = idx
+
+	// Parse s again in order to obtain the error.
+	return p.parse(a, s)
+}
+
+// ParseBytesIndexed parses b containing JSON with the help of a structural index.
+//
+// See ParseIndexed for details.
+func (p *Parser) ParseBytesIndexed(a arena.Arena, b []byte) (*Value, error) {
+	return p.ParseIndexed(a, b2s(b))
+}
+
+// ValidateIndexed validates JSON s with the help of a structural index.
+//
+// The returned errors are identical to Validate.
+func ValidateIndexed(s string) error {
+	bp := structuralIndexPool.Get().(*[]uint32)
+	defer structuralIndexPool.Put(bp)
+
+	idx, ok := buildStructuralIndex((*bp)[:0], s)
+	*bp = idx
+	if ok {
+		idx = append(idx, uint32(len(s)))
+		*bp = idx
+		i, ok := validateIndexedValue(s, idx, 0)
+		if ok && i == len(idx)-1 {
+			return nil
+		}
+	}
+
+	// Validate s again in order to obtain the error.
+	return Validate(s)
+}
+
+var structuralIndexPool = sync.Pool{
+	New: func() any {
+		return new([]uint32)
+	},
+}
+
+// ValidateBytesIndexed validates JSON b with the help of a structural index.
+func ValidateBytesIndexed(b []byte) error {
+	return ValidateIndexed(b2s(b))
+}
+
+// indexedChar returns the char at the i-th indexed position.
+//
+// 0 is returned for the trailing position.
+func indexedChar(s string, idx []uint32, i int) byte {
+	if n := int(idx[i]); n < len(s) {
+		return s[n]
+	}
+	return 0
+}
+
+// indexedString returns the raw contents of the string
+// at the i-th indexed position.
+func indexedString(s string, idx []uint32, i int) string {
+	start := int(idx[i]) + 1
+	// Only whitespace may follow the closing quote up to the next token.
+	n := strings.LastIndexByte(s[start:idx[i+1]], '"')
+	return s[start : start+n]
+}
+
+// indexedScalar returns the scalar value at the i-th indexed position.
+func indexedScalar(s string, idx []uint32, i int) string {
+	ss := s[idx[i]:idx[i+1]]
+	n := len(ss)
+	for n > 0 && charClasses[ss[n-1]] == charWS {
+		n--
+	}
+	return ss[:n]
+}
+
+// parseIndexedValue parses the value at the i-th indexed position
+// and returns the position of the next token.
+//
+// false is returned on error.
+func (p *Parser) parseIndexedValue(s string, idx []uint32, i, depth int) (*Value, int, bool) {
+	depth++
+	if p.l.enter(depth) != nil {
+		return nil, i, false
+	}
+
+	switch c := indexedChar(s, idx, i); c {
+	case '"':
+		ss := indexedString(s, idx, i)
+		if p.l.checkString(ss) != nil {
+			return nil, i, false
+		}
+		v := p.newValue()
+		v.t = TypeString
+		v.s = p.internString(p.unescapeString(ss))
+		return v, i + 1, true
+	case '{':
+		return p.parseIndexedObject(s, idx, i+1, depth)
+	case '[':
+		return p.parseIndexedArray(s, idx, i+1, depth)
+	case 0, '}', ']', ',', ':':
+		return nil, i, false
+	default:
+		ss := indexedScalar(s, idx, i)
+		switch c {
+		case 't':
+			return valueTrue, i + 1, ss == "true"
+		case 'f':
+			return valueFalse, i + 1, ss == "false"
+		case 'n':
+			if ss == "null" {
+				return valueNull, i + 1, true
+			}
+			if len(ss) != 3 || !strings.EqualFold(ss, "nan") {
+				return nil, i, false
+			}
+		default:
+			ns, tail, err := parseRawNumber(ss)
+			if err != nil || len(tail) > 0 {
+				return nil, i, false
+			}
+			ss = ns
+		}
+		if p.l.checkNumber(ss) != nil {
+			return nil, i, false
+		}
+		v := p.newValue()
+		v.t = TypeNumber
+		v.s = ss
+		return v, i + 1, true
+	}
+}
+
+func (p *Parser) parseIndexedArray(s string, idx []uint32, i, depth int) (*Value, int, bool) {
+	arr := p.newArray()
+	if indexedChar(s, idx, i) == ']' {
+		return arr, i + 1, true
+	}
+
+	for {
+		var v *Value
+		var ok bool
+
+		if p.l.checkItems(len(arr.x.a)+1) != nil {
+			return nil, i, false
+		}
+		v, i, ok = p.parseIndexedValue(s, idx, i, depth)
+		if !ok {
+			return nil, i, false
+		}
+		arr.x.a = arena.SliceAppend(p.a, arr.x.a, v)
+
+		switch indexedChar(s, idx, i) {
+		case ',':
+			i++
+		case ']':
+			return arr, i + 1, true
+		default:
+			return nil, i, false
+		}
+	}
+}
+
+func (p *Parser) parseIndexedObject(s string, idx []uint32, i, depth int) (*Value, int, bool) {
+	o := p.newObject()
+	if indexedChar(s, idx, i) == '}' {
+		return o, i + 1, true
+	}
+
+	for {
+		var ok bool
+
+		if indexedChar(s, idx, i) != '"' || indexedChar(s, idx, i+1) != ':' {
+			return nil, i, false
+		}
+		if p.l.checkMembers(len(o.x.o.kvs)+1) != nil {
+			return nil, i, false
+		}
+		kv := p.getKV(&o.x.o)
+		kv.k = indexedString(s, idx, i)
+		if p.l.checkString(kv.k) != nil {
+			return nil, i, false
+		}
+		p.internKey(kv)
+
+		kv.v, i, ok = p.parseIndexedValue(s, idx, i+2, depth)
+		if !ok {
+			return nil, i, false
+		}
+
+		switch indexedChar(s, idx, i) {
+		case ',':
+			i++
+		case '}':
+			if p.dedupKeys(&o.x.o) != nil {
+				return nil, i, false
+			}
+			p.indexObject(&o.x.o)
+			return o, i + 1, true
+		default:
+			return nil, i, false
+		}
+	}
+}
+
+// validateIndexedValue validates the value at the i-th indexed position
+// and returns the position of the next token.
+//
+// false is returned on error.
+func validateIndexedValue(s string, idx []uint32, i int) (int, bool) {
+	switch c := indexedChar(s, idx, i); c {
+	case '"':
+		return i + 1, validateIndexedString(s, idx, i)
+	case '{':
+		return validateIndexedObject(s, idx, i+1)
+	case '[':
+		return validateIndexedArray(s, idx, i+1)
+	case 0, '}', ']', ',', ':':
+		return i, false
+	default:
+		ss := indexedScalar(s, idx, i)
+		switch c {
+		case 't':
+			return i + 1, ss == "true"
+		case 'f':
+			return i + 1, ss == "false"
+		case 'n':
+			return i + 1, ss == "null"
+		default:
+			tail, err := validateNumber(ss)
+			return i + 1, err == nil && len(tail) == 0
+		}
+	}
+}
+
+// validateIndexedString validates the string or object key
+// at the i-th indexed position.
+func validateIndexedString(s string, idx []uint32, i int) bool {
+	ss := indexedString(s, idx, i)
+	for j := 0; j < len(ss); j++ {
+		if ss[j] < 0x20 {
+			return false
+		}
+	}
+	if strings.IndexByte(ss, '\\') < 0 {
+		return true
+	}
+	_, _, err := validateString(s[idx[i]+1:])
+	return err == nil
+}
+
+func validateIndexedArray(s string, idx []uint32, i int) (int, bool) {
+	if indexedChar(s, idx, i) == ']' {
+		return i + 1, true
+	}
+
+	for {
+		var ok bool
+
+		i, ok = validateIndexedValue(s, idx, i)
+		if !ok {
+			return i, false
+		}
+
+		switch indexedChar(s, idx, i) {
+		case ',':
+			i++
+		case ']':
+			return i + 1, true
+		default:
+			return i, false
+		}
+	}
+}
+
+func validateIndexedObject(s string, idx []uint32, i int) (int, bool) {
+	if indexedChar(s, idx, i) == '}' {
+		return i + 1, true
+	}
+
+	for {
+		var ok bool
+
+		if indexedChar(s, idx, i) != '"' || indexedChar(s, idx, i+1) != ':' || !validateIndexedString(s, idx, i) {
+			return i, false
+		}
+
+		i, ok = validateIndexedValue(s, idx, i+2)
+		if !ok {
+			return i, false
+		}
+
+		switch indexedChar(s, idx, i) {
+		case ',':
+			i++
+		case '}':
+			return i + 1, true
+		default:
+			return i, false
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package astjson
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// InternerOptions contains the limits of Interner.
+type InternerOptions struct {
+	// MaxStringLength is the maximum length in bytes of the interned
+	// string values.
+	//
+	// DefaultInternerMaxStringLength is used if it is zero.
+	// Negative value disables interning of string values.
+	MaxStringLength int
+
+	// MaxKeyLength is the maximum length in bytes of the interned object keys.
+	//
+	// DefaultInternerMaxKeyLength is used if it is zero.
+	// Negative value disables interning of object keys.
+	MaxKeyLength int
+
+	// MaxEntries is the maximum number of the interned strings.
+	//
+	// New strings aren't interned after the limit is reached.
+	// DefaultInternerMaxEntries is used if it is zero.
+	// Negative value means no limit.
+	MaxEntries int
+}
+
+const (
+	// DefaultInternerMaxStringLength is the default InternerOptions.MaxStringLength.
+	DefaultInternerMaxStringLength = 32
+
+	// DefaultInternerMaxKeyLength is the default InternerOptions.MaxKeyLength.
+	DefaultInternerMaxKeyLength = 128
+
+	// DefaultInternerMaxEntries is the default InternerOptions.MaxEntries.
+	DefaultInternerMaxEntries = 64 * 1024
+)
+
+// Interner deduplicates object keys and short string values across
+// parsed documents.
+//
+// Pass it to the parser via ParserOptions.Interner. The parsed keys and strings
+// are replaced with the shared copies from the interner table, so documents
+// of the same shape retained in memory share the memory of their keys
+// and enum-like values. Such keys are compared by pointer equality
+// before their bytes are compared.
+//
+// The interned strings don't reference the parsed input or arenas,
+// so they stay valid after the input is re-used or the arena is reset.
+//
+// Interner may be used by concurrent parsers.
+// The zero Interner is ready to use with the default options.
+type Interner struct {
+	opts InternerOptions
+
+	mu sync.RWMutex
+	m  map[string]string
+
+	// bytes is the total length of the interned strings.
+	bytes int
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// InternerStats contains Interner statistics.
+type InternerStats struct {
+	// Hits is the number of strings found in the table.
+	Hits uint64
+
+	// Misses is the number of strings missing in the table,
+	// including the strings, which have been added to it.
+	Misses uint64
+
+	// Entries is the number of the interned strings.
+	Entries int
+
+	// Bytes is the total length of the interned strings.
+	Bytes int
+}
+
+// HitRate returns the share of the strings found in the table.
+func (st InternerStats) HitRate() float64 {
+	n := st.Hits + st.Misses
+	if n == 0 {
+		return 0
+	}
+	return float64(st.Hits) / float64(n)
+}
+
+// NewInterner returns an Interner with the given opts.
+func NewInterner(opts InternerOptions) *Interner {
+	return &Interner{
+		opts: opts,
+	}
+}
+
+// internerLimit returns n or the default limit if n is zero.
+func internerLimit(n, defaultLimit int) int {
+	if n == 0 {
+		return defaultLimit
+	}
+	return n
+}
+
+// Stats returns the statistics of the in.
+func (in *Interner) Stats() InternerStats {
+	in.mu.RLock()
+	st := InternerStats{
+		Entries: len(in.m),
+		Bytes:   in.bytes,
+	}
+	in.mu.RUnlock()
+	st.Hits = in.hits.Load()
+	st.Misses = in.misses.Load()
+	return st
+}
+
+// Reset drops all the interned strings and resets the statistics.
+//
+// The strings returned before Reset stay valid.
+func (in *Interner) Reset() {
+	in.mu.Lock()
+	in.m = nil
+	in.bytes = 0
+	in.mu.Unlock()
+	in.hits.Store(0)
+	in.misses.Store(0)
+}
+
+// Intern returns the shared copy of s.
+//
+// s is added to the table if it is missing there and the table isn't full.
+func (in *Interner) Intern(s string) string {
+	in.mu.RLock()
+	is, ok := in.m[s]
+	in.mu.RUnlock()
+	if ok {
+		in.hits.Add(1)
+		return is
+	}
+	in.misses.Add(1)
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if is, ok := in.m[s]; ok {
+		return is
+	}
+	if maxEntries := internerLimit(in.opts.MaxEntries, DefaultInternerMaxEntries); maxEntries > 0 && len(in.m) >= maxEntries {
+		return s
+	}
+	if in.m == nil {
+		in.m = make(map[string]string)
+	}
+	is = strings.Clone(s)
+	in.m[is] = is
+	in.bytes += len(is)
+	return is
+}
+
+// internString returns the shared copy of the parsed string value s
+// if it is short enough.
+func (in *Interner) internString(s string) string {
+	if len(s) > internerLimit(in.opts.MaxStringLength, DefaultInternerMaxStringLength) {
+		return s
+	}
+	return in.Intern(s)
+}
+
+// internKey returns the shared copy of the parsed object key k
+// if it is short enough.
+func (in *Interner) internKey(k string) string {
+	if len(k) > internerLimit(in.opts.MaxKeyLength, DefaultInternerMaxKeyLength) {
+		return k
+	}
+	return in.Intern(k)
+}
+
+// internKey replaces the key of kv with the shared copy if p has an Interner.
+func (p *Parser) internKey(kv *kv) {
+	if in := p.l.opts.Interner; in != nil {
+		kv.k = in.internKey(kv.k)
+	}
+}
+
+// internString returns the shared copy of the parsed string value s
+// if p has an Interner.
+func (p *Parser) internString(s string) string {
+	if in := p.l.opts.Interner; in != nil {
+		return in.internString(s)
+	}
+	return s
+}
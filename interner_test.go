@@ -0,0 +1,212 @@
+package astjson
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestInterner(t *testing.T) {
+	sameString := func(a, b string) bool {
+		return unsafe.StringData(a) == unsafe.StringData(b)
+	}
+
+	f := func(t *testing.T, opts ParserOptions, parse func(p *Parser, s string) (*Value, error)) {
+		t.Helper()
+
+		in := NewInterner(InternerOptions{
+			MaxStringLength: 8,
+		})
+		opts.Interner = in
+		p := NewParser(opts)
+
+		var keys []string
+		var values []string
+		var long []string
+		for i := 0; i < 3; i++ {
+			// Every document uses a distinct input buffer.
+			s := fmt.Sprintf(`{"status": "active", "description": "%s long description", "items": [{"status": "active"}]}`, strings.Repeat("x", i))
+			v, err := parse(p, s)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			v.GetObject().Visit(func(key []byte, v *Value) {
+				if string(key) == "status" {
+					keys = append(keys, b2s(key))
+				}
+			})
+			values = append(values, b2s(v.GetStringBytes("status")))
+			values = append(values, b2s(v.GetStringBytes("items", "0", "status")))
+			long = append(long, b2s(v.GetStringBytes("description")))
+		}
+		for i := range keys {
+			if !sameString(keys[i], keys[0]) {
+				t.Fatalf("the keys aren't interned")
+			}
+		}
+		for i := range values {
+			if values[i] != "active" || !sameString(values[i], values[0]) {
+				t.Fatalf("the string values aren't interned")
+			}
+		}
+		if sameString(long[0], long[1]) {
+			t.Fatalf("the long string values mustn't be interned")
+		}
+
+		st := in.Stats()
+		// "status", "active", "description", "items" are interned.
+		if st.Entries != 4 {
+			t.Fatalf("unexpected number of entries; got %d; want 4", st.Entries)
+		}
+		if st.Bytes != len("status")+len("active")+len("description")+len("items") {
+			t.Fatalf("unexpected number of bytes; got %d", st.Bytes)
+		}
+		// Every document contains 6 interned strings.
+		if st.Hits+st.Misses != 18 || st.Misses != 4 {
+			t.Fatalf("unexpected stats; got %+v", st)
+		}
+		if hr := st.HitRate(); hr != 14.0/18 {
+			t.Fatalf("unexpected hit rate; got %v; want %v", hr, 14.0/18)
+		}
+	}
+
+	t.Run("Parse", func(t *testing.T) {
+		f(t, ParserOptions{}, func(p *Parser, s string) (*Value, error) {
+			return p.Parse(s)
+		})
+	})
+	t.Run("ParseWithArena", func(t *testing.T) {
+		f(t, ParserOptions{}, func(p *Parser, s string) (*Value, error) {
+			return p.ParseWithArena(arena.NewMonotonicArena(), s)
+		})
+	})
+	t.Run("ParseIndexed", func(t *testing.T) {
+		f(t, ParserOptions{}, func(p *Parser, s string) (*Value, error) {
+			return p.ParseIndexed(nil, s)
+		})
+	})
+	t.Run("ParseLazy", func(t *testing.T) {
+		f(t, ParserOptions{}, func(p *Parser, s string) (*Value, error) {
+			return p.ParseLazy(nil, s)
+		})
+	})
+	t.Run("JSON5", func(t *testing.T) {
+		f(t, ParserOptions{JSON5: true}, func(p *Parser, s string) (*Value, error) {
+			return p.Parse(s)
+		})
+	})
+}
+
+func TestInternerLimits(t *testing.T) {
+	in := NewInterner(InternerOptions{
+		MaxEntries: 2,
+	})
+	a := in.Intern(strings.Clone("a"))
+	in.Intern("b")
+	c := strings.Clone("c")
+	if s := in.Intern(c); unsafe.StringData(s) != unsafe.StringData(c) {
+		t.Fatalf("the string mustn't be interned after the limit is reached")
+	}
+	if s := in.Intern(strings.Clone("a")); unsafe.StringData(s) != unsafe.StringData(a) {
+		t.Fatalf("the interned string must be returned")
+	}
+	if st := in.Stats(); st.Entries != 2 || st.Hits != 1 || st.Misses != 3 {
+		t.Fatalf("unexpected stats; got %+v", st)
+	}
+
+	in.Reset()
+	if st := in.Stats(); st != (InternerStats{}) {
+		t.Fatalf("unexpected stats after Reset; got %+v", st)
+	}
+
+	in = NewInterner(InternerOptions{
+		MaxStringLength: -1,
+	})
+	p := NewParser(ParserOptions{
+		Interner: in,
+	})
+	if _, err := p.Parse(`{"a": "b"}`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if st := in.Stats(); st.Entries != 1 {
+		t.Fatalf("only the key must be interned; got %+v", st)
+	}
+
+	in = NewInterner(InternerOptions{
+		MaxKeyLength: 3,
+	})
+	p = NewParser(ParserOptions{
+		Interner: in,
+	})
+	if _, err := p.Parse(`{"abc": 1, "abcd": {"a\u0062cd": 2}}`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if st := in.Stats(); st.Entries != 1 {
+		t.Fatalf("only the short key must be interned; got %+v", st)
+	}
+
+	// The number of entries is limited by default.
+	in = NewInterner(InternerOptions{})
+	for i := 0; i < DefaultInternerMaxEntries+10; i++ {
+		in.Intern(fmt.Sprintf("s%d", i))
+	}
+	if st := in.Stats(); st.Entries != DefaultInternerMaxEntries {
+		t.Fatalf("unexpected number of entries; got %d; want %d", st.Entries, DefaultInternerMaxEntries)
+	}
+}
+
+func TestInternerZeroValue(t *testing.T) {
+	var in Interner
+	a := in.Intern(strings.Clone("a"))
+	if s := in.Intern(strings.Clone("a")); unsafe.StringData(s) != unsafe.StringData(a) {
+		t.Fatalf("the interned string must be returned")
+	}
+
+	p := NewParser(ParserOptions{
+		Interner: &in,
+	})
+	if _, err := p.Parse(`{"key": "value"}`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if st := in.Stats(); st.Entries != 3 {
+		t.Fatalf("the key and the value must be interned with the default limits; got %+v", st)
+	}
+
+	in.Reset()
+	in.Intern("b")
+	if st := in.Stats(); st.Entries != 1 {
+		t.Fatalf("unexpected stats after Reset; got %+v", st)
+	}
+}
+
+func TestInternerConcurrent(t *testing.T) {
+	in := NewInterner(InternerOptions{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := NewParser(ParserOptions{
+				Interner: in,
+			})
+			for j := 0; j < 100; j++ {
+				s := fmt.Sprintf(`{"id": %d, "kind": "k%d"}`, j, j%10)
+				v, err := p.Parse(s)
+				if err != nil {
+					panic(err)
+				}
+				if got := string(v.GetStringBytes("kind")); got != fmt.Sprintf("k%d", j%10) {
+					panic(fmt.Errorf("unexpected kind %q", got))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if st := in.Stats(); st.Entries != 12 {
+		t.Fatalf("unexpected number of entries; got %d; want 12", st.Entries)
+	}
+}
@@ -0,0 +1,844 @@
+package astjson
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/wundergraph/go-arena"
+)
+
+// parseJSON5 parses s containing JSON5.
+//
+// See https://spec.json5.org/ for the syntax.
+func (p *Parser) parseJSON5(s string) (*Value, error) {
+	v, tail, err := p.parseJSON5Value(skipJSON5WS(s), 0)
+	if err != nil {
+		return nil, newParseError(s, tail, err, p.path)
+	}
+	tail = skipJSON5WS(tail)
+	if len(tail) > 0 {
+		return nil, newParseError(s, tail, errUnexpectedTail, nil)
+	}
+	return v, nil
+}
+
+// validateJSON5 validates s containing JSON5.
+//
+// It accepts exactly the same input as parseJSON5
+// and returns the same errors, but allocates nothing.
+func (v *validator) validateJSON5(s string) error {
+	tail, err := v.validateJSON5Value(skipJSON5WS(s), 0)
+	if err != nil {
+		return newParseError(s, tail, err, v.path)
+	}
+	tail = skipJSON5WS(tail)
+	if len(tail) > 0 {
+		return newParseError(s, tail, errUnexpectedTail, nil)
+	}
+	return nil
+}
+
+func (v *validator) validateJSON5Value(s string, depth int) (string, error) {
+	if len(s) == 0 {
+		return s, errEmptyString
+	}
+	depth++
+	if err := v.l.enter(depth); err != nil {
+		return s, err
+	}
+
+	switch c := s[0]; c {
+	case '"', '\'':
+		tail, err := skipJSON5String(s[1:], c)
+		if err != nil {
+			return tail, fmt.Errorf("cannot parse string: %w", err)
+		}
+		if err := v.l.checkString(s[1 : len(s)-len(tail)-1]); err != nil {
+			return s, fmt.Errorf("cannot parse string: %w", err)
+		}
+		return tail, nil
+	case '{':
+		return v.validateJSON5Object(s[1:], depth)
+	case '[':
+		return v.validateJSON5Array(s[1:], depth)
+	case 't':
+		if !strings.HasPrefix(s, "true") {
+			return s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		return s[len("true"):], nil
+	case 'f':
+		if !strings.HasPrefix(s, "false") {
+			return s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		return s[len("false"):], nil
+	case 'n':
+		if !strings.HasPrefix(s, "null") {
+			return s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		return s[len("null"):], nil
+	case '/':
+		if strings.HasPrefix(s, "/*") {
+			return s, fmt.Errorf("missing '*/' at the end of comment")
+		}
+		return s, fmt.Errorf("unexpected char: %q", s[:1])
+	default:
+		_, tail, err := scanJSON5Number(s)
+		if err != nil {
+			return tail, fmt.Errorf("cannot parse number: %w", err)
+		}
+		if err := v.l.checkNumber(s[:len(s)-len(tail)]); err != nil {
+			return s, fmt.Errorf("cannot parse number: %w", err)
+		}
+		return tail, nil
+	}
+}
+
+func (v *validator) validateJSON5Array(s string, depth int) (string, error) {
+	for n := 0; ; n++ {
+		var err error
+
+		s = skipJSON5WS(s)
+		if len(s) == 0 {
+			return s, errMissingArrayEnd
+		}
+		if s[0] == ']' {
+			// The array is either empty or ends with a trailing comma.
+			return s[1:], nil
+		}
+		if err = v.l.checkItems(n + 1); err != nil {
+			return s, err
+		}
+		s, err = v.validateJSON5Value(s, depth)
+		if err != nil {
+			v.path.pushIndex(n)
+			return s, err
+		}
+
+		s = skipJSON5WS(s)
+		if len(s) == 0 {
+			return s, errArrayEnd
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] == ']' {
+			return s[1:], nil
+		}
+		return s, errMissingArrayComma
+	}
+}
+
+func (v *validator) validateJSON5Object(s string, depth int) (string, error) {
+	start := len(v.l.dk.keys)
+	for n := 0; ; n++ {
+		var err error
+
+		// Validate key.
+		s = skipJSON5WS(s)
+		if len(s) == 0 {
+			return s, errMissingObjectEnd
+		}
+		if s[0] == '}' {
+			// The object is either empty or ends with a trailing comma.
+			if err = v.l.checkKeys(start); err != nil {
+				return s, err
+			}
+			return s[1:], nil
+		}
+		if err = v.l.checkMembers(n + 1); err != nil {
+			return s, err
+		}
+		ks := s
+		s, err = v.validateJSON5Key(s)
+		if err != nil {
+			return s, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		ks = ks[:len(ks)-len(s)]
+		s = skipJSON5WS(s)
+		if len(s) == 0 || s[0] != ':' {
+			return s, errMissingColon
+		}
+		s = s[1:]
+
+		// Validate value
+		s = skipJSON5WS(s)
+		s, err = v.validateJSON5Value(s, depth)
+		if err != nil {
+			v.path.pushKey(unescapeJSON5Key(ks))
+			return s, err
+		}
+		s = skipJSON5WS(s)
+		if len(s) == 0 {
+			return s, errObjectEnd
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] == '}' {
+			if err = v.l.checkKeys(start); err != nil {
+				return s, err
+			}
+			return s[1:], nil
+		}
+		return s, errMissingObjectComma
+	}
+}
+
+// validateJSON5Key validates the object key at the start of s.
+func (v *validator) validateJSON5Key(s string) (string, error) {
+	if c := s[0]; c == '"' || c == '\'' {
+		tail, err := skipJSON5String(s[1:], c)
+		if err != nil {
+			return tail, err
+		}
+		if err := v.l.checkString(s[1 : len(s)-len(tail)-1]); err != nil {
+			return s, err
+		}
+		if v.l.opts.DuplicateKeys == DuplicateKeysError {
+			v.l.pushUnescapedKey(unescapeJSON5Key(s[:len(s)-len(tail)]))
+		}
+		return tail, nil
+	}
+
+	n := json5IdentifierLen(s)
+	if n == 0 {
+		return s, fmt.Errorf("cannot find object key")
+	}
+	if err := v.l.checkString(s[:n]); err != nil {
+		return s, err
+	}
+	v.l.pushUnescapedKey(s[:n])
+	return s[n:], nil
+}
+
+// unescapeJSON5Key returns the unescaped form of the valid key k.
+//
+// Quoted keys with escape sequences are unescaped into newly allocated memory.
+func unescapeJSON5Key(k string) string {
+	if c := k[0]; c != '"' && c != '\'' {
+		return k
+	}
+	if strings.IndexByte(k, '\\') < 0 {
+		return k[1 : len(k)-1]
+	}
+	var p Parser
+	uk, _, _ := p.parseJSON5String(k[1:], k[0])
+	return uk
+}
+
+func (p *Parser) parseJSON5Value(s string, depth int) (*Value, string, error) {
+	if p.spans {
+		v, tail, err := p.parseBareJSON5Value(s, depth)
+		if err != nil {
+			return nil, tail, err
+		}
+		return p.withSpan(v, s, tail), tail, nil
+	}
+	return p.parseBareJSON5Value(s, depth)
+}
+
+// parseBareJSON5Value parses the value at the start of s without recording its span.
+func (p *Parser) parseBareJSON5Value(s string, depth int) (*Value, string, error) {
+	if len(s) == 0 {
+		return nil, s, errEmptyString
+	}
+	depth++
+	if err := p.l.enter(depth); err != nil {
+		return nil, s, err
+	}
+
+	switch c := s[0]; c {
+	case '"', '\'':
+		ss, tail, err := p.parseJSON5String(s[1:], c)
+		if err != nil {
+			return nil, tail, fmt.Errorf("cannot parse string: %w", err)
+		}
+		if err := p.l.checkString(s[1 : len(s)-len(tail)-1]); err != nil {
+			return nil, s, fmt.Errorf("cannot parse string: %w", err)
+		}
+		v := p.newValue()
+		v.t = TypeString
+		v.s = p.internString(ss)
+		return v, tail, nil
+	case '{':
+		return p.parseJSON5Object(s[1:], depth)
+	case '[':
+		return p.parseJSON5Array(s[1:], depth)
+	case 't':
+		if !strings.HasPrefix(s, "true") {
+			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		return valueTrue, s[len("true"):], nil
+	case 'f':
+		if !strings.HasPrefix(s, "false") {
+			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		return valueFalse, s[len("false"):], nil
+	case 'n':
+		if !strings.HasPrefix(s, "null") {
+			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		return valueNull, s[len("null"):], nil
+	case '/':
+		if strings.HasPrefix(s, "/*") {
+			return nil, s, fmt.Errorf("missing '*/' at the end of comment")
+		}
+		return nil, s, fmt.Errorf("unexpected char: %q", s[:1])
+	default:
+		ns, tail, err := p.parseJSON5Number(s)
+		if err != nil {
+			return nil, tail, fmt.Errorf("cannot parse number: %w", err)
+		}
+		if err := p.l.checkNumber(s[:len(s)-len(tail)]); err != nil {
+			return nil, s, fmt.Errorf("cannot parse number: %w", err)
+		}
+		v := p.newValue()
+		v.t = TypeNumber
+		v.s = ns
+		return v, tail, nil
+	}
+}
+
+func (p *Parser) parseJSON5Array(s string, depth int) (*Value, string, error) {
+	arr := p.newArray()
+	for {
+		var v *Value
+		var err error
+
+		s = skipJSON5WS(s)
+		if len(s) == 0 {
+			return nil, s, errMissingArrayEnd
+		}
+		if s[0] == ']' {
+			// The array is either empty or ends with a trailing comma.
+			return arr, s[1:], nil
+		}
+		if err = p.l.checkItems(len(arr.x.a) + 1); err != nil {
+			return nil, s, err
+		}
+		v, s, err = p.parseJSON5Value(s, depth)
+		if err != nil {
+			p.path.pushIndex(len(arr.x.a))
+			return nil, s, err
+		}
+		arr.x.a = arena.SliceAppend(p.a, arr.x.a, v)
+
+		s = skipJSON5WS(s)
+		if len(s) == 0 {
+			return nil, s, errArrayEnd
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] == ']' {
+			return arr, s[1:], nil
+		}
+		return nil, s, errMissingArrayComma
+	}
+}
+
+func (p *Parser) parseJSON5Object(s string, depth int) (*Value, string, error) {
+	o := p.newObject()
+	for {
+		var err error
+
+		// Parse key.
+		s = skipJSON5WS(s)
+		if len(s) == 0 {
+			return nil, s, errMissingObjectEnd
+		}
+		if s[0] == '}' {
+			// The object is either empty or ends with a trailing comma.
+			if err = p.dedupKeys(&o.x.o); err != nil {
+				return nil, s, err
+			}
+			p.indexObject(&o.x.o)
+			return o, s[1:], nil
+		}
+		if err = p.l.checkMembers(len(o.x.o.kvs) + 1); err != nil {
+			return nil, s, err
+		}
+		kv := p.getKV(&o.x.o)
+		ks := s
+		s, err = p.parseJSON5Key(kv, s)
+		if err != nil {
+			return nil, s, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		p.internKey(kv)
+		var ksp *Span
+		if p.spans {
+			ksp = p.newSpan(ks, s)
+		}
+		s = skipJSON5WS(s)
+		if len(s) == 0 || s[0] != ':' {
+			return nil, s, errMissingColon
+		}
+		s = s[1:]
+
+		// Parse value
+		s = skipJSON5WS(s)
+		kv.v, s, err = p.parseJSON5Value(s, depth)
+		if err != nil {
+			p.path.pushKey(kv.k)
+			return nil, s, err
+		}
+		if ksp != nil {
+			p.setKeySpan(kv, ksp)
+		}
+		s = skipJSON5WS(s)
+		if len(s) == 0 {
+			return nil, s, errObjectEnd
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] == '}' {
+			if err = p.dedupKeys(&o.x.o); err != nil {
+				return nil, s, err
+			}
+			p.indexObject(&o.x.o)
+			return o, s[1:], nil
+		}
+		return nil, s, errMissingObjectComma
+	}
+}
+
+// parseJSON5Key parses the object key at the start of s into kv.
+//
+// The key may be either a quoted string or an identifier.
+func (p *Parser) parseJSON5Key(kv *kv, s string) (string, error) {
+	if c := s[0]; c == '"' || c == '\'' {
+		k, tail, err := p.parseJSON5String(s[1:], c)
+		if err != nil {
+			return tail, err
+		}
+		rk := s[1 : len(s)-len(tail)-1]
+		if err := p.l.checkString(rk); err != nil {
+			return s, err
+		}
+		kv.k = k
+		// Every escape sequence is longer than the char it stands for.
+		// Keys without escape sequences are marshaled as is,
+		// unless they contain chars, which must be escaped in JSON.
+		kv.keyUnescaped = len(k) != len(rk) || hasSpecialChars(k)
+		return tail, nil
+	}
+
+	n := json5IdentifierLen(s)
+	if n == 0 {
+		return s, fmt.Errorf("cannot find object key")
+	}
+	if err := p.l.checkString(s[:n]); err != nil {
+		return s, err
+	}
+	kv.k = s[:n]
+	return s[n:], nil
+}
+
+// json5IdentifierLen returns the length of the identifier at the start of s.
+func json5IdentifierLen(s string) int {
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c == '_' || c == '$' || c|0x20 >= 'a' && c|0x20 <= 'z' || i > 0 && c >= '0' && c <= '9' {
+				i++
+				continue
+			}
+			return i
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !unicode.IsLetter(r) && !unicode.Is(unicode.Nl, r) &&
+			(i == 0 || !unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc) && r != '\u200c' && r != '\u200d') {
+			return i
+		}
+		i += size
+	}
+	return i
+}
+
+// parseJSON5String parses the string, which starts at s right after
+// the opening quote, and returns the unescaped string and the tail.
+func (p *Parser) parseJSON5String(s string, quote byte) (string, string, error) {
+	// Fast path - the string doesn't contain escape sequences.
+	n := 0
+	for n < len(s) && s[n] != quote && s[n] != '\\' && s[n] != '\n' && s[n] != '\r' {
+		n++
+	}
+	if n < len(s) && s[n] == quote {
+		return s[:n], s[n+1:], nil
+	}
+
+	// Slow path - unescape the string into memory allocated from p.a or from p.c.
+	var b []byte
+	if p.a == nil {
+		b = p.c.b
+	}
+	start := len(b)
+	for {
+		b = arena.SliceAppend(p.a, b, []byte(s[:n])...)
+		s = s[n:]
+		if len(s) == 0 {
+			return "", s, fmt.Errorf("missing closing %q", quote)
+		}
+		if s[0] == quote {
+			break
+		}
+		if s[0] != '\\' {
+			return "", s, errorf(ErrorKindBadString, "string cannot contain unescaped newline")
+		}
+		if len(s) < 2 {
+			return "", s, fmt.Errorf("missing closing %q", quote)
+		}
+		es := s
+		ch := s[1]
+		s = s[2:]
+		switch ch {
+		case 'b':
+			b = arena.SliceAppend(p.a, b, '\b')
+		case 'f':
+			b = arena.SliceAppend(p.a, b, '\f')
+		case 'n':
+			b = arena.SliceAppend(p.a, b, '\n')
+		case 'r':
+			b = arena.SliceAppend(p.a, b, '\r')
+		case 't':
+			b = arena.SliceAppend(p.a, b, '\t')
+		case 'v':
+			b = arena.SliceAppend(p.a, b, '\v')
+		case '0':
+			if len(s) > 0 && s[0] >= '0' && s[0] <= '9' {
+				return "", s, errorf(ErrorKindBadEscape, "octal escape sequences aren't allowed")
+			}
+			b = arena.SliceAppend(p.a, b, 0)
+		case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			return "", s, errorf(ErrorKindBadEscape, `unknown escape sequence \%c`, ch)
+		case 'x':
+			if len(s) < 2 {
+				return "", s, errorf(ErrorKindBadEscape, `too short escape sequence: \x%s`, s)
+			}
+			x, err := strconv.ParseUint(s[:2], 16, 8)
+			if err != nil {
+				return "", s, errorf(ErrorKindBadEscape, `invalid escape sequence \x%s: %s`, s[:2], err)
+			}
+			b = arena.SliceAppend(p.a, b, []byte(string(rune(x)))...)
+			s = s[2:]
+		case 'u':
+			x, err := parseEscapedRune(es)
+			if err != nil {
+				return "", s, err
+			}
+			s = es[6:]
+			r := x
+			if utf16.IsSurrogate(x) {
+				// Unpaired surrogates are replaced with utf8.RuneError.
+				r = utf8.RuneError
+				if x1, err := parseEscapedRune(s); err == nil {
+					if r1 := utf16.DecodeRune(x, x1); r1 != utf8.RuneError {
+						r = r1
+						s = s[6:]
+					}
+				}
+			}
+			b = arena.SliceAppend(p.a, b, []byte(string(r))...)
+		case '\n':
+			// Line continuation.
+		case '\r':
+			// Line continuation.
+			if len(s) > 0 && s[0] == '\n' {
+				s = s[1:]
+			}
+		default:
+			if ch == 0xE2 && (strings.HasPrefix(s, "\x80\xa8") || strings.HasPrefix(s, "\x80\xa9")) {
+				// Line continuation with U+2028 or U+2029.
+				s = s[2:]
+				break
+			}
+			// Other chars stand for themselves.
+			b = arena.SliceAppend(p.a, b, ch)
+		}
+
+		n = 0
+		for n < len(s) && s[n] != quote && s[n] != '\\' && s[n] != '\n' && s[n] != '\r' {
+			n++
+		}
+	}
+	if p.a == nil {
+		p.c.b = b
+	}
+	return b2s(b[start:]), s[1:], nil
+}
+
+// skipJSON5String skips the string, which starts at s right after
+// the opening quote, and returns the tail.
+//
+// It accepts exactly the same input as parseJSON5String
+// and returns the same errors.
+func skipJSON5String(s string, quote byte) (string, error) {
+	for {
+		n := 0
+		for n < len(s) && s[n] != quote && s[n] != '\\' && s[n] != '\n' && s[n] != '\r' {
+			n++
+		}
+		s = s[n:]
+		if len(s) == 0 {
+			return s, fmt.Errorf("missing closing %q", quote)
+		}
+		if s[0] == quote {
+			return s[1:], nil
+		}
+		if s[0] != '\\' {
+			return s, errorf(ErrorKindBadString, "string cannot contain unescaped newline")
+		}
+		if len(s) < 2 {
+			return s, fmt.Errorf("missing closing %q", quote)
+		}
+		es := s
+		ch := s[1]
+		s = s[2:]
+		// Surrogate pairs and line continuations with U+2028 or U+2029
+		// consist of valid chars, so they needn't be handled specially.
+		switch ch {
+		case '0':
+			if len(s) > 0 && s[0] >= '0' && s[0] <= '9' {
+				return s, errorf(ErrorKindBadEscape, "octal escape sequences aren't allowed")
+			}
+		case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			return s, errorf(ErrorKindBadEscape, `unknown escape sequence \%c`, ch)
+		case 'x':
+			if len(s) < 2 {
+				return s, errorf(ErrorKindBadEscape, `too short escape sequence: \x%s`, s)
+			}
+			if _, err := strconv.ParseUint(s[:2], 16, 8); err != nil {
+				return s, errorf(ErrorKindBadEscape, `invalid escape sequence \x%s: %s`, s[:2], err)
+			}
+			s = s[2:]
+		case 'u':
+			if _, err := parseEscapedRune(es); err != nil {
+				return s, err
+			}
+			s = es[6:]
+		case '\r':
+			// Line continuation.
+			if len(s) > 0 && s[0] == '\n' {
+				s = s[1:]
+			}
+		}
+	}
+}
+
+// json5Number is a JSON5 number split into parts.
+type json5Number struct {
+	// s is the number if it may be returned as is.
+	s string
+
+	neg bool
+
+	// hex contains the digits of the hexadecimal number.
+	hex string
+
+	// ip, fp and ep are the integer, fractional and exponent parts
+	// of the decimal number. ep includes the 'e'.
+	ip, fp, ep string
+}
+
+// parseJSON5Number parses the number at the start of s
+// and returns it in the standard JSON form if possible.
+//
+// Hexadecimal numbers are converted to decimal ones. The leading plus sign
+// and a missing integer or fractional part are fixed up. Infinity and NaN
+// are returned as is, since JSON cannot represent them.
+func (p *Parser) parseJSON5Number(s string) (string, string, error) {
+	n, tail, err := scanJSON5Number(s)
+	if err != nil {
+		return "", tail, err
+	}
+	if len(n.s) > 0 {
+		return n.s, tail, nil
+	}
+	if len(n.hex) > 0 {
+		return p.appendHexNumber(n.neg, n.hex), tail, nil
+	}
+
+	var b []byte
+	if p.a == nil {
+		b = p.c.b
+	}
+	start := len(b)
+	if n.neg {
+		b = arena.SliceAppend(p.a, b, '-')
+	}
+	ip := n.ip
+	if len(ip) == 0 {
+		ip = "0"
+	}
+	b = arena.SliceAppend(p.a, b, []byte(ip)...)
+	if len(n.fp) > 0 {
+		b = arena.SliceAppend(p.a, b, '.')
+		b = arena.SliceAppend(p.a, b, []byte(n.fp)...)
+	}
+	b = arena.SliceAppend(p.a, b, []byte(n.ep)...)
+	if p.a == nil {
+		p.c.b = b
+	}
+	return b2s(b[start:]), tail, nil
+}
+
+// scanJSON5Number splits the number at the start of s into parts.
+func scanJSON5Number(s string) (json5Number, string, error) {
+	var num json5Number
+	i := 0
+	if s[0] == '+' || s[0] == '-' {
+		i++
+	}
+	num.neg = s[0] == '-'
+	ns := s[i:]
+
+	switch {
+	case strings.HasPrefix(ns, "Infinity"):
+		if num.neg {
+			num.s = s[:i+len("Infinity")]
+			return num, s[i+len("Infinity"):], nil
+		}
+		num.s = ns[:len("Infinity")]
+		return num, ns[len("Infinity"):], nil
+	case strings.HasPrefix(ns, "NaN"):
+		num.s = ns[:len("NaN")]
+		return num, ns[len("NaN"):], nil
+	case len(ns) > 1 && ns[0] == '0' && ns[1]|0x20 == 'x':
+		n := 2
+		for n < len(ns) && (ns[n] >= '0' && ns[n] <= '9' || ns[n]|0x20 >= 'a' && ns[n]|0x20 <= 'f') {
+			n++
+		}
+		if n == 2 {
+			return num, ns, errorf(ErrorKindBadNumber, "missing hexadecimal digits")
+		}
+		num.hex = ns[2:n]
+		return num, ns[n:], nil
+	}
+
+	// Decimal number.
+	n := 0
+	for n < len(ns) && ns[n] >= '0' && ns[n] <= '9' {
+		n++
+	}
+	num.ip = ns[:n]
+	if len(num.ip) > 1 && num.ip[0] == '0' {
+		return num, ns, errorf(ErrorKindBadNumber, "unexpected number starting from 0")
+	}
+	hasDot := n < len(ns) && ns[n] == '.'
+	if hasDot {
+		n++
+		start := n
+		for n < len(ns) && ns[n] >= '0' && ns[n] <= '9' {
+			n++
+		}
+		num.fp = ns[start:n]
+	}
+	if len(num.ip) == 0 && len(num.fp) == 0 {
+		if len(ns) == 0 {
+			return num, ns, errorf(ErrorKindBadNumber, "missing number after sign")
+		}
+		return num, ns, fmt.Errorf("unexpected char: %q", ns[:1])
+	}
+	if n < len(ns) && ns[n]|0x20 == 'e' {
+		start := n
+		n++
+		if n < len(ns) && (ns[n] == '+' || ns[n] == '-') {
+			n++
+		}
+		digits := n
+		for n < len(ns) && ns[n] >= '0' && ns[n] <= '9' {
+			n++
+		}
+		if n == digits {
+			return num, ns[n:], errorf(ErrorKindBadNumber, "missing exponent part")
+		}
+		num.ep = ns[start:n]
+	}
+	tail := ns[n:]
+	if s[0] != '+' && len(num.ip) > 0 && (!hasDot || len(num.fp) > 0) {
+		// Fast path - the number is valid JSON.
+		num.s = s[:len(s)-len(tail)]
+	}
+	return num, tail, nil
+}
+
+// appendHexNumber returns the decimal form of the hexadecimal digits xs.
+func (p *Parser) appendHexNumber(neg bool, xs string) string {
+	var buf [24]byte
+	d := buf[:0]
+	if neg {
+		d = append(d, '-')
+	}
+	if x, err := strconv.ParseUint(xs, 16, 64); err == nil {
+		d = strconv.AppendUint(d, x, 10)
+	} else {
+		// The number doesn't fit uint64.
+		x, _ := new(big.Int).SetString(xs, 16)
+		d = x.Append(d, 10)
+	}
+
+	var b []byte
+	if p.a == nil {
+		b = p.c.b
+	}
+	start := len(b)
+	b = arena.SliceAppend(p.a, b, d...)
+	if p.a == nil {
+		p.c.b = b
+	}
+	return b2s(b[start:])
+}
+
+// skipJSON5WS skips whitespace and comments at the start of s.
+//
+// s is returned from the start of the unterminated block comment if any.
+func skipJSON5WS(s string) string {
+	for len(s) > 0 {
+		c := s[0]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f':
+			s = s[1:]
+		case c == '/' && len(s) > 1 && s[1] == '/':
+			n := strings.IndexAny(s, "\n\r\u2028\u2029")
+			if n < 0 {
+				return ""
+			}
+			s = s[n:]
+		case c == '/' && len(s) > 1 && s[1] == '*':
+			n := strings.Index(s[2:], "*/")
+			if n < 0 {
+				return s
+			}
+			s = s[n+len("/**/"):]
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(s)
+			if !isJSON5Space(r) {
+				return s
+			}
+			s = s[size:]
+		default:
+			return s
+		}
+	}
+	return s
+}
+
+// isJSON5Space returns true if r is a JSON5 whitespace or line terminator.
+func isJSON5Space(r rune) bool {
+	switch r {
+	case ' ', '\t', '\n', '\r', '\v', '\f', '\u00a0', '\ufeff', '\u2028', '\u2029':
+		return true
+	}
+	return unicode.Is(unicode.Zs, r)
+}
@@ -0,0 +1,345 @@
+package astjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestParseJSON5(t *testing.T) {
+	opts := ParserOptions{
+		JSON5: true,
+	}
+	f := func(s, expected string) {
+		t.Helper()
+
+		p := NewParser(opts)
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		result := v.String()
+		if result != expected {
+			t.Fatalf("unexpected result for %q; got %s; want %s", s, result, expected)
+		}
+		if !json.Valid([]byte(result)) {
+			t.Fatalf("encoding/json rejects the result for %q: %s", s, result)
+		}
+
+		// Parse with arena.
+		a := arena.NewMonotonicArena(arena.WithMinBufferSize(1024))
+		v, err = p.ParseWithArena(a, s)
+		if err != nil {
+			t.Fatalf("unexpected error for %q with arena: %s", s, err)
+		}
+		if result := v.String(); result != expected {
+			t.Fatalf("unexpected result for %q with arena; got %s; want %s", s, result, expected)
+		}
+
+		if err := ValidateWithOptions(s, opts); err != nil {
+			t.Fatalf("cannot validate %q: %s", s, err)
+		}
+	}
+
+	// Standard JSON
+	f(`{"foo": [1, "bar", true, null, {"x": -1.5e3}]}`, `{"foo":[1,"bar",true,null,{"x":-1.5e3}]}`)
+	f(`"a\"b\\cA𝄞"`, `"a\"b\\cA𝄞"`)
+
+	// Comments
+	f("// comment\n[1, // one\n2 /* two */, /* three */ 3] // end", `[1,2,3]`)
+	f("/* a /* b */ {/**/\"a\"/**/:/**/1/**/}/**/", `{"a":1}`)
+	f("[1] // comment at the end", `[1]`)
+
+	// Trailing commas
+	f(`[1, 2, 3,]`, `[1,2,3]`)
+	f(`{"a": 1, "b": 2,}`, `{"a":1,"b":2}`)
+	f(`[[],{},]`, `[[],{}]`)
+
+	// Unquoted keys
+	f(`{foo: 1, _bar$: 2, $x1: 3, ключ: 4}`, `{"foo":1,"_bar$":2,"$x1":3,"ключ":4}`)
+	f(`{null: 1, true: 2}`, `{"null":1,"true":2}`)
+
+	// Single-quoted strings
+	f(`'foo'`, `"foo"`)
+	f(`['a"b', 'it\'s']`, `["a\"b","it's"]`)
+	f(`{'a"b': 1, "c'd": 2, 'e\nf': 3}`, `{"a\"b":1,"c'd":2,"e\nf":3}`)
+
+	// Escape sequences
+	f(`'\v\0\x41é\a\/'`, `"\u000b\u0000Aéa/"`)
+	f("'line\\\ncontinuation\\\r\nand\\ more'", `"linecontinuationandmore"`)
+	f(`'\uD800x'`, "\"\ufffdx\"")
+	f("'tab\there'", `"tab\there"`)
+
+	// Numbers
+	f(`[0x1F, 0XaB, -0x10, +0x0]`, `[31,171,-16,0]`)
+	f(`0x10000000000000000`, `18446744073709551616`)
+	f(`[.5, 5., -.5e3, +1, +1.5, 5.e2]`, `[0.5,5,-0.5e3,1,1.5,5e2]`)
+	f(`[0, -0, 1e5, 1E+5, 1.5e-5]`, `[0,-0,1e5,1E+5,1.5e-5]`)
+
+	// Whitespace
+	f("\ufeff \v\f[\u00a0 1 ]\u3000", `[1]`)
+
+	// JSON5 example from https://json5.org/
+	f(`{
+  // comments
+  unquoted: 'and you can quote me on that',
+  singleQuotes: 'I can use "double quotes" here',
+  lineBreaks: "Look, Mom! \
+No \\n's!",
+  hexadecimal: 0xdecaf,
+  leadingDecimalPoint: .8675309, andTrailing: 8675309.,
+  positiveSign: +1,
+  trailingComma: 'in objects', andIn: ['arrays',],
+  "backwardsCompatible": "with JSON",
+}`, `{"unquoted":"and you can quote me on that","singleQuotes":"I can use \"double quotes\" here","lineBreaks":"Look, Mom! No \\n's!","hexadecimal":912559,"leadingDecimalPoint":0.8675309,"andTrailing":8675309,"positiveSign":1,"trailingComma":"in objects","andIn":["arrays"],"backwardsCompatible":"with JSON"}`)
+}
+
+func TestParseJSON5InfinityNaN(t *testing.T) {
+	p := NewParser(ParserOptions{
+		JSON5: true,
+	})
+	v, err := p.Parse(`[Infinity, +Infinity, -Infinity, NaN, -NaN]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result := v.String()
+	expected := `[Infinity,Infinity,-Infinity,NaN,NaN]`
+	if result != expected {
+		t.Fatalf("unexpected result; got %s; want %s", result, expected)
+	}
+	for i, want := range []string{"+Inf", "+Inf", "-Inf", "NaN", "NaN"} {
+		f := v.GetFloat64(fmt.Sprint(i))
+		if got := fmt.Sprint(f); got != want {
+			t.Fatalf("unexpected float for item #%d; got %s; want %s", i, got, want)
+		}
+	}
+}
+
+func TestParseJSON5Get(t *testing.T) {
+	p := NewParser(ParserOptions{
+		JSON5: true,
+	})
+	v, err := p.Parse(`{foo: {'b"ar': [1, 2,], "baz": 'x',},}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := v.GetInt("foo", `b"ar`, "1"); n != 2 {
+		t.Fatalf("unexpected value; got %d; want 2", n)
+	}
+	if s := v.GetStringBytes("foo", "baz"); string(s) != "x" {
+		t.Fatalf("unexpected value; got %q; want %q", s, "x")
+	}
+	var keys []string
+	v.GetObject("foo").Visit(func(key []byte, _ *Value) {
+		keys = append(keys, string(key))
+	})
+	if s := strings.Join(keys, ","); s != `b"ar,baz` {
+		t.Fatalf("unexpected keys; got %q; want %q", s, `b"ar,baz`)
+	}
+}
+
+func TestParseJSON5Error(t *testing.T) {
+	opts := ParserOptions{
+		JSON5: true,
+	}
+	f := func(s string) {
+		t.Helper()
+
+		p := NewParser(opts)
+		_, err := p.Parse(s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+		verr := ValidateWithOptions(s, opts)
+		if verr == nil {
+			t.Fatalf("expecting non-nil validation error for %q", s)
+		}
+		if verr.Error() != err.Error() {
+			t.Fatalf("unexpected validation error for %q; got %s; want %s", s, verr, err)
+		}
+	}
+
+	f(``)
+	f(`// only comment`)
+	f(`/* unterminated`)
+	f(`[1 /* unterminated`)
+	f(`[,]`)
+	f(`[1,,]`)
+	f(`{,}`)
+	f(`{a: 1,,}`)
+	f(`{1a: 1}`)
+	f(`{a b: 1}`)
+	f(`{a}`)
+	f(`'unterminated`)
+	f(`"mixed quotes'`)
+	f("'new\nline'")
+	f(`'\01'`)
+	f(`'\1'`)
+	f(`'\x4'`)
+	f(`'\xzz'`)
+	f(`'\u12'`)
+	f(`[01]`)
+	f(`[0x]`)
+	f(`[.]`)
+	f(`[+]`)
+	f(`[1e]`)
+	f(`[infinity]`)
+	f(`[nan]`)
+	f(`[Infinity1]`)
+	f(`[True]`)
+	f(`[1] [2]`)
+	f(`[1] /`)
+	f(`[1] x`)
+	f(`{a: [1, {'b\x41': [2, +]}]}`)
+	f(`{"\u0041": 1, 'b': 2, "\\": 0x}`)
+	f(`['\u12x']`)
+	f(`{a: 1, b: {c: 'd' e}}`)
+	f(strings.Repeat("[", MaxDepth+1))
+}
+
+func TestValidateJSON5(t *testing.T) {
+	opts := ParserOptions{
+		JSON5: true,
+	}
+	s := `{
+		// comment
+		a: [1, +2, .5, 0x1F, Infinity, -NaN, 'single\x41\u00e9\
+line', "\uD834\uDD1E"],
+		'b\u0063': {d: null, e: true, f: false,},
+	}`
+	n := testing.AllocsPerRun(10, func() {
+		if err := ValidateWithOptions(s, opts); err != nil {
+			panic(fmt.Errorf("unexpected error: %w", err))
+		}
+	})
+	if n != 0 {
+		t.Fatalf("unexpected number of allocations; got %v; want 0", n)
+	}
+
+	// Escaped keys are unescaped before looking for duplicates.
+	opts.DuplicateKeys = DuplicateKeysError
+	if err := ValidateWithOptions(`{bc: 1, 'b\x63': 2}`, opts); !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("unexpected error; got %v; want %v", err, ErrDuplicateKey)
+	}
+	if err := ValidateWithOptions(`{'\\': 1, "\\\\": 2, '\\x': 3}`, opts); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestParseJSON5Options(t *testing.T) {
+	t.Run("default mode rejects JSON5", func(t *testing.T) {
+		for _, s := range []string{`[1,]`, `{a: 1}`, `'a'`, `// c` + "\n1", `0x10`} {
+			var p Parser
+			if _, err := p.Parse(s); err == nil {
+				t.Fatalf("expecting non-nil error for %q", s)
+			}
+			if err := Validate(s); err == nil {
+				t.Fatalf("expecting non-nil validation error for %q", s)
+			}
+		}
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		opts := ParserOptions{
+			JSON5:          true,
+			MaxArrayLength: 2,
+		}
+		p := NewParser(opts)
+		if _, err := p.Parse(`[1, 2,]`); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err := p.Parse(`[1, 2, 3,]`); !errors.Is(err, ErrMaxArrayLength) {
+			t.Fatalf("unexpected error; got %v; want %v", err, ErrMaxArrayLength)
+		}
+		if err := ValidateWithOptions(`[1, 2, 3]`, opts); !errors.Is(err, ErrMaxArrayLength) {
+			t.Fatalf("unexpected validation error; got %v; want %v", err, ErrMaxArrayLength)
+		}
+	})
+
+	t.Run("strict is ignored", func(t *testing.T) {
+		p := NewParser(ParserOptions{
+			JSON5:  true,
+			Strict: true,
+		})
+		if _, err := p.Parse(`['\x41', NaN]`); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	t.Run("other parse modes", func(t *testing.T) {
+		p := NewParser(ParserOptions{
+			JSON5: true,
+		})
+		s := `{a: [1, 2,], b: {c: 'd'}}`
+		expected := `{"a":[1,2],"b":{"c":"d"}}`
+		v, err := p.ParseLazy(nil, s)
+		if err != nil {
+			t.Fatalf("ParseLazy: unexpected error: %s", err)
+		}
+		if result := v.String(); result != expected {
+			t.Fatalf("ParseLazy: unexpected result; got %s; want %s", result, expected)
+		}
+		v, err = p.ParseWithProjection(nil, s, CompileProjection([]string{"a"}))
+		if err != nil {
+			t.Fatalf("ParseWithProjection: unexpected error: %s", err)
+		}
+		if result := v.String(); result != expected {
+			t.Fatalf("ParseWithProjection: unexpected result; got %s; want %s", result, expected)
+		}
+		if _, err := p.ParseIndexed(nil, `[01]`); err == nil {
+			t.Fatalf("ParseIndexed: expecting non-nil error")
+		}
+	})
+}
+
+func TestScannerJSON5(t *testing.T) {
+	s := "\ufeff// header\n{a: 1,} /* between */ 'str' [0x10,]\n// number\n+5//c\n.5 Infinity/**/NaN /* trailing */\n// end"
+	expected := `{"a":1}|"str"|[16]|5|0.5|Infinity|NaN|`
+
+	var sc Scanner
+	sc.Options.JSON5 = true
+
+	f := func(name string) {
+		t.Helper()
+		var bb bytes.Buffer
+		for sc.Next() {
+			fmt.Fprintf(&bb, "%s|", sc.Value())
+		}
+		if err := sc.Error(); err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		if result := bb.String(); result != expected {
+			t.Fatalf("%s: unexpected result; got %q; want %q", name, result, expected)
+		}
+	}
+
+	sc.Init(s)
+	f("Init")
+	sc.InitReader(strings.NewReader(s))
+	f("InitReader")
+	sc.InitReader(iotest.OneByteReader(strings.NewReader(s)))
+	f("InitReader one byte")
+
+	t.Run("error", func(t *testing.T) {
+		for _, s := range []string{`{a: 1} /* unterminated`, `{a: 1} [1,,]`} {
+			sc.Init(s)
+			for sc.Next() {
+			}
+			if sc.Error() == nil {
+				t.Fatalf("Init: expecting non-nil error for %q", s)
+			}
+			sc.InitReader(iotest.OneByteReader(strings.NewReader(s)))
+			for sc.Next() {
+			}
+			if sc.Error() == nil {
+				t.Fatalf("InitReader: expecting non-nil error for %q", s)
+			}
+		}
+	})
+}
@@ -0,0 +1,303 @@
+package astjson
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wundergraph/go-arena"
+)
+
+// lazyState is shared by all the lazy values of a single ParseLazy call.
+type lazyState struct {
+	// a is the arena used for materializing lazy values.
+	a arena.Arena
+
+	// dk is the policy for duplicate keys in materialized objects.
+	dk DuplicateKeyPolicy
+
+	// in is the Interner for keys and strings in materialized values.
+	in *Interner
+
+	// unvalidated is set for the values created by RawValue,
+	// whose JSON hasn't been validated yet.
+	unvalidated bool
+}
+
+// ParseLazy parses s containing JSON in lazy mode.
+//
+// s is validated completely, but objects and arrays nested into
+// the top-level value are only recorded as raw spans of s. They are parsed
+// into Values on first access via Get, GetObject, GetArray, Object, Array,
+// Set or Del. Objects and arrays nested into them stay lazy until
+// they are accessed in turn.
+//
+// MarshalTo copies untouched objects and arrays from s verbatim.
+//
+// Values are allocated from a, also when they are materialized
+// after ParseLazy returns. The returned Value references s.
+func (p *Parser) ParseLazy(a arena.Arena, s string) (*Value, error) {
+	p.lz = arena.Allocate[lazyState](a)
+	p.lz.a = a
+	p.lz.dk = p.l.opts.DuplicateKeys
+	p.lz.in = p.l.opts.Interner
+	return p.parse(a, s)
+}
+
+// ParseBytesLazy parses b containing JSON in lazy mode.
+//
+// See ParseLazy for details.
+func (p *Parser) ParseBytesLazy(a arena.Arena, b []byte) (*Value, error) {
+	return p.ParseLazy(a, b2s(b))
+}
+
+// parseLazy records the object or array at the start of s as a lazy value.
+func (p *Parser) parseLazy(s string, depth int, t Type) (*Value, string, error) {
+	var tail string
+	var err error
+	switch {
+	case p.lzValidated:
+		// The input has been validated by the initial ParseLazy call.
+		tail = skipValidated(s)
+	case t == TypeObject:
+		tail, err = p.skipObject(s[1:], depth)
+	default:
+		tail, err = p.skipArray(s[1:], depth)
+	}
+	if err != nil {
+		return nil, tail, err
+	}
+	v := p.newValue()
+	v.t = t
+	v.s = s[:len(s)-len(tail)]
+	p.getExt(v).lz = p.lz
+	return v, tail, nil
+}
+
+// materialize parses the lazy v in place.
+//
+// Nested objects and arrays stay lazy. An error is returned only
+// for invalid values created by RawValue. v stays lazy then,
+// and the error is returned by the subsequent calls.
+func (v *Value) materialize() error {
+	ext := v.x.ext
+	if ext.err != nil {
+		return ext.err
+	}
+	lz := ext.lz
+	p := Parser{
+		a:           lz.a,
+		lz:          lz,
+		lzValidated: !lz.unvalidated,
+		l: limits{
+			opts: ParserOptions{
+				DuplicateKeys: lz.dk,
+				Interner:      lz.in,
+			},
+		},
+	}
+	var nv *Value
+	var tail string
+	var err error
+	if v.t == TypeObject {
+		nv, tail, err = p.parseObject(v.s[1:], 1)
+	} else {
+		nv, tail, err = p.parseArray(v.s[1:], 1)
+	}
+	if err != nil {
+		if !lz.unvalidated {
+			panic(fmt.Errorf("BUG: cannot parse validated JSON: %s", err))
+		}
+		ext.err = fmt.Errorf("cannot parse JSON passed to RawValue: %w", newParseError(v.s, tail, err, p.path))
+		return ext.err
+	}
+	*v = *nv
+	return nil
+}
+
+// skipValidated skips the object or array at the start of s,
+// which must have been validated already.
+func skipValidated(s string) string {
+	tail, _ := skipBrackets(s)
+	return tail
+}
+
+// skipBrackets skips the object or array at the start of s
+// by matching the brackets outside strings.
+//
+// false is returned if the object or array isn't closed.
+func skipBrackets(s string) (string, bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			_, tail, _ := parseRawString(s[i+1:])
+			i = len(s) - len(tail) - 1
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return s[i+1:], true
+			}
+		}
+	}
+	return "", false
+}
+
+// skipValue skips the JSON value at the start of s.
+//
+// It accepts exactly the same input as parseValue
+// and returns the same errors, but allocates nothing.
+func (p *Parser) skipValue(s string, depth int) (string, error) {
+	if len(s) == 0 {
+		return s, errEmptyString
+	}
+	depth++
+	if err := p.l.enter(depth); err != nil {
+		return s, err
+	}
+
+	switch s[0] {
+	case '"':
+		ss, tail, err := parseRawString(s[1:])
+		if err != nil {
+			return tail, fmt.Errorf("cannot parse string: %w", err)
+		}
+		if err := p.l.checkString(ss); err != nil {
+			return s, fmt.Errorf("cannot parse string: %w", err)
+		}
+		return tail, nil
+	case '{':
+		return p.skipObject(s[1:], depth)
+	case '[':
+		return p.skipArray(s[1:], depth)
+	case 't':
+		if len(s) < len("true") || s[:len("true")] != "true" {
+			return s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		return s[len("true"):], nil
+	case 'f':
+		if len(s) < len("false") || s[:len("false")] != "false" {
+			return s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		return s[len("false"):], nil
+	case 'n':
+		if len(s) < len("null") || s[:len("null")] != "null" {
+			if len(s) >= 3 && strings.EqualFold(s[:3], "nan") {
+				if err := p.l.checkNumber(s[:3]); err != nil {
+					return s, fmt.Errorf("cannot parse number: %w", err)
+				}
+				return s[3:], nil
+			}
+			return s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		return s[len("null"):], nil
+	default:
+		ns, tail, err := parseRawNumber(s)
+		if err != nil {
+			return tail, fmt.Errorf("cannot parse number: %w", err)
+		}
+		if err := p.l.checkNumber(ns); err != nil {
+			return s, fmt.Errorf("cannot parse number: %w", err)
+		}
+		return tail, nil
+	}
+}
+
+func (p *Parser) skipArray(s string, depth int) (string, error) {
+	s = skipWS(s)
+	if len(s) == 0 {
+		return s, errMissingArrayEnd
+	}
+	if s[0] == ']' {
+		return s[1:], nil
+	}
+
+	for n := 0; ; n++ {
+		var err error
+		s = skipWS(s)
+		if err = p.l.checkItems(n + 1); err != nil {
+			return s, err
+		}
+		s, err = p.skipValue(s, depth)
+		if err != nil {
+			p.path.pushIndex(n)
+			return s, err
+		}
+
+		s = skipWS(s)
+		if len(s) == 0 {
+			return s, errArrayEnd
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] == ']' {
+			return s[1:], nil
+		}
+		return s, errMissingArrayComma
+	}
+}
+
+func (p *Parser) skipObject(s string, depth int) (string, error) {
+	s = skipWS(s)
+	if len(s) == 0 {
+		return s, errMissingObjectEnd
+	}
+	if s[0] == '}' {
+		return s[1:], nil
+	}
+
+	start := len(p.l.dk.keys)
+	for n := 0; ; n++ {
+		var err error
+		if err = p.l.checkMembers(n + 1); err != nil {
+			return s, err
+		}
+
+		// Skip key.
+		s = skipWS(s)
+		if len(s) == 0 || s[0] != '"' {
+			return s, errMissingKeyQuote
+		}
+		ks := s
+		var k string
+		k, s, err = parseRawKey(s[1:])
+		if err != nil {
+			return s, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		if err = p.l.checkString(k); err != nil {
+			return ks, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		p.l.pushKey(k)
+		s = skipWS(s)
+		if len(s) == 0 || s[0] != ':' {
+			return s, errMissingColon
+		}
+		s = s[1:]
+
+		// Skip value
+		s = skipWS(s)
+		s, err = p.skipValue(s, depth)
+		if err != nil {
+			p.path.pushRawKey(k)
+			return s, err
+		}
+		s = skipWS(s)
+		if len(s) == 0 {
+			return s, errObjectEnd
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] == '}' {
+			if err = p.l.checkKeys(start); err != nil {
+				return s, err
+			}
+			return s[1:], nil
+		}
+		return s, errMissingObjectComma
+	}
+}
@@ -0,0 +1,159 @@
+package astjson
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestParseLazy(t *testing.T) {
+	var p Parser
+	a := arena.NewMonotonicArena()
+
+	t.Run("untouched", func(t *testing.T) {
+		s := `{ "foo" : [ 1, 2 ], "bar": {"baz": "x"} }`
+		v, err := p.ParseLazy(a, s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if v.Type() != TypeObject {
+			t.Fatalf("unexpected type; got %s; want %s", v.Type(), TypeObject)
+		}
+		expected := `{"foo":[ 1, 2 ],"bar":{"baz": "x"}}`
+		if vs := v.String(); vs != expected {
+			t.Fatalf("unexpected value; got %s; want %s", vs, expected)
+		}
+	})
+
+	t.Run("partially touched", func(t *testing.T) {
+		v, err := p.ParseLazy(a, `{"foo": [ 1, {"x": 2} ], "bar": { "baz" : "x" }}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if n := v.GetInt("foo", "1", "x"); n != 2 {
+			t.Fatalf("unexpected int; got %d; want %d", n, 2)
+		}
+		if v.lazy() || v.Get("foo").lazy() {
+			t.Fatalf("touched values must be materialized")
+		}
+		if !v.x.o.kvs[1].v.lazy() {
+			t.Fatalf("untouched value must stay lazy")
+		}
+		expected := `{"foo":[1,{"x":2}],"bar":{ "baz" : "x" }}`
+		if vs := v.String(); vs != expected {
+			t.Fatalf("unexpected value; got %s; want %s", vs, expected)
+		}
+	})
+
+	t.Run("fixtures", func(t *testing.T) {
+		for _, s := range []string{smallFixture, mediumFixture, largeFixture, canadaFixture, citmFixture, twitterFixture} {
+			v, err := p.ParseLazy(a, s)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			o := v.GetObject()
+			o.Visit(func(key []byte, v *Value) {
+				if !v.lazy() {
+					return
+				}
+				start := strings.Index(s, v.s)
+				if start < 0 || !strings.HasPrefix(s[start:], v.String()) {
+					t.Fatalf("untouched value for key %q must be marshaled verbatim", key)
+				}
+			})
+			materializeAll(v)
+			if vs, expected := v.String(), MustParse(s).String(); vs != expected {
+				t.Fatalf("unexpected materialized value")
+			}
+		}
+	})
+
+	t.Run("accessors", func(t *testing.T) {
+		v, err := p.ParseLazy(a, `[{"a": 1}, [2, 3], {"b": [4]}]`)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		arr, err := v.Array()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		o, err := arr[0].Object()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		o.Set(a, "c", arr[1])
+		arr[1].SetArrayItem(a, 2, NumberValue(a, "5"))
+		arr[2].Del("b")
+		expected := `[{"a":1,"c":[2,3,5]},[2,3,5],{}]`
+		if vs := v.String(); vs != expected {
+			t.Fatalf("unexpected value; got %s; want %s", vs, expected)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		f := func(s string) {
+			t.Helper()
+			_, err := p.ParseLazy(a, s)
+			if err == nil {
+				t.Fatalf("expecting non-nil error when parsing %q", s)
+			}
+			_, expectedErr := p.Parse(s)
+			if err.Error() != expectedErr.Error() {
+				t.Fatalf("unexpected error for %q; got %q; want %q", s, err, expectedErr)
+			}
+		}
+
+		f(`{"foo": [1, 2}`)
+		f(`[{"a" 1}]`)
+		f(`{"a": {"b": tru}}`)
+		f(`[1, "unclosed]`)
+		f(`{"a": [}`)
+		f(`[[1] 2]`)
+		f(`{"a":{}}}`)
+	})
+}
+
+func materializeAll(v *Value) {
+	switch v.Type() {
+	case TypeObject:
+		v.GetObject().Visit(func(_ []byte, v *Value) {
+			materializeAll(v)
+		})
+	case TypeArray:
+		for _, vv := range v.GetArray() {
+			materializeAll(vv)
+		}
+	}
+}
+
+func BenchmarkParseLazyAndGet(b *testing.B) {
+	fileData := getFromFile("testdata/twitter.json")
+	f := func(b *testing.B, parse func(p *Parser, a arena.Arena) (*Value, error)) {
+		var p Parser
+		a := arena.NewMonotonicArena(arena.WithMinBufferSize(1024 * 1024 * 2))
+		b.SetBytes(int64(len(fileData)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			v, err := parse(&p, a)
+			if err != nil {
+				b.Fatalf("cannot parse json: %s", err)
+			}
+			_ = v.GetStringBytes("search_metadata", "max_id_str")
+			_ = v.GetInt("search_metadata", "count")
+			_ = v.GetStringBytes("search_metadata", "query")
+			a.Reset()
+		}
+	}
+	b.Run("eager", func(b *testing.B) {
+		f(b, func(p *Parser, a arena.Arena) (*Value, error) {
+			return p.ParseWithArena(a, fileData)
+		})
+	})
+	b.Run("lazy", func(b *testing.B) {
+		f(b, func(p *Parser, a arena.Arena) (*Value, error) {
+			return p.ParseLazy(a, fileData)
+		})
+	})
+}
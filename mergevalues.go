@@ -33,8 +33,14 @@ func MergeValues(ar arena.Arena, a, b *Value) (v *Value, changed bool, err error
 	}
 	switch a.Type() {
 	case TypeObject:
-		ao, _ := a.Object()
-		bo, _ := b.Object()
+		ao, err := a.Object()
+		if err != nil {
+			return nil, false, err
+		}
+		bo, err := b.Object()
+		if err != nil {
+			return nil, false, err
+		}
 		// Unescape keys as needed during iteration
 		for i := range bo.kvs {
 			if !bo.kvs[i].keyUnescaped {
@@ -59,8 +65,14 @@ func MergeValues(ar arena.Arena, a, b *Value) (v *Value, changed bool, err error
 		}
 		return a, false, nil
 	case TypeArray:
-		aa, _ := a.Array()
-		ba, _ := b.Array()
+		aa, err := a.Array()
+		if err != nil {
+			return nil, false, err
+		}
+		ba, err := b.Array()
+		if err != nil {
+			return nil, false, err
+		}
 		if len(aa) == 0 {
 			return b, true, nil
 		}
@@ -118,12 +130,10 @@ func MergeValuesWithPath(ar arena.Arena, a, b *Value, path ...string) (v *Value,
 	if len(path) == 0 {
 		return MergeValues(ar, a, b)
 	}
-	root := &Value{
-		t: TypeObject,
-	}
+	root := ObjectValue(ar)
 	current := root
 	for i := 0; i < len(path)-1; i++ {
-		current.Set(ar, path[i], &Value{t: TypeObject})
+		current.Set(ar, path[i], ObjectValue(ar))
 		current = current.Get(path[i])
 	}
 	current.Set(ar, path[len(path)-1], b)
@@ -0,0 +1,206 @@
+package astjson
+
+import (
+	"hash/maphash"
+	"math/bits"
+
+	"github.com/wundergraph/go-arena"
+)
+
+// objectIndexThreshold is the minimum number of members in objects,
+// which are looked up via a hash index.
+const objectIndexThreshold = 32
+
+// objectIndexSeed is the seed for hashing object keys.
+var objectIndexSeed = maphash.MakeSeed()
+
+// objectIndex is a hash index of object members.
+//
+// It is an open-addressing hash table, which maps the unescaped keys
+// to their indexes in Object.kvs. Only the first member with the given key
+// is indexed, so Get returns the same value as the linear search does.
+// The member order is kept in Object.kvs.
+type objectIndex struct {
+	// a is the arena used for allocating slots.
+	a arena.Arena
+
+	// slots contains the indexes of members plus one.
+	// Zero means an empty slot. The table is built on the first lookup
+	// if slots is empty.
+	slots []uint32
+}
+
+func (c *cache) getIndex() *objectIndex {
+	if cap(c.ixs) > len(c.ixs) {
+		c.ixs = c.ixs[:len(c.ixs)+1]
+	} else {
+		c.ixs = append(c.ixs, objectIndex{})
+	}
+	ix := &c.ixs[len(c.ixs)-1]
+	// Re-use the memory of the slots of the previously parsed object.
+	*ix = objectIndex{
+		slots: ix.slots[:0],
+	}
+	return ix
+}
+
+// indexObject attaches a hash index to the parsed o if it has enough members.
+//
+// The index is built on the first lookup, so objects, which are never
+// looked up, don't pay for it.
+func (p *Parser) indexObject(o *Object) {
+	if len(o.kvs) < objectIndexThreshold {
+		return
+	}
+	if p.a != nil {
+		o.attachIndex(p.a)
+		return
+	}
+	o.ix = p.c.getIndex()
+}
+
+// attachIndex attaches a hash index allocated from a to o
+// if it has enough members.
+func (o *Object) attachIndex(a arena.Arena) {
+	if len(o.kvs) < objectIndexThreshold {
+		return
+	}
+	o.ix = arena.Allocate[objectIndex](a)
+	o.ix.a = a
+}
+
+// lookup returns the index of the first member with the given key in o.
+//
+// o must have an index. -1 is returned if the key isn't found.
+func (o *Object) lookup(key string) int {
+	ix := o.ix
+	if len(ix.slots) == 0 {
+		o.buildIndex()
+	}
+	mask := uint64(len(ix.slots) - 1)
+	for i := maphash.String(objectIndexSeed, key) & mask; ; i = (i + 1) & mask {
+		n := ix.slots[i]
+		if n == 0 {
+			return -1
+		}
+		if o.kvs[n-1].k == key {
+			return int(n - 1)
+		}
+	}
+}
+
+// buildIndex fills the index of o with all the members of o.
+func (o *Object) buildIndex() {
+	ix := o.ix
+	for _, kv := range o.kvs {
+		o.unescapeKey(ix.a, kv)
+	}
+
+	// Keep the load factor at or below 1/2.
+	size := 1 << bits.Len(uint(2*len(o.kvs)))
+	if cap(ix.slots) >= size {
+		ix.slots = ix.slots[:size]
+		clear(ix.slots)
+	} else {
+		ix.slots = arena.AllocateSlice[uint32](ix.a, size, size)
+	}
+	for i := range o.kvs {
+		o.insertIndex(i)
+	}
+}
+
+// insertIndex adds the i-th member of o to the index
+// unless a member with the same key is already indexed.
+func (o *Object) insertIndex(i int) {
+	slots := o.ix.slots
+	mask := uint64(len(slots) - 1)
+	key := o.kvs[i].k
+	for j := maphash.String(objectIndexSeed, key) & mask; ; j = (j + 1) & mask {
+		n := slots[j]
+		if n == 0 {
+			slots[j] = uint32(i + 1)
+			return
+		}
+		if o.kvs[n-1].k == key {
+			return
+		}
+	}
+}
+
+// addIndex adds the last member of o to the index of o.
+//
+// The index is attached to o with the given arena once o has enough members.
+func (o *Object) addIndex(a arena.Arena) {
+	if o.ix == nil {
+		o.attachIndex(a)
+		return
+	}
+	if len(o.ix.slots) == 0 {
+		return
+	}
+	if 2*len(o.kvs) > len(o.ix.slots) {
+		// Rebuild the index with more slots on the next lookup.
+		o.ix.slots = o.ix.slots[:0]
+		return
+	}
+	o.unescapeKey(o.ix.a, o.kvs[len(o.kvs)-1])
+	o.insertIndex(len(o.kvs) - 1)
+}
+
+// delIndex removes the i-th member from o and updates the index of o.
+//
+// It takes O(n) time, since the following members and their slots
+// are shifted like in an unindexed object.
+func (o *Object) delIndex(i int) {
+	key := o.kvs[i].k
+	if len(o.ix.slots) > 0 {
+		o.unindex(i)
+	}
+	o.kvs = append(o.kvs[:i], o.kvs[i+1:]...)
+	if len(o.ix.slots) > 0 {
+		// The next member with the same key becomes the first one.
+		for j := i; j < len(o.kvs); j++ {
+			if o.kvs[j].k == key {
+				o.insertIndex(j)
+				break
+			}
+		}
+	}
+}
+
+// unindex removes the i-th member of o from the index of o
+// and adjusts the indexes of the following members,
+// which are about to be shifted.
+func (o *Object) unindex(i int) {
+	slots := o.ix.slots
+	mask := uint64(len(slots) - 1)
+	j := maphash.String(objectIndexSeed, o.kvs[i].k) & mask
+	for slots[j] != uint32(i+1) {
+		j = (j + 1) & mask
+	}
+	o.freeSlot(j)
+	for j, n := range slots {
+		if n > uint32(i+1) {
+			slots[j] = n - 1
+		}
+	}
+}
+
+// freeSlot clears the j-th slot of the index of o via backward-shift deletion,
+// so the probe sequences of the remaining members stay unbroken.
+func (o *Object) freeSlot(j uint64) {
+	slots := o.ix.slots
+	mask := uint64(len(slots) - 1)
+	slots[j] = 0
+	for k := (j + 1) & mask; slots[k] != 0; k = (k + 1) & mask {
+		// The member may be moved to j only if its home slot
+		// isn't cyclically in (j, k].
+		n := slots[k]
+		h := maphash.String(objectIndexSeed, o.kvs[n-1].k) & mask
+		if j < k && (h <= j || h > k) || j > k && h <= j && h > k {
+			slots[j] = n
+			slots[k] = 0
+			j = k
+		}
+	}
+}
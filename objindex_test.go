@@ -0,0 +1,261 @@
+package astjson
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestObjectIndex(t *testing.T) {
+	const n = 200
+
+	var ss []string
+	for i := 0; i < n; i++ {
+		ss = append(ss, fmt.Sprintf(`"key_%d": %d`, i, i))
+	}
+	// Escaped and duplicate keys.
+	ss = append(ss, `"escaped": "a"`, `"key_7": "dup"`)
+	s := "{" + strings.Join(ss, ",") + "}"
+
+	f := func(t *testing.T, a arena.Arena, v *Value) {
+		t.Helper()
+
+		o := v.GetObject()
+		if o.ix == nil {
+			t.Fatalf("missing index for an object with %d members", o.Len())
+		}
+		for i := 0; i < n; i++ {
+			if got := o.Get(fmt.Sprintf("key_%d", i)).GetInt(); got != i {
+				t.Fatalf("unexpected value for key_%d; got %d", i, got)
+			}
+		}
+		if got := string(o.Get("escaped").GetStringBytes()); got != "a" {
+			t.Fatalf("unexpected value for the escaped key; got %q", got)
+		}
+		if o.Get("missing") != nil {
+			t.Fatalf("unexpected value for the missing key")
+		}
+		if len(o.GetAll("key_7")) != 2 {
+			t.Fatalf("unexpected number of values for the duplicate key")
+		}
+
+		// Set and Del keep the index consistent and preserve member order.
+		o.Set(a, "key_3", MustParse(`"updated"`))
+		o.Set(a, "new", MustParse(`"new"`))
+		o.Del("key_0")
+		o.Del("key_7")
+		o.Del("missing")
+		if got := string(o.Get("key_3").GetStringBytes()); got != "updated" {
+			t.Fatalf("unexpected value after Set; got %q", got)
+		}
+		if got := string(o.Get("new").GetStringBytes()); got != "new" {
+			t.Fatalf("unexpected value after Set; got %q", got)
+		}
+		if o.Get("key_0") != nil {
+			t.Fatalf("unexpected value after Del")
+		}
+		if got := string(o.Get("key_7").GetStringBytes()); got != "dup" {
+			t.Fatalf("the duplicate key must be found after Del; got %q", got)
+		}
+		for i := 8; i < n; i++ {
+			if got := o.Get(fmt.Sprintf("key_%d", i)).GetInt(); got != i {
+				t.Fatalf("unexpected value for key_%d after Del; got %d", i, got)
+			}
+		}
+
+		var keys []string
+		o.Visit(func(key []byte, _ *Value) {
+			keys = append(keys, string(key))
+		})
+		expectedKeys := []string{"key_1", "key_2", "key_3", "key_4"}
+		if strings.Join(keys[:4], ",") != strings.Join(expectedKeys, ",") {
+			t.Fatalf("unexpected member order; got %v; want %v", keys[:4], expectedKeys)
+		}
+		if keys[len(keys)-1] != "new" || keys[len(keys)-2] != "key_7" || keys[len(keys)-3] != "escaped" {
+			t.Fatalf("unexpected member order at the end; got %v", keys[len(keys)-3:])
+		}
+		if !strings.HasSuffix(v.String(), `"escaped":"a","key_7":"dup","new":"new"}`) {
+			t.Fatalf("unexpected marshaled object: %s", v)
+		}
+	}
+
+	t.Run("Parse", func(t *testing.T) {
+		var p Parser
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		f(t, nil, v)
+
+		// The index memory is re-used by the next Parse call.
+		v, err = p.Parse(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		f(t, nil, v)
+	})
+	t.Run("ParseWithArena", func(t *testing.T) {
+		a := arena.NewMonotonicArena()
+		var p Parser
+		v, err := p.ParseWithArena(a, s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		f(t, a, v)
+	})
+	t.Run("ParseIndexed", func(t *testing.T) {
+		var p Parser
+		v, err := p.ParseIndexed(nil, s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		f(t, nil, v)
+	})
+}
+
+func TestObjectSetIndex(t *testing.T) {
+	const n = 100
+
+	a := arena.NewMonotonicArena()
+	v := MustParse(`{}`)
+	for i := 0; i < n; i++ {
+		if i == objectIndexThreshold-1 && v.GetObject().ix != nil {
+			t.Fatalf("unexpected index for an object with %d members", i)
+		}
+		v.Set(a, fmt.Sprintf("key_%d", i), MustParse(fmt.Sprintf("%d", i)))
+		if i%10 == 0 {
+			// Look up keys while the object grows, so the index is resized.
+			if got := v.GetInt(fmt.Sprintf("key_%d", i/2)); got != i/2 {
+				t.Fatalf("unexpected value for key_%d; got %d", i/2, got)
+			}
+		}
+	}
+	if v.GetObject().ix == nil {
+		t.Fatalf("missing index for an object with %d members", n)
+	}
+	v.Set(a, "key_10", MustParse(`"updated"`))
+	v.Del("key_20")
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key_%d", i)
+		switch i {
+		case 10:
+			if got := string(v.GetStringBytes(key)); got != "updated" {
+				t.Fatalf("unexpected value after Set; got %q", got)
+			}
+		case 20:
+			if v.Exists(key) {
+				t.Fatalf("unexpected value after Del")
+			}
+		default:
+			if got := v.GetInt(key); got != i {
+				t.Fatalf("unexpected value for %s; got %d", key, got)
+			}
+		}
+	}
+	if !strings.HasPrefix(v.String(), `{"key_0":0,"key_1":1,`) || !strings.HasSuffix(v.String(), `"key_98":98,"key_99":99}`) {
+		t.Fatalf("unexpected marshaled object: %s", v)
+	}
+}
+
+func TestMergeValuesLargeObjects(t *testing.T) {
+	const n = 5000
+
+	var as, bs []string
+	for i := 0; i < n; i++ {
+		as = append(as, fmt.Sprintf(`"a_%d": %d`, i, i))
+		bs = append(bs, fmt.Sprintf(`"b_%d": %d`, i, i))
+	}
+	as = append(as, `"common": {"x": 1}`)
+	bs = append(bs, `"common": {"y": 2}`)
+	a := MustParse("{" + strings.Join(as, ",") + "}")
+	b := MustParse("{" + strings.Join(bs, ",") + "}")
+
+	ar := arena.NewMonotonicArena()
+	v, _, err := MergeValues(ar, a, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	o := v.GetObject()
+	if o.Len() != 2*n+1 {
+		t.Fatalf("unexpected number of members; got %d; want %d", o.Len(), 2*n+1)
+	}
+	for i := 0; i < n; i++ {
+		if v.GetInt(fmt.Sprintf("a_%d", i)) != i || v.GetInt(fmt.Sprintf("b_%d", i)) != i {
+			t.Fatalf("unexpected value for the member %d", i)
+		}
+	}
+	if got := v.Get("common").String(); got != `{"x":1,"y":2}` {
+		t.Fatalf("unexpected merged value; got %s", got)
+	}
+}
+
+func TestObjectDelIndex(t *testing.T) {
+	const n = 300
+
+	var ss []string
+	for i := 0; i < n; i++ {
+		// Every key occurs twice, so the second occurrence is looked up
+		// after the first one is deleted.
+		ss = append(ss, fmt.Sprintf(`"k%d": %d`, i%(n/2), i))
+	}
+	v := MustParse("{" + strings.Join(ss, ",") + "}")
+	o := v.GetObject()
+	if o.Get("k0") == nil {
+		t.Fatalf("missing value for k0")
+	}
+
+	// The expected values are found via linear search.
+	expected := func(key string) *Value {
+		for _, kv := range o.kvs {
+			if kv.k == key {
+				return kv.v
+			}
+		}
+		return nil
+	}
+	rnd := rand.New(rand.NewSource(1))
+	for o.Len() > 0 {
+		o.Del(fmt.Sprintf("k%d", rnd.Intn(n/2)))
+		for i := 0; i < n/2; i++ {
+			key := fmt.Sprintf("k%d", i)
+			if got, want := o.Get(key), expected(key); got != want {
+				t.Fatalf("unexpected value for %s after Del; got %v; want %v", key, got, want)
+			}
+		}
+	}
+}
+
+func TestObjectIndexAttached(t *testing.T) {
+	var ss []string
+	for i := 0; i < objectIndexThreshold; i++ {
+		ss = append(ss, fmt.Sprintf(`"key_%d": {"a": %d}`, i, i))
+	}
+	s := "{" + strings.Join(ss, ",") + "}"
+
+	f := func(name string, v *Value) {
+		t.Helper()
+		o := v.GetObject()
+		if o.ix == nil {
+			t.Fatalf("%s: missing index for an object with %d members", name, o.Len())
+		}
+		if got := v.GetInt("key_5", "a"); got != 5 {
+			t.Fatalf("%s: unexpected value; got %d; want %d", name, got, 5)
+		}
+	}
+
+	var p Parser
+	v, err := p.ParseWithProjection(nil, s, CompileProjection([]string{ProjectionWildcard, "a"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f("ParseWithProjection", v)
+
+	v, err = LoadSnapshot(nil, mustAppendSnapshot(MustParse(s), nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f("LoadSnapshot", v)
+}
@@ -0,0 +1,218 @@
+package astjson
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ParserOptions contains limits and syntax options for the parsed JSON.
+//
+// Zero fields mean no limit, except for MaxDepth.
+type ParserOptions struct {
+	// Strict enables strict RFC 8259 parsing.
+	//
+	// By default the parser accepts NaN and Inf numbers, doesn't validate
+	// numbers, escape sequences and UTF-8 in strings, and keeps invalid
+	// escape sequences unchanged. Strict rejects such input,
+	// so the parsed values are always marshaled to standard JSON.
+	Strict bool
+
+	// JSON5 enables the relaxed JSON5 syntax, which is a superset of JSONC.
+	// See https://spec.json5.org/ .
+	//
+	// Comments, trailing commas, unquoted object keys, single-quoted strings,
+	// hexadecimal numbers, Infinity and NaN are accepted among others.
+	// The parsed values are marshaled to standard JSON except for
+	// Infinity and NaN numbers, which cannot be represented in JSON.
+	//
+	// ParseLazy and ParseWithProjection parse JSON5 eagerly and without
+	// the projection. Strict is ignored if JSON5 is set.
+	JSON5 bool
+
+	// DuplicateKeys is the policy for duplicate object keys.
+	//
+	// All the members with duplicate keys are kept by default.
+	DuplicateKeys DuplicateKeyPolicy
+
+	// Interner deduplicates object keys and short string values
+	// across parsed documents if it is set. See Interner.
+	Interner *Interner
+
+	// Spans enables recording the position of every parsed value
+	// and object key in the input. See Value.Span and Object.KeySpan.
+	//
+	// ParseLazy and ParseWithProjection ignore Spans.
+	Spans bool
+
+	// MinPackedArrayLength enables packing of arrays, which consist of
+	// at least MinPackedArrayLength numbers, if it is positive.
+	//
+	// The numbers of packed arrays are stored as float64 and int64 slices
+	// instead of Values, which saves memory for big numeric arrays.
+	// See Value.Float64s and Value.Int64s. Packed arrays are unpacked
+	// into Values on the first access to their items.
+	//
+	// ParseLazy, ParseWithProjection and JSON5 ignore MinPackedArrayLength.
+	// It is ignored if Spans is set.
+	MinPackedArrayLength int
+
+	// MaxDepth is the maximum nesting depth of objects and arrays.
+	//
+	// The MaxDepth constant is used if it is zero.
+	MaxDepth int
+
+	// MaxBytes is the maximum size of the input in bytes.
+	MaxBytes int
+
+	// MaxNodes is the maximum number of values in the input
+	// including nested values.
+	MaxNodes int
+
+	// MaxObjectMembers is the maximum number of members in a single object.
+	MaxObjectMembers int
+
+	// MaxArrayLength is the maximum number of items in a single array.
+	MaxArrayLength int
+
+	// MaxStringLength is the maximum length in bytes of a single string
+	// or object key. Escape sequences are counted as they appear in the input.
+	MaxStringLength int
+
+	// MaxNumberLength is the maximum length in bytes of a single number.
+	MaxNumberLength int
+}
+
+// Errors returned when the input exceeds ParserOptions limits.
+//
+// Use errors.Is for checking the returned errors.
+var (
+	ErrMaxDepth         = errors.New("too big depth for the nested JSON")
+	ErrMaxBytes         = errors.New("too big JSON input")
+	ErrMaxNodes         = errors.New("too many values in JSON")
+	ErrMaxObjectMembers = errors.New("too many object members")
+	ErrMaxArrayLength   = errors.New("too many array items")
+	ErrMaxStringLength  = errors.New("too long string")
+	ErrMaxNumberLength  = errors.New("too long number")
+)
+
+// NewParser returns a Parser, which enforces the given opts.
+func NewParser(opts ParserOptions) *Parser {
+	return &Parser{
+		l: limits{
+			opts: opts,
+		},
+	}
+}
+
+// ValidateWithOptions validates JSON s and enforces the given opts.
+//
+// Unlike Validate, it limits the nesting depth to the MaxDepth constant
+// if opts.MaxDepth is zero.
+func ValidateWithOptions(s string, opts ParserOptions) error {
+	v := validator{
+		l: limits{
+			opts: opts,
+		},
+	}
+	return v.validate(s)
+}
+
+// ValidateBytesWithOptions validates JSON b and enforces the given opts.
+func ValidateBytesWithOptions(b []byte, opts ParserOptions) error {
+	return ValidateWithOptions(b2s(b), opts)
+}
+
+// noLimits are the limits of Validate.
+var noLimits = ParserOptions{
+	MaxDepth: math.MaxInt,
+}
+
+// limits enforces ParserOptions during a single Parse* or Validate* call.
+type limits struct {
+	opts ParserOptions
+
+	// nodes is the number of values seen so far.
+	nodes int
+
+	// dk finds duplicate object keys.
+	dk duplicateKeys
+}
+
+func (l *limits) reset() {
+	l.nodes = 0
+	l.dk.reset()
+}
+
+func (l *limits) maxDepth() int {
+	if l.opts.MaxDepth > 0 {
+		return l.opts.MaxDepth
+	}
+	return MaxDepth
+}
+
+// checkBytes returns an error if s exceeds MaxBytes.
+func (l *limits) checkBytes(s string) error {
+	if l.opts.MaxBytes > 0 && len(s) > l.opts.MaxBytes {
+		return &MaxBytesError{Limit: l.opts.MaxBytes}
+	}
+	return nil
+}
+
+// enter must be called for every value at the given depth.
+func (l *limits) enter(depth int) error {
+	if depth > l.maxDepth() {
+		return limitError(ErrMaxDepth, l.maxDepth())
+	}
+	if l.opts.MaxNodes > 0 {
+		l.nodes++
+		if l.nodes > l.opts.MaxNodes {
+			return limitError(ErrMaxNodes, l.opts.MaxNodes)
+		}
+	}
+	return nil
+}
+
+// checkMembers returns an error if an object with n members exceeds MaxObjectMembers.
+func (l *limits) checkMembers(n int) error {
+	if l.opts.MaxObjectMembers > 0 && n > l.opts.MaxObjectMembers {
+		return limitError(ErrMaxObjectMembers, l.opts.MaxObjectMembers)
+	}
+	return nil
+}
+
+// checkItems returns an error if an array with n items exceeds MaxArrayLength.
+func (l *limits) checkItems(n int) error {
+	if l.opts.MaxArrayLength > 0 && n > l.opts.MaxArrayLength {
+		return limitError(ErrMaxArrayLength, l.opts.MaxArrayLength)
+	}
+	return nil
+}
+
+// checkString returns an error if the raw string s exceeds MaxStringLength
+// or if it is invalid in Strict mode.
+func (l *limits) checkString(s string) error {
+	if l.opts.MaxStringLength > 0 && len(s) > l.opts.MaxStringLength {
+		return limitError(ErrMaxStringLength, l.opts.MaxStringLength)
+	}
+	if l.opts.Strict && !l.opts.JSON5 {
+		return validateStrictString(s)
+	}
+	return nil
+}
+
+// checkNumber returns an error if the raw number s exceeds MaxNumberLength
+// or if it is invalid in Strict mode.
+func (l *limits) checkNumber(s string) error {
+	if l.opts.MaxNumberLength > 0 && len(s) > l.opts.MaxNumberLength {
+		return limitError(ErrMaxNumberLength, l.opts.MaxNumberLength)
+	}
+	if l.opts.Strict && !l.opts.JSON5 {
+		return validateStrictNumber(s)
+	}
+	return nil
+}
+
+func limitError(err error, limit int) error {
+	return fmt.Errorf("%w; it exceeds %d", err, limit)
+}
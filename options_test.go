@@ -0,0 +1,139 @@
+package astjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParserOptions(t *testing.T) {
+	f := func(t *testing.T, opts ParserOptions, s string, expectedErr error) {
+		t.Helper()
+
+		p := NewParser(opts)
+		parsers := map[string]func() error{
+			"Parse": func() error {
+				_, err := p.Parse(s)
+				return err
+			},
+			"ParseLazy": func() error {
+				_, err := p.ParseLazy(nil, s)
+				return err
+			},
+			"ParseWithProjection": func() error {
+				_, err := p.ParseWithProjection(nil, s, CompileProjection([]string{"a"}))
+				return err
+			},
+			"ParseIndexed": func() error {
+				_, err := p.ParseIndexed(nil, s)
+				return err
+			},
+			"ParseReader": func() error {
+				_, err := p.ParseReader(nil, strings.NewReader(s), 0)
+				return err
+			},
+			"ValidateWithOptions": func() error {
+				return ValidateWithOptions(s, opts)
+			},
+		}
+		for name, parse := range parsers {
+			err := parse()
+			if expectedErr == nil {
+				if err != nil {
+					t.Fatalf("%s: unexpected error for %q: %s", name, s, err)
+				}
+				continue
+			}
+			if !errors.Is(err, expectedErr) {
+				t.Fatalf("%s: unexpected error for %q; got %v; want %v", name, s, err, expectedErr)
+			}
+		}
+	}
+
+	t.Run("depth", func(t *testing.T) {
+		opts := ParserOptions{
+			MaxDepth: 3,
+		}
+		f(t, opts, `{"a": [{}]}`, nil)
+		f(t, opts, `{"a": [{"b": 1}]}`, ErrMaxDepth)
+		f(t, opts, `[[[[]]]]`, ErrMaxDepth)
+		f(t, ParserOptions{}, strings.Repeat("[", MaxDepth)+strings.Repeat("]", MaxDepth), nil)
+		f(t, ParserOptions{}, strings.Repeat("[", MaxDepth+1)+strings.Repeat("]", MaxDepth+1), ErrMaxDepth)
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		opts := ParserOptions{
+			MaxBytes: 10,
+		}
+		f(t, opts, `{"a": 123}`, nil)
+		f(t, opts, `{"a": 1234}`, ErrMaxBytes)
+	})
+
+	t.Run("nodes", func(t *testing.T) {
+		opts := ParserOptions{
+			MaxNodes: 4,
+		}
+		f(t, opts, `{"a": [1, 2]}`, nil)
+		f(t, opts, `{"a": [1, 2, 3]}`, ErrMaxNodes)
+		f(t, opts, `{"a": [[[[]]]]}`, ErrMaxNodes)
+	})
+
+	t.Run("object members", func(t *testing.T) {
+		opts := ParserOptions{
+			MaxObjectMembers: 2,
+		}
+		f(t, opts, `{"a": {"b": 1, "c": 2}, "d": 3}`, nil)
+		f(t, opts, `{"a": 1, "b": 2, "c": 3}`, ErrMaxObjectMembers)
+		f(t, opts, `{"a": {"b": 1, "c": 2, "d": 3}}`, ErrMaxObjectMembers)
+	})
+
+	t.Run("array length", func(t *testing.T) {
+		opts := ParserOptions{
+			MaxArrayLength: 2,
+		}
+		f(t, opts, `{"a": [[1, 2], 3]}`, nil)
+		f(t, opts, `[1, 2, 3]`, ErrMaxArrayLength)
+		f(t, opts, `{"a": [[1, 2, 3]]}`, ErrMaxArrayLength)
+	})
+
+	t.Run("string length", func(t *testing.T) {
+		opts := ParserOptions{
+			MaxStringLength: 4,
+		}
+		f(t, opts, `{"abcd": "a\"b"}`, nil)
+		f(t, opts, `{"a": "abcde"}`, ErrMaxStringLength)
+		f(t, opts, `{"abcde": 1}`, ErrMaxStringLength)
+		f(t, opts, `{"a": ["a\"bcd"]}`, ErrMaxStringLength)
+	})
+
+	t.Run("number length", func(t *testing.T) {
+		opts := ParserOptions{
+			MaxNumberLength: 4,
+		}
+		f(t, opts, `{"a": [-1.5, 1e10]}`, nil)
+		f(t, opts, `{"a": 12345}`, ErrMaxNumberLength)
+		f(t, opts, `{"a": [1.2345]}`, ErrMaxNumberLength)
+	})
+
+	t.Run("error message", func(t *testing.T) {
+		p := NewParser(ParserOptions{
+			MaxArrayLength: 2,
+		})
+		_, err := p.Parse(`{"a": [1, 2, 3]}`)
+		expected := `cannot parse JSON: line 1, column 14 at $.a: too many array items; it exceeds 2; unparsed tail: "3]}"`
+		if err == nil || err.Error() != expected {
+			t.Fatalf("unexpected error; got %v; want %q", err, expected)
+		}
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("expecting ParseError; got %T", err)
+		}
+	})
+
+	t.Run("validate without limits", func(t *testing.T) {
+		s := strings.Repeat("[", MaxDepth+1) + strings.Repeat("]", MaxDepth+1)
+		if err := Validate(s); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+}
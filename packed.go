@@ -0,0 +1,233 @@
+package astjson
+
+import (
+	"fmt"
+
+	"github.com/wundergraph/astjson/fastfloat"
+	"github.com/wundergraph/go-arena"
+)
+
+// packedArray contains the items of an array, which consists of numbers only.
+//
+// Packed arrays are lazy values, so they are unpacked into Values
+// by the lazy materialization on the first access to their items
+// via Get, GetArray, Array, Set or Del.
+type packedArray struct {
+	// f contains the items.
+	f []float64
+
+	// i contains the items if all of them are integers, which fit int64.
+	i []int64
+}
+
+// parsePackedArray tries parsing the array at the start of s as a packed array.
+//
+// false is returned if the array contains items other than numbers,
+// if it is shorter than ParserOptions.MinPackedArrayLength or if it is invalid.
+// The array must be parsed with parseArray in this case.
+func (p *Parser) parsePackedArray(s string, depth int) (*Value, string, bool) {
+	src := s
+	s = s[1:]
+	nodes := p.l.nodes
+	var f []float64
+	var ints []int64
+	if p.a == nil {
+		f, ints = p.c.fs, p.c.is
+	}
+	fStart, iStart := len(f), len(ints)
+	allInts := true
+	for {
+		s = skipWS(s)
+		if len(s) == 0 || (s[0] != '-' && (s[0] < '0' || s[0] > '9')) {
+			break
+		}
+		if p.l.checkItems(len(f)-fStart+1) != nil || p.l.enter(depth+1) != nil {
+			break
+		}
+		ns, tail, err := parseRawNumber(s)
+		if err != nil || p.l.checkNumber(ns) != nil {
+			break
+		}
+		x, err := fastfloat.Parse(ns)
+		if err != nil {
+			break
+		}
+		if allInts {
+			if n, ok := parseRawInt(ns); ok {
+				ints = arena.SliceAppend(p.a, ints, n)
+			} else {
+				allInts = false
+				ints = ints[:iStart]
+			}
+		}
+		f = arena.SliceAppend(p.a, f, x)
+
+		s = skipWS(tail)
+		if len(s) == 0 {
+			break
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] != ']' || len(f)-fStart < p.l.opts.MinPackedArrayLength {
+			break
+		}
+		s = s[1:]
+
+		pa := p.newPackedArray()
+		pa.f = f[fStart:len(f):len(f)]
+		if allInts {
+			pa.i = ints[iStart:len(ints):len(ints)]
+		}
+		if p.a == nil {
+			p.c.fs, p.c.is = f, ints
+		}
+		v := p.newArray()
+		v.s = src[:len(src)-len(s)]
+		ext := p.getExt(v)
+		ext.lz = p.pk
+		ext.pk = pa
+		return v, s, true
+	}
+	p.l.nodes = nodes
+	return nil, src, false
+}
+
+// parseRawInt parses the raw number s if it is an integer, which fits int64.
+func parseRawInt(s string) (int64, bool) {
+	// Check the syntax first, since ParseInt64 errors are expensive.
+	digits := s
+	if len(digits) > 0 && digits[0] == '-' {
+		digits = digits[1:]
+	}
+	if len(digits) == 0 {
+		return 0, false
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, false
+		}
+	}
+	n, err := fastfloat.ParseInt64(s)
+	return n, err == nil
+}
+
+// newPackedArray returns a new packedArray allocated from p.a
+// or from p.c if p.a is nil.
+//
+// It also initializes p.pk, which is shared by the packed arrays.
+func (p *Parser) newPackedArray() *packedArray {
+	if p.a != nil {
+		if p.pk == nil {
+			p.pk = arena.Allocate[lazyState](p.a)
+			p.pk.a = p.a
+			p.pk.dk = p.l.opts.DuplicateKeys
+			p.pk.in = p.l.opts.Interner
+		}
+		return arena.Allocate[packedArray](p.a)
+	}
+	if p.pk == nil {
+		p.pk = p.c.getLazyState()
+		p.pk.dk = p.l.opts.DuplicateKeys
+		p.pk.in = p.l.opts.Interner
+	}
+	return p.c.getPackedArray()
+}
+
+func (c *cache) getLazyState() *lazyState {
+	if c.lz == nil {
+		c.lz = &lazyState{}
+	}
+	*c.lz = lazyState{}
+	return c.lz
+}
+
+func (c *cache) getPackedArray() *packedArray {
+	if cap(c.pks) > len(c.pks) {
+		c.pks = c.pks[:len(c.pks)+1]
+	} else {
+		c.pks = append(c.pks, packedArray{})
+	}
+	pa := &c.pks[len(c.pks)-1]
+	*pa = packedArray{}
+	return pa
+}
+
+// appendPacked appends the packed array v to dst without whitespace.
+func appendPacked(dst []byte, v *Value) []byte {
+	s := v.s
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t', '\n', '\r':
+		default:
+			dst = append(dst, s[i])
+		}
+	}
+	return dst
+}
+
+// IsPacked returns true if v is an array of numbers packed by the parser.
+//
+// See ParserOptions.MinPackedArrayLength. The array is unpacked into Values
+// on the first access to its items via Get, GetArray, Array, Set or Del.
+func (v *Value) IsPacked() bool {
+	return v != nil && v.packed() != nil
+}
+
+// Float64s returns the items of the array v, which must contain numbers only.
+//
+// The items of packed arrays are returned without memory allocations.
+// The returned slice mustn't be modified and it is valid until Parse
+// is called on the Parser returned v.
+func (v *Value) Float64s() ([]float64, error) {
+	if v.Type() != TypeArray {
+		return nil, fmt.Errorf("value doesn't contain array; it contains %s", v.Type())
+	}
+	if pk := v.packed(); pk != nil {
+		return pk.f, nil
+	}
+	a, err := v.Array()
+	if err != nil {
+		return nil, err
+	}
+	f := make([]float64, len(a))
+	for i, item := range a {
+		x, err := item.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse array item #%d: %w", i, err)
+		}
+		f[i] = x
+	}
+	return f, nil
+}
+
+// Int64s returns the items of the array v, which must contain integers only.
+//
+// The items of packed arrays are returned without memory allocations.
+// The returned slice mustn't be modified and it is valid until Parse
+// is called on the Parser returned v.
+func (v *Value) Int64s() ([]int64, error) {
+	if v.Type() != TypeArray {
+		return nil, fmt.Errorf("value doesn't contain array; it contains %s", v.Type())
+	}
+	if pk := v.packed(); pk != nil {
+		if pk.i == nil {
+			return nil, fmt.Errorf("array contains numbers, which aren't int64")
+		}
+		return pk.i, nil
+	}
+	a, err := v.Array()
+	if err != nil {
+		return nil, err
+	}
+	n := make([]int64, len(a))
+	for i, item := range a {
+		x, err := item.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse array item #%d: %w", i, err)
+		}
+		n[i] = x
+	}
+	return n, nil
+}
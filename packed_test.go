@@ -0,0 +1,198 @@
+package astjson
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestPackedArray(t *testing.T) {
+	f := func(s string, packed bool, expectedFloats []float64, expectedInts []int64) {
+		t.Helper()
+
+		for _, a := range []arena.Arena{nil, arena.NewMonotonicArena()} {
+			p := NewParser(ParserOptions{
+				MinPackedArrayLength: 3,
+			})
+			v, err := p.ParseWithArena(a, s)
+			if err != nil {
+				t.Fatalf("unexpected error when parsing %q: %s", s, err)
+			}
+			arr := v.Get("a")
+			if arr.IsPacked() != packed {
+				t.Fatalf("unexpected IsPacked for %q; got %v; want %v", s, arr.IsPacked(), packed)
+			}
+			floats, err := arr.Float64s()
+			if expectedFloats == nil {
+				if err == nil {
+					t.Fatalf("expecting non-nil error from Float64s for %q", s)
+				}
+			} else if err != nil || !reflect.DeepEqual(floats, expectedFloats) {
+				t.Fatalf("unexpected Float64s for %q; got %v, %v; want %v", s, floats, err, expectedFloats)
+			}
+			ints, err := arr.Int64s()
+			if expectedInts == nil {
+				if err == nil {
+					t.Fatalf("expecting non-nil error from Int64s for %q", s)
+				}
+			} else if err != nil || !reflect.DeepEqual(ints, expectedInts) {
+				t.Fatalf("unexpected Int64s for %q; got %v, %v; want %v", s, ints, err, expectedInts)
+			}
+
+			// The packed array is marshaled without whitespace.
+			expected := MustParse(s).String()
+			if got := v.String(); got != expected {
+				t.Fatalf("unexpected marshaled value for %q; got %s; want %s", s, got, expected)
+			}
+
+			// The packed array is unpacked on access to its items.
+			items := arr.GetArray()
+			if len(items) != len(MustParse(s).GetArray("a")) {
+				t.Fatalf("unexpected number of items for %q; got %d", s, len(items))
+			}
+			if arr.IsPacked() {
+				t.Fatalf("the array must be unpacked after GetArray for %q", s)
+			}
+			if got := v.String(); got != expected {
+				t.Fatalf("unexpected marshaled value after unpacking %q; got %s; want %s", s, got, expected)
+			}
+		}
+	}
+
+	f(`{"a": [1, 2, 3]}`, true, []float64{1, 2, 3}, []int64{1, 2, 3})
+	f(`{"a": [ -1 ,2.5,1e3 ]}`, true, []float64{-1, 2.5, 1000}, nil)
+	f(`{"a": [1, 2, 9223372036854775808]}`, true, []float64{1, 2, 9223372036854775808}, nil)
+	f(`{"a": [[1, 2, 3]]}`, false, nil, nil)
+
+	// Too short arrays.
+	f(`{"a": []}`, false, []float64{}, []int64{})
+	f(`{"a": [1, 2]}`, false, []float64{1, 2}, []int64{1, 2})
+
+	// Arrays with other items.
+	f(`{"a": [1, 2, 3, "4"]}`, false, nil, nil)
+	f(`{"a": [1, 2, 3, null]}`, false, nil, nil)
+	f(`{"a": [1, 2, 3, true]}`, false, nil, nil)
+}
+
+func TestPackedArrayAccess(t *testing.T) {
+	p := NewParser(ParserOptions{
+		MinPackedArrayLength: 2,
+	})
+	v, err := p.Parse(`[[1, 2], [3, 4], [5, 6], [7, 8]]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := v.GetInt("0", "1"); n != 2 {
+		t.Fatalf("unexpected value; got %d; want 2", n)
+	}
+	v.Get("1").SetArrayItem(nil, 2, MustParse(`"x"`))
+	v.Get("2").Del("0")
+	if _, err := v.Get("3").Array(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := v.String(); got != `[[1,2],[3,4,"x"],[6],[7,8]]` {
+		t.Fatalf("unexpected value; got %s", got)
+	}
+
+	// The arrays are unpacked by MergeValues.
+	v, err = p.Parse(`{"a": [1, 2], "b": [3, 4]}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	merged, _, err := MergeValues(nil, v.Get("a"), v.Get("b"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := merged.String(); got != `[3,4]` {
+		t.Fatalf("unexpected merged value; got %s", got)
+	}
+}
+
+func TestPackedArrayLimits(t *testing.T) {
+	f := func(s string, opts ParserOptions) {
+		t.Helper()
+
+		_, expectedErr := NewParser(opts).Parse(s)
+		opts.MinPackedArrayLength = 1
+		_, err := NewParser(opts).Parse(s)
+		if (err == nil) != (expectedErr == nil) || (err != nil && err.Error() != expectedErr.Error()) {
+			t.Fatalf("unexpected error for %q; got %v; want %v", s, err, expectedErr)
+		}
+	}
+
+	f(`[1, 2, 3]`, ParserOptions{MaxArrayLength: 2})
+	f(`[1, 2, 3]`, ParserOptions{MaxNodes: 3})
+	f(`[1, 2, 3]`, ParserOptions{MaxNodes: 4})
+	f(`[1, 22, 3]`, ParserOptions{MaxNumberLength: 1})
+	f(`[1, 01, 3]`, ParserOptions{Strict: true})
+	f(`[1, 2, 3`, ParserOptions{})
+	f(`[1, 2 3]`, ParserOptions{})
+	f(`[1, 2,]`, ParserOptions{})
+}
+
+func TestPackedArrayFixtures(t *testing.T) {
+	p := NewParser(ParserOptions{
+		MinPackedArrayLength: 2,
+	})
+	for _, s := range []string{smallFixture, mediumFixture, largeFixture, canadaFixture, citmFixture, twitterFixture} {
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if got, expected := v.String(), MustParse(s).String(); got != expected {
+			t.Fatalf("unexpected marshaled value for %q", startEndString(s))
+		}
+
+		n := testing.AllocsPerRun(10, func() {
+			if _, err := p.Parse(s); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+		if n != 0 {
+			t.Fatalf("unexpected number of allocations when parsing %q; got %v; want 0", startEndString(s), n)
+		}
+	}
+
+	v, err := p.Parse(canadaFixture)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	coords := v.Get("features", "0", "geometry", "coordinates", "0", "0")
+	if !coords.IsPacked() {
+		t.Fatalf("the coordinates must be packed")
+	}
+	f, err := coords.Float64s()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(f) != 2 || f[0] != coords.GetFloat64("0") || f[1] != coords.GetFloat64("1") {
+		t.Fatalf("unexpected coordinates; got %v", f)
+	}
+}
+
+func BenchmarkParsePackedArrays(b *testing.B) {
+	f := func(b *testing.B, opts ParserOptions) {
+		p := NewParser(opts)
+		a := arena.NewMonotonicArena(arena.WithMinBufferSize(1024 * 1024 * 2))
+		b.SetBytes(int64(len(canadaFixture)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			v, err := p.ParseWithArena(a, canadaFixture)
+			if err != nil {
+				b.Fatalf("cannot parse json: %s", err)
+			}
+			_ = v.GetArray("features", "0", "geometry", "coordinates", "0", "0")
+			a.Reset()
+		}
+	}
+	b.Run("unpacked", func(b *testing.B) {
+		f(b, ParserOptions{})
+	})
+	b.Run("packed", func(b *testing.B) {
+		f(b, ParserOptions{
+			MinPackedArrayLength: 2,
+		})
+	})
+}
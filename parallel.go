@@ -0,0 +1,156 @@
+package astjson
+
+import (
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/wundergraph/go-arena"
+)
+
+// parallelBatchSize is the number of array items parsed by a goroutine at once.
+const parallelBatchSize = 64
+
+// ParallelParse parses s containing JSON on multiple goroutines.
+//
+// If s contains a top-level array, its items are parsed concurrently
+// by len(arenas) goroutines. Every goroutine allocates the parsed items
+// from its own arena. The top-level array is allocated from arenas[0].
+// Other values are parsed sequentially with arenas[0].
+//
+// Nil arenas allocate from the heap. The top-level array is allocated
+// from the heap if any of arenas is nil. If arenas is empty,
+// runtime.GOMAXPROCS(0) goroutines allocate from the heap.
+//
+// The returned errors are identical to the errors returned by Parser.
+//
+// The returned value is valid until any of arenas is reset.
+func ParallelParse(arenas []arena.Arena, s string) (*Value, error) {
+	if len(arenas) == 0 {
+		arenas = make([]arena.Arena, runtime.GOMAXPROCS(0))
+	}
+	if v := parseArrayParallel(arenas, s); v != nil {
+		return v, nil
+	}
+	// Either s doesn't contain an array or it is invalid.
+	// Parse it sequentially in order to obtain the value or the error.
+	var p Parser
+	return p.ParseWithArena(arenas[0], s)
+}
+
+// ParallelParseBytes parses b containing JSON on multiple goroutines.
+//
+// See ParallelParse for details.
+func ParallelParseBytes(arenas []arena.Arena, b []byte) (*Value, error) {
+	return ParallelParse(arenas, b2s(b))
+}
+
+// parseArrayParallel parses the top-level array in s on len(arenas) goroutines.
+//
+// nil is returned if s doesn't contain a valid array.
+func parseArrayParallel(arenas []arena.Arena, s string) *Value {
+	s = skipWS(s)
+	if len(s) == 0 || s[0] != '[' {
+		return nil
+	}
+	items, ok := splitArrayItems(s[1:])
+	if !ok {
+		return nil
+	}
+
+	a := arenas[0]
+	if slices.Contains(arenas, nil) {
+		// Arena memory isn't scanned by the GC, so the items allocated
+		// from the heap must be referenced by the heap-allocated array.
+		a = nil
+	}
+	arr := arena.Allocate[Value](a)
+	arr.t = TypeArray
+	arr.x = arena.Allocate[node](a)
+	arr.x.a = arena.AllocateSlice[*Value](a, len(items), len(items))
+
+	var next atomic.Int64
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	workers := min(len(arenas), (len(items)+parallelBatchSize-1)/parallelBatchSize)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(a arena.Arena) {
+			defer wg.Done()
+			p := Parser{
+				a: a,
+			}
+			for !failed.Load() {
+				start := int(next.Add(parallelBatchSize)) - parallelBatchSize
+				if start >= len(items) {
+					return
+				}
+				end := min(start+parallelBatchSize, len(items))
+				for j := start; j < end; j++ {
+					// The top-level array has depth 1.
+					// Items are parsed up to the end of their string, so parseValue
+					// must reject them there like before the following delimiter,
+					// e.g. a lone sign.
+					v, tail, err := p.parseValue(skipWS(items[j]), 1)
+					if err != nil || len(skipWS(tail)) > 0 {
+						failed.Store(true)
+						return
+					}
+					arr.x.a[j] = v
+				}
+			}
+		}(arenas[i])
+	}
+	wg.Wait()
+
+	if failed.Load() {
+		return nil
+	}
+	return arr
+}
+
+// splitArrayItems returns the raw items of the array, which starts at s
+// right after the opening '['.
+//
+// Item boundaries are found without validating the items.
+// false is returned if the closing ']' isn't found or it is followed
+// by non-whitespace chars.
+func splitArrayItems(s string) ([]string, bool) {
+	s = skipWS(s)
+	if len(s) > 0 && s[0] == ']' {
+		return nil, len(skipWS(s[1:])) == 0
+	}
+
+	var items []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			_, tail, err := parseRawString(s[i+1:])
+			if err != nil {
+				return nil, false
+			}
+			i = len(s) - len(tail) - 1
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth > 0 {
+				depth--
+				continue
+			}
+			if s[i] != ']' {
+				return nil, false
+			}
+			items = append(items, s[start:i])
+			return items, len(skipWS(s[i+1:])) == 0
+		case ',':
+			if depth == 0 {
+				items = append(items, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,171 @@
+package astjson
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestParallelParse(t *testing.T) {
+	arenas := []arena.Arena{
+		arena.NewMonotonicArena(),
+		arena.NewMonotonicArena(),
+		arena.NewMonotonicArena(),
+		nil,
+	}
+
+	f := func(s string) {
+		t.Helper()
+		v, err := ParallelParse(arenas, s)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", startEndString(s), err)
+		}
+		if vs, expected := v.String(), MustParse(s).String(); vs != expected {
+			t.Fatalf("unexpected value for %q; got %s; want %s", startEndString(s), vs, expected)
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		f(`[]`)
+		f(` [ ] `)
+		f(`[1]`)
+		f(`[1, "a,]", {"b": [2, {"c": "]}"}]}, null, true, [[]], "\"]"]`)
+		f(`{"a": [1, 2]}`)
+		f(`"foo"`)
+		f(`123`)
+
+		items := []string{smallFixture, mediumFixture, twitterFixture, `"x"`, `1.5`, `null`, `[]`, `{}`}
+		var sb strings.Builder
+		sb.WriteString("[\n")
+		for i := 0; i < 1000; i++ {
+			if i > 0 {
+				sb.WriteString(" ,\n")
+			}
+			sb.WriteString(items[i%len(items)])
+		}
+		sb.WriteString("\n]\n")
+		f(sb.String())
+	})
+
+	t.Run("heap", func(t *testing.T) {
+		s := `[{"a": 1}, [2, 3], "x"]`
+		v, err := ParallelParse(nil, s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if vs, expected := v.String(), `[{"a":1},[2,3],"x"]`; vs != expected {
+			t.Fatalf("unexpected value; got %s; want %s", vs, expected)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		f := func(s string) {
+			t.Helper()
+			_, err := ParallelParse(arenas, s)
+			if err == nil {
+				t.Fatalf("expecting non-nil error when parsing %q", s)
+			}
+			var p Parser
+			_, expectedErr := p.Parse(s)
+			if err.Error() != expectedErr.Error() {
+				t.Fatalf("unexpected error for %q; got %q; want %q", s, err, expectedErr)
+			}
+		}
+
+		f(``)
+		f(`[`)
+		f(`[1,]`)
+		f(`[,1]`)
+		f(`[1,,2]`)
+		f(`[1 2]`)
+		f(`[1]]`)
+		f(`[1] x`)
+		f(`[{"a": 1]`)
+		f(`[{"a": 1}}, 2]`)
+		f(`[1, "unclosed]`)
+		f(`[1, tru]`)
+		f(`[1, {"a" 1}]`)
+		f(`[1,+]`)
+		f(`[0,"\"0",nan,+]`)
+		f(`[-, 1]`)
+		f(strings.Repeat("[", MaxDepth+1) + strings.Repeat("]", MaxDepth+1))
+
+		s := "[" + strings.Repeat(`{"a": [1, 2]}, `, 500) + `{"a": [1 2]}, ` + strings.Repeat(`{"a": 3}, `, 500) + "1]"
+		f(s)
+	})
+
+	t.Run("mutations", func(t *testing.T) {
+		r := rand.New(rand.NewSource(1))
+		alphabet := []byte(" \"\\{}[]:,a0-+.etfn")
+		src := `[1, -2.5e3, "a,\"]", nan, [true, null], {"b": [-1, {"c": "]}"}]}, false]`
+		for n := 0; n < 5000; n++ {
+			b := []byte(src)
+			for i := 0; i < 1+r.Intn(3); i++ {
+				b[r.Intn(len(b))] = alphabet[r.Intn(len(alphabet))]
+			}
+			s := string(b)
+
+			v, err := ParallelParse(arenas, s)
+			var p Parser
+			expectedV, expectedErr := p.Parse(s)
+			if expectedErr != nil {
+				if err == nil || err.Error() != expectedErr.Error() {
+					t.Fatalf("unexpected error for %q; got %v; want %q", s, err, expectedErr)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %s", s, err)
+			}
+			if vs, expected := v.String(), expectedV.String(); vs != expected {
+				t.Fatalf("unexpected value for %q; got %s; want %s", s, vs, expected)
+			}
+		}
+	})
+}
+
+func BenchmarkParallelParse(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i := 0; i < 10000; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(mediumFixture)
+	}
+	sb.WriteString("]")
+	s := sb.String()
+
+	b.Run("sequential", func(b *testing.B) {
+		var p Parser
+		a := arena.NewMonotonicArena(arena.WithMinBufferSize(1024 * 1024 * 2))
+		b.SetBytes(int64(len(s)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := p.ParseWithArena(a, s); err != nil {
+				b.Fatalf("cannot parse json: %s", err)
+			}
+			a.Reset()
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		arenas := make([]arena.Arena, 8)
+		for i := range arenas {
+			arenas[i] = arena.NewMonotonicArena(arena.WithMinBufferSize(1024 * 1024 * 2))
+		}
+		b.SetBytes(int64(len(s)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := ParallelParse(arenas, s); err != nil {
+				b.Fatalf("cannot parse json: %s", err)
+			}
+			for _, a := range arenas {
+				a.Reset()
+			}
+		}
+	})
+}
@@ -2,6 +2,7 @@ package astjson
 
 import (
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"unicode/utf16"
@@ -10,32 +11,136 @@ import (
 	"github.com/wundergraph/go-arena"
 )
 
-type ParseError struct {
-	Err error
+// Parser parses JSON.
+//
+// Parser may be re-used for subsequent parsing. Values parsed without
+// an arena are allocated from memory owned by Parser, which is re-used
+// by the next Parse* call, so repeated parsing doesn't allocate.
+//
+// Parser cannot be used from concurrent goroutines.
+// Use per-goroutine parsers or ParserPool instead.
+type Parser struct {
+	// a is the arena used by the current Parse* call.
+	a arena.Arena
+
+	// lz is set while parsing in lazy mode.
+	lz *lazyState
+
+	// lzValidated is set while materializing lazy values,
+	// which have been validated by ParseLazy.
+	lzValidated bool
+
+	// proj contains the paths selected by ParseWithProjection.
+	proj *projectionNode
+
+	// idx contains the structural index built by ParseIndexed.
+	// It is re-used by subsequent ParseIndexed calls.
+	idx []uint32
+
+	// c contains the nodes re-used by Parse* calls without an arena.
+	c cache
+
+	// l contains the limits passed to NewParser.
+	l limits
+
+	// spans is set if the current Parse* call records spans.
+	// src is the input of the call in this case.
+	spans bool
+	src   string
+
+	// packed is set if the current Parse* call packs arrays of numbers.
+	// pk is the lazyState shared by the packed arrays in this case.
+	packed bool
+	pk     *lazyState
+
+	// path collects the path to the failure point.
+	path errorPath
+}
+
+// cache contains Values, kvs and unescaped strings, which are re-used
+// between Parse* calls without an arena, so repeated parsing of similar
+// documents doesn't allocate.
+type cache struct {
+	vs  []Value
+	ns  []node
+	es  []nodeExt
+	kvs []kv
+	sps []Span
+	ixs []objectIndex
+	pks []packedArray
+
+	// fs and is contain the items of packed arrays.
+	fs []float64
+	is []int64
+
+	// lz is shared by the packed arrays.
+	lz *lazyState
+
+	// b contains the unescaped strings.
+	b []byte
 }
 
-func (p *ParseError) Error() string {
-	if p == nil {
-		return ""
+func (c *cache) reset() {
+	c.vs = c.vs[:0]
+	c.ns = c.ns[:0]
+	c.es = c.es[:0]
+	c.kvs = c.kvs[:0]
+	c.sps = c.sps[:0]
+	c.ixs = c.ixs[:0]
+	c.pks = c.pks[:0]
+	c.fs = c.fs[:0]
+	c.is = c.is[:0]
+	c.b = c.b[:0]
+}
+
+func (c *cache) getValue() *Value {
+	if cap(c.vs) > len(c.vs) {
+		c.vs = c.vs[:len(c.vs)+1]
+	} else {
+		c.vs = append(c.vs, Value{})
 	}
-	return p.Err.Error()
+	v := &c.vs[len(c.vs)-1]
+	*v = Value{}
+	return v
 }
 
-func NewParseError(err error) *ParseError {
-	if err == nil {
-		return nil
+func (c *cache) getNode() *node {
+	if cap(c.ns) > len(c.ns) {
+		c.ns = c.ns[:len(c.ns)+1]
+	} else {
+		c.ns = append(c.ns, node{})
+	}
+	n := &c.ns[len(c.ns)-1]
+	// Re-use the memory of the items of the previously parsed value.
+	*n = node{
+		a: n.a[:0],
+		o: Object{
+			kvs: n.o.kvs[:0],
+		},
 	}
+	return n
+}
 
-	return &ParseError{Err: err}
+func (c *cache) getExt() *nodeExt {
+	if cap(c.es) > len(c.es) {
+		c.es = c.es[:len(c.es)+1]
+	} else {
+		c.es = append(c.es, nodeExt{})
+	}
+	ext := &c.es[len(c.es)-1]
+	*ext = nodeExt{}
+	return ext
 }
 
-// Parser parses JSON.
-//
-// Parser may be re-used for subsequent parsing.
-//
-// Parser cannot be used from concurrent goroutines.
-// Use per-goroutine parsers or ParserPool instead.
-type Parser struct {
+func (c *cache) getKV() *kv {
+	if cap(c.kvs) > len(c.kvs) {
+		c.kvs = c.kvs[:len(c.kvs)+1]
+	} else {
+		c.kvs = append(c.kvs, kv{})
+	}
+	e := &c.kvs[len(c.kvs)-1]
+	*e = kv{}
+	return e
 }
 
 // Parse parses s containing JSON.
@@ -64,20 +169,145 @@ func (p *Parser) ParseBytesWithArena(a arena.Arena, b []byte) (*Value, error) {
 	return p.parse(a, b2s(b))
 }
 
+// ParseReader reads the whole r and parses it as a single JSON value.
+//
+// The input is read into a buffer allocated from a, which grows as needed.
+// A *MaxBytesError is returned if r contains more than maxBytes bytes.
+// ParserOptions.MaxBytes is used if maxBytes <= 0. The size is unlimited
+// only if both maxBytes and ParserOptions.MaxBytes are <= 0.
+//
+// The returned Value has the same lifetime as the one returned
+// by ParseBytesWithArena.
+func (p *Parser) ParseReader(a arena.Arena, r io.Reader, maxBytes int) (*Value, error) {
+	if maxBytes <= 0 {
+		maxBytes = p.l.opts.MaxBytes
+	}
+	b, err := readAll(a, r, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	return p.parse(a, b2s(b))
+}
+
 func (p *Parser) parse(a arena.Arena, s string) (*Value, error) {
-	s = skipWS(s)
+	if err := p.l.checkBytes(s); err != nil {
+		return nil, err
+	}
+	p.a = a
+	p.c.reset()
+	p.l.reset()
+	p.path.reset()
+	defer p.reset()
+
+	if p.l.opts.Spans && p.lz == nil && p.proj == nil {
+		p.spans = true
+		p.src = s
+	}
+	if p.l.opts.MinPackedArrayLength > 0 && p.lz == nil && p.proj == nil && !p.spans {
+		p.packed = true
+	}
+	if p.l.opts.JSON5 {
+		return p.parseJSON5(s)
+	}
 
-	v, tail, err := parseValue(a, s, 0)
+	var v *Value
+	var tail string
+	var err error
+	if p.proj != nil {
+		v, tail, err = p.parseProjected(skipWS(s), 0, p.proj)
+	} else {
+		v, tail, err = p.parseValue(skipWS(s), 0)
+	}
 	if err != nil {
-		return nil, NewParseError(fmt.Errorf("cannot parse JSON: %s; unparsed tail: %q", err, startEndString(tail)))
+		return nil, newParseError(s, tail, err, p.path)
 	}
 	tail = skipWS(tail)
 	if len(tail) > 0 {
-		return nil, NewParseError(fmt.Errorf("unexpected tail: %q", startEndString(tail)))
+		return nil, newParseError(s, tail, errUnexpectedTail, nil)
 	}
 	return v, nil
 }
 
+// newValue returns a new Value allocated from p.a or from p.c if p.a is nil.
+func (p *Parser) newValue() *Value {
+	if p.a != nil {
+		return arena.Allocate[Value](p.a)
+	}
+	return p.c.getValue()
+}
+
+// newNode returns a new node allocated from p.a or from p.c if p.a is nil.
+func (p *Parser) newNode() *node {
+	if p.a != nil {
+		return arena.Allocate[node](p.a)
+	}
+	return p.c.getNode()
+}
+
+// getExt returns the rarely used data of v. It is allocated if v has none.
+func (p *Parser) getExt(v *Value) *nodeExt {
+	if v.x == nil {
+		v.x = p.newNode()
+	}
+	if v.x.ext == nil {
+		if p.a != nil {
+			v.x.ext = arena.Allocate[nodeExt](p.a)
+		} else {
+			v.x.ext = p.c.getExt()
+		}
+	}
+	return v.x.ext
+}
+
+// newArray returns a new empty array.
+func (p *Parser) newArray() *Value {
+	v := p.newValue()
+	v.t = TypeArray
+	v.x = p.newNode()
+	return v
+}
+
+// newObject returns a new empty object.
+func (p *Parser) newObject() *Value {
+	v := p.newValue()
+	v.t = TypeObject
+	v.x = p.newNode()
+	return v
+}
+
+// getKV appends a new item to o and returns it.
+func (p *Parser) getKV(o *Object) *kv {
+	if p.a != nil {
+		return o.getKV(p.a)
+	}
+	kv := p.c.getKV()
+	o.kvs = append(o.kvs, kv)
+	return kv
+}
+
+// unescapeString unescapes s into memory allocated from p.a or from p.c if p.a is nil.
+func (p *Parser) unescapeString(s string) string {
+	n := strings.IndexByte(s, '\\')
+	if n < 0 || p.a != nil {
+		return unescapeStringBestEffort(p.a, s)
+	}
+	start := len(p.c.b)
+	p.c.b = appendUnescapedString(nil, p.c.b, s, n)
+	return b2s(p.c.b[start:])
+}
+
+// reset drops the references to the state of the last Parse* call.
+func (p *Parser) reset() {
+	p.a = nil
+	p.lz = nil
+	p.lzValidated = false
+	p.proj = nil
+	p.spans = false
+	p.src = ""
+	p.packed = false
+	p.pk = nil
+}
+
 func skipWS(s string) string {
 	if len(s) == 0 || s[0] > 0x20 {
 		// Fast path - most common case
@@ -110,19 +340,31 @@ type kv struct {
 	keyUnescaped bool   // 1 byte - tracks if this specific key has been unescaped
 	k            string // 16 bytes
 	v            *Value // 8 bytes
-	// Total: 25 bytes - still fits in cache line
+	// Total: 32 bytes with padding - still fits in cache line
 }
 
 // MaxDepth is the maximum depth for nested JSON.
 const MaxDepth = 300
 
-func parseValue(a arena.Arena, s string, depth int) (*Value, string, error) {
+func (p *Parser) parseValue(s string, depth int) (*Value, string, error) {
+	if p.spans {
+		v, tail, err := p.parseBareValue(s, depth)
+		if err != nil {
+			return nil, tail, err
+		}
+		return p.withSpan(v, s, tail), tail, nil
+	}
+	return p.parseBareValue(s, depth)
+}
+
+// parseBareValue parses the value at the start of s without recording its span.
+func (p *Parser) parseBareValue(s string, depth int) (*Value, string, error) {
 	if len(s) == 0 {
-		return nil, s, fmt.Errorf("cannot parse empty string")
+		return nil, s, errEmptyString
 	}
 	depth++
-	if depth > MaxDepth {
-		return nil, s, fmt.Errorf("too big depth for the nested JSON; it exceeds %d", MaxDepth)
+	if err := p.l.enter(depth); err != nil {
+		return nil, s, err
 	}
 
 	// Branch prediction optimization: order by frequency
@@ -132,26 +374,32 @@ func parseValue(a arena.Arena, s string, depth int) (*Value, string, error) {
 		// String - most common in JSON
 		ss, tail, err := parseRawString(s[1:])
 		if err != nil {
-			return nil, tail, fmt.Errorf("cannot parse string: %s", err)
+			return nil, tail, fmt.Errorf("cannot parse string: %w", err)
 		}
-		v := arena.Allocate[Value](a)
+		if err := p.l.checkString(ss); err != nil {
+			return nil, s, fmt.Errorf("cannot parse string: %w", err)
+		}
+		v := p.newValue()
 		v.t = TypeString
-		v.s = unescapeStringBestEffort(a, ss)
+		v.s = p.internString(p.unescapeString(ss))
 		return v, tail, nil
 	case '{':
 		// Object - very common
-		v, tail, err := parseObject(a, s[1:], depth)
-		if err != nil {
-			return nil, tail, fmt.Errorf("cannot parse object: %s", err)
+		if p.lz != nil && depth > 1 {
+			return p.parseLazy(s, depth, TypeObject)
 		}
-		return v, tail, nil
+		return p.parseObject(s[1:], depth)
 	case '[':
 		// Array - common
-		v, tail, err := parseArray(a, s[1:], depth)
-		if err != nil {
-			return nil, tail, fmt.Errorf("cannot parse array: %s", err)
+		if p.lz != nil && depth > 1 {
+			return p.parseLazy(s, depth, TypeArray)
 		}
-		return v, tail, nil
+		if p.packed {
+			if v, tail, ok := p.parsePackedArray(s, depth); ok {
+				return v, tail, nil
+			}
+		}
+		return p.parseArray(s[1:], depth)
 	case 't':
 		// true literal - less common
 		if len(s) < len("true") || s[:len("true")] != "true" {
@@ -169,7 +417,10 @@ func parseValue(a arena.Arena, s string, depth int) (*Value, string, error) {
 		if len(s) < len("null") || s[:len("null")] != "null" {
 			// Try parsing NaN
 			if len(s) >= 3 && strings.EqualFold(s[:3], "nan") {
-				v := arena.Allocate[Value](a)
+				if err := p.l.checkNumber(s[:3]); err != nil {
+					return nil, s, fmt.Errorf("cannot parse number: %w", err)
+				}
+				v := p.newValue()
 				v.t = TypeNumber
 				v.s = s[:3]
 				return v, s[3:], nil
@@ -181,50 +432,55 @@ func parseValue(a arena.Arena, s string, depth int) (*Value, string, error) {
 		// Number - very common, but handled last due to complex parsing
 		ns, tail, err := parseRawNumber(s)
 		if err != nil {
-			return nil, tail, fmt.Errorf("cannot parse number: %s", err)
+			return nil, tail, fmt.Errorf("cannot parse number: %w", err)
 		}
-		v := arena.Allocate[Value](a)
+		if err := p.l.checkNumber(ns); err != nil {
+			return nil, s, fmt.Errorf("cannot parse number: %w", err)
+		}
+		v := p.newValue()
 		v.t = TypeNumber
 		v.s = ns
 		return v, tail, nil
 	}
 }
 
-func parseArray(a arena.Arena, s string, depth int) (*Value, string, error) {
+func (p *Parser) parseArray(s string, depth int) (*Value, string, error) {
 	s = skipWS(s)
 	if len(s) == 0 {
-		return nil, s, fmt.Errorf("missing ']'")
+		return nil, s, errMissingArrayEnd
 	}
 
 	if s[0] == ']' {
-		v := arena.Allocate[Value](a)
-		v.t = TypeArray
-		v.a = v.a[:0]
+		v := p.newArray()
 		return v, s[1:], nil
 	}
 
-	arr := arena.Allocate[Value](a)
-	arr.t = TypeArray
-	arr.a = arr.a[:0]
+	arr := p.newArray()
+	// Keep the items in a local variable in the hot loop.
+	a := arr.x.a
 	for {
 		var v *Value
 		var err error
 
 		s = skipWS(s)
-		v, s, err = parseValue(a, s, depth)
+		if err := p.l.checkItems(len(a) + 1); err != nil {
+			return nil, s, err
+		}
+		v, s, err = p.parseValue(s, depth)
 		if err != nil {
-			return nil, s, fmt.Errorf("cannot parse array value: %s", err)
+			p.path.pushIndex(len(a))
+			return nil, s, err
 		}
-		if arr.a == nil {
-			arr.a = arena.AllocateSlice[*Value](a, 1, 1)
-			arr.a[0] = v
+		if a == nil {
+			a = arena.AllocateSlice[*Value](p.a, 1, 1)
+			a[0] = v
 		} else {
-			arr.a = arena.SliceAppend(a, arr.a, v)
+			a = arena.SliceAppend(p.a, a, v)
 		}
 
 		s = skipWS(s)
 		if len(s) == 0 {
-			return nil, s, fmt.Errorf("unexpected end of array")
+			return nil, s, errArrayEnd
 		}
 		if s[0] == ',' {
 			s = s[1:]
@@ -232,65 +488,82 @@ func parseArray(a arena.Arena, s string, depth int) (*Value, string, error) {
 		}
 		if s[0] == ']' {
 			s = s[1:]
+			arr.x.a = a
 			return arr, s, nil
 		}
-		return nil, s, fmt.Errorf("missing ',' after array value")
+		return nil, s, errMissingArrayComma
 	}
 }
 
-func parseObject(a arena.Arena, s string, depth int) (*Value, string, error) {
+func (p *Parser) parseObject(s string, depth int) (*Value, string, error) {
 	s = skipWS(s)
 	if len(s) == 0 {
-		return nil, s, fmt.Errorf("missing '}'")
+		return nil, s, errMissingObjectEnd
 	}
 
 	if s[0] == '}' {
-		v := arena.Allocate[Value](a)
-		v.t = TypeObject
-		v.o.reset()
+		v := p.newObject()
 		return v, s[1:], nil
 	}
 
-	o := arena.Allocate[Value](a)
-	o.t = TypeObject
-	o.o.reset()
+	o := p.newObject()
 	for {
 		var err error
-		kv := o.o.getKV(a)
+		if err = p.l.checkMembers(len(o.x.o.kvs) + 1); err != nil {
+			return nil, s, err
+		}
+		kv := p.getKV(&o.x.o)
 
 		// Parse key.
 		s = skipWS(s)
 		if len(s) == 0 || s[0] != '"' {
-			return nil, s, fmt.Errorf(`cannot find opening '"" for object key`)
+			return nil, s, errMissingKeyQuote
 		}
+		ks := s
 		kv.k, s, err = parseRawKey(s[1:])
 		if err != nil {
-			return nil, s, fmt.Errorf("cannot parse object key: %s", err)
+			return nil, s, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		if err = p.l.checkString(kv.k); err != nil {
+			return nil, ks, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		p.internKey(kv)
+		var ksp *Span
+		if p.spans {
+			ksp = p.newSpan(ks, s)
 		}
 		s = skipWS(s)
 		if len(s) == 0 || s[0] != ':' {
-			return nil, s, fmt.Errorf("missing ':' after object key")
+			return nil, s, errMissingColon
 		}
 		s = s[1:]
 
 		// Parse value
 		s = skipWS(s)
-		kv.v, s, err = parseValue(a, s, depth)
+		kv.v, s, err = p.parseValue(s, depth)
 		if err != nil {
-			return nil, s, fmt.Errorf("cannot parse object value: %s", err)
+			p.path.pushRawKey(kv.k)
+			return nil, s, err
+		}
+		if ksp != nil {
+			p.setKeySpan(kv, ksp)
 		}
 		s = skipWS(s)
 		if len(s) == 0 {
-			return nil, s, fmt.Errorf("unexpected end of object")
+			return nil, s, errObjectEnd
 		}
 		if s[0] == ',' {
 			s = s[1:]
 			continue
 		}
 		if s[0] == '}' {
+			if err = p.dedupKeys(&o.x.o); err != nil {
+				return nil, s, err
+			}
+			p.indexObject(&o.x.o)
 			return o, s[1:], nil
 		}
-		return nil, s, fmt.Errorf("missing ',' after object value")
+		return nil, s, errMissingObjectComma
 	}
 }
 
@@ -371,7 +644,13 @@ func unescapeStringBestEffort(a arena.Arena, s string) string {
 	// Estimate capacity to avoid frequent reallocations
 	estimatedCap := len(s) + 4
 	b := arena.AllocateSlice[byte](a, 0, estimatedCap)
+	return b2s(appendUnescapedString(a, b, s, n))
+}
 
+// appendUnescapedString appends the unescaped s to b and returns the result.
+//
+// n must be the index of the first backslash in s.
+func appendUnescapedString(a arena.Arena, b []byte, s string, n int) []byte {
 	// Add the initial part before the first escape
 	b = arena.SliceAppend(a, b, []byte(s[:n])...)
 	s = s[n+1:]
@@ -443,7 +722,7 @@ func unescapeStringBestEffort(a arena.Arena, s string) string {
 		b = arena.SliceAppend(a, b, []byte(s[:n])...)
 		s = s[n+1:]
 	}
-	return b2s(b)
+	return b
 }
 
 // parseRawKey is similar to parseRawString, but is optimized
@@ -459,13 +738,13 @@ func parseRawKey(s string) (string, string, error) {
 			return parseRawString(s)
 		}
 	}
-	return s, "", fmt.Errorf(`missing closing '"'`)
+	return s, "", errMissingQuote
 }
 
 func parseRawString(s string) (string, string, error) {
 	n := strings.IndexByte(s, '"')
 	if n < 0 {
-		return s, "", fmt.Errorf(`missing closing '"'`)
+		return s, "", errMissingQuote
 	}
 	if n == 0 || s[n-1] != '\\' {
 		// Fast path. No escaped ".
@@ -486,7 +765,7 @@ func parseRawString(s string) (string, string, error) {
 
 		n = strings.IndexByte(s, '"')
 		if n < 0 {
-			return ss, "", fmt.Errorf(`missing closing '"'`)
+			return ss, "", errMissingQuote
 		}
 		if n == 0 || s[n-1] != '\\' {
 			return ss[:len(ss)-len(s)+n], s[n+1:], nil
@@ -516,6 +795,10 @@ func parseRawNumber(s string) (string, string, error) {
 		s = s[i:]
 		return ns, s, nil
 	}
+	if len(s) == 1 && (s[0] == '-' || s[0] == '+') {
+		// A sign without digits.
+		return "", s, fmt.Errorf("unexpected char: %q", s[:1])
+	}
 	return s, "", nil
 }
 
@@ -527,11 +810,15 @@ func parseRawNumber(s string) (string, string, error) {
 // Cache-friendly layout: hot data first
 type Object struct {
 	kvs []*kv // HOT: frequently accessed - 24 bytes
-	// Total: 24 bytes - compact and cache-friendly
+
+	// ix is the hash index of large objects.
+	ix *objectIndex // COLD: 8 bytes
+	// Total: 32 bytes - compact and cache-friendly
 }
 
 func (o *Object) reset() {
 	o.kvs = o.kvs[:0]
+	o.ix = nil
 }
 
 // MarshalTo appends marshaled o to dst and returns the result.
@@ -599,6 +886,13 @@ func (o *Object) Get(key string) *Value {
 		return nil
 	}
 
+	if o.ix != nil {
+		if i := o.lookup(key); i >= 0 {
+			return o.kvs[i].v
+		}
+		return nil
+	}
+
 	// Fast path - try searching for the key without unescaping if the key doesn't contain escapes
 	if strings.IndexByte(key, '\\') < 0 {
 		for _, kv := range o.kvs {
@@ -644,25 +938,101 @@ func (o *Object) Visit(f func(key []byte, v *Value)) {
 // Value cannot be used from concurrent goroutines.
 // Use per-goroutine parsers or ParserPool instead.
 //
-// Cache-friendly layout: hot data first, compact structure
+// Value is a compact tagged node, since most of the parsed values
+// are strings and numbers. The payload of arrays and objects is kept
+// in a separate node.
 type Value struct {
-	t Type     // HOT: accessed on every operation - 8 bytes
-	s string   // HOT: frequently accessed for strings/numbers - 16 bytes
-	a []*Value // HOT: frequently accessed for arrays - 24 bytes
-	o Object   // COLD: less frequently accessed - 25 bytes
-	// Total: 73 bytes - compact and cache-friendly
+	t Type // 8 bytes
+
+	// s contains strings and numbers. It contains the raw JSON
+	// of lazy and packed objects and arrays.
+	s string // 16 bytes
+
+	// x is set for all the objects and arrays. It is also set for other
+	// values, which carry spans or are incomplete.
+	x *node // 8 bytes
+	// Total: 32 bytes - two values per cache line
+}
+
+// node contains the payload of objects and arrays.
+type node struct {
+	a []*Value
+	o Object
+
+	// ext is set for values, which carry rarely used data.
+	ext *nodeExt
+}
+
+// nodeExt contains the rarely used data of values.
+type nodeExt struct {
+	// lz is set for objects and arrays, which haven't been parsed yet
+	// by ParseLazy. Value.s contains their raw JSON in this case.
+	lz *lazyState
+
+	// pk is set for arrays of numbers packed by the parser.
+	// lz is set for them too, so they are unpacked like lazy values.
+	pk *packedArray
+
+	// sp is set if the parser records spans.
+	sp *Span
+
+	// ksp is set for object member values if the parser records spans.
+	// It is the span of the key of the member kv, which contains the value.
+	// Key spans are kept here, so kv doesn't grow when spans are off.
+	ksp *Span
+	kv  *kv
+
+	// incomplete is set for values of the prefix parsed by ParsePartial,
+	// which may change when more input arrives.
+	incomplete bool
+
+	// err is set if the lazy value created by RawValue cannot be parsed.
+	err error
+}
+
+// lazy returns true if v is a lazy or packed value, which must be
+// materialized before accessing its items.
+func (v *Value) lazy() bool {
+	ext := v.ext()
+	return ext != nil && ext.lz != nil
+}
+
+// packed returns the packed items of v or nil if v isn't packed.
+func (v *Value) packed() *packedArray {
+	ext := v.ext()
+	if ext == nil {
+		return nil
+	}
+	return ext.pk
+}
+
+// ext returns the rarely used data of v or nil if v has none.
+func (v *Value) ext() *nodeExt {
+	if v.x == nil {
+		return nil
+	}
+	return v.x.ext
 }
 
 // MarshalTo appends marshaled v to dst and returns the result.
 func (v *Value) MarshalTo(dst []byte) []byte {
 	switch v.t {
 	case TypeObject:
-		return v.o.MarshalTo(dst)
+		if v.lazy() {
+			return append(dst, v.s...)
+		}
+		return v.x.o.MarshalTo(dst)
 	case TypeArray:
+		if v.lazy() {
+			if v.x.ext.pk != nil {
+				return appendPacked(dst, v)
+			}
+			return append(dst, v.s...)
+		}
 		dst = append(dst, '[')
-		for i, vv := range v.a {
+		for i, vv := range v.x.a {
 			dst = vv.MarshalTo(dst)
-			if i != len(v.a)-1 {
+			if i != len(v.x.a)-1 {
 				dst = append(dst, ',')
 			}
 		}
@@ -773,18 +1143,21 @@ func (v *Value) Get(keys ...string) *Value {
 		return nil
 	}
 	for _, key := range keys {
+		if v.lazy() && v.materialize() != nil {
+			return nil
+		}
 		switch v.t {
 		case TypeObject:
-			v = v.o.Get(key)
+			v = v.x.o.Get(key)
 			if v == nil {
 				return nil
 			}
 		case TypeArray:
 			n, err := strconv.Atoi(key)
-			if err != nil || n < 0 || n >= len(v.a) {
+			if err != nil || n < 0 || n >= len(v.x.a) {
 				return nil
 			}
-			v = v.a[n]
+			v = v.x.a[n]
 		default:
 			return nil
 		}
@@ -804,7 +1177,10 @@ func (v *Value) GetObject(keys ...string) *Object {
 	if v == nil || v.t != TypeObject {
 		return nil
 	}
-	return &v.o
+	if v.lazy() && v.materialize() != nil {
+		return nil
+	}
+	return &v.x.o
 }
 
 // GetArray returns array value by the given keys path.
@@ -819,7 +1195,10 @@ func (v *Value) GetArray(keys ...string) []*Value {
 	if v == nil || v.t != TypeArray {
 		return nil
 	}
-	return v.a
+	if v.lazy() && v.materialize() != nil {
+		return nil
+	}
+	return v.x.a
 }
 
 // GetFloat64 returns float64 value by the given keys path.
@@ -934,7 +1313,12 @@ func (v *Value) Object() (*Object, error) {
 	if v.t != TypeObject {
 		return nil, fmt.Errorf("value doesn't contain object; it contains %s", v.Type())
 	}
-	return &v.o, nil
+	if v.lazy() {
+		if err := v.materialize(); err != nil {
+			return nil, err
+		}
+	}
+	return &v.x.o, nil
 }
 
 // Array returns the underlying JSON array for the v.
@@ -946,7 +1330,12 @@ func (v *Value) Array() ([]*Value, error) {
 	if v.t != TypeArray {
 		return nil, fmt.Errorf("value doesn't contain array; it contains %s", v.Type())
 	}
-	return v.a, nil
+	if v.lazy() {
+		if err := v.materialize(); err != nil {
+			return nil, err
+		}
+	}
+	return v.x.a, nil
 }
 
 // StringBytes returns the underlying JSON string for the v.
@@ -1,11 +1,14 @@
 package astjson
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
+	"unsafe"
 
 	"github.com/wundergraph/go-arena"
 )
@@ -179,6 +182,18 @@ func TestParseRawString(t *testing.T) {
 	})
 }
 
+func TestValueSize(t *testing.T) {
+	if unsafe.Sizeof(uintptr(0)) != 8 {
+		t.Skip("the size is checked on 64-bit platforms only")
+	}
+	if n := unsafe.Sizeof(Value{}); n != 32 {
+		t.Fatalf("unexpected Value size; got %d; want 32", n)
+	}
+	if n := unsafe.Sizeof(kv{}); n != 32 {
+		t.Fatalf("unexpected kv size; got %d; want 32", n)
+	}
+}
+
 func TestValueInvalidTypeConversion(t *testing.T) {
 	var p Parser
 
@@ -1448,6 +1463,69 @@ func TestParseBytesWithArena(t *testing.T) {
 	})
 }
 
+func TestParseReader(t *testing.T) {
+	var p Parser
+	a := arena.NewMonotonicArena()
+
+	t.Run("success", func(t *testing.T) {
+		v, err := p.ParseReader(a, strings.NewReader(`{"foo": [1, "bar"]}`), 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if s := v.String(); s != `{"foo":[1,"bar"]}` {
+			t.Fatalf("unexpected value; got %s; want %s", s, `{"foo":[1,"bar"]}`)
+		}
+	})
+
+	t.Run("small reads", func(t *testing.T) {
+		s := strings.Repeat(`{"a":"b"},`, 1000)
+		s = "[" + s[:len(s)-1] + "]"
+		v, err := p.ParseReader(a, iotest.OneByteReader(strings.NewReader(s)), len(s))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if n := len(v.GetArray()); n != 1000 {
+			t.Fatalf("unexpected array length; got %d; want %d", n, 1000)
+		}
+	})
+
+	t.Run("nil arena", func(t *testing.T) {
+		v, err := ParseReader(nil, strings.NewReader(`"foo"`), 5)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if sb := v.GetStringBytes(); string(sb) != "foo" {
+			t.Fatalf("unexpected string; got %q; want %q", sb, "foo")
+		}
+	})
+
+	t.Run("max bytes exceeded", func(t *testing.T) {
+		_, err := p.ParseReader(a, strings.NewReader(`[1,2,3]`), 6)
+		var mbe *MaxBytesError
+		if !errors.As(err, &mbe) {
+			t.Fatalf("expecting MaxBytesError; got %v", err)
+		}
+		if mbe.Limit != 6 {
+			t.Fatalf("unexpected limit; got %d; want %d", mbe.Limit, 6)
+		}
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		_, err := p.ParseReader(a, iotest.ErrReader(iotest.ErrTimeout), 0)
+		if !errors.Is(err, iotest.ErrTimeout) {
+			t.Fatalf("unexpected error; got %v; want %v", err, iotest.ErrTimeout)
+		}
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, err := p.ParseReader(a, strings.NewReader(`{"foo"`), 0)
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("expecting ParseError; got %v", err)
+		}
+	})
+}
+
 // TestSkipWSSlow tests the slow whitespace skipping path
 func TestSkipWSSlow(t *testing.T) {
 	t.Run("all whitespace types", func(t *testing.T) {
@@ -1656,7 +1734,7 @@ func TestObjectGetEdgeCases(t *testing.T) {
 		}
 		// Check that the specific key was unescaped
 		found := false
-		for _, kv := range v.o.kvs {
+		for _, kv := range v.x.o.kvs {
 			if kv.k == "key\\with\\escapes" && kv.keyUnescaped {
 				found = true
 				break
@@ -2102,3 +2180,51 @@ func TestGetIntGetUintOverflow(t *testing.T) {
 		}
 	})
 }
+
+func TestParserCache(t *testing.T) {
+	var p Parser
+
+	t.Run("reuse", func(t *testing.T) {
+		f := func(s string) {
+			t.Helper()
+			v, err := p.Parse(s)
+			if err != nil {
+				t.Fatalf("unexpected error when parsing %q: %s", s, err)
+			}
+			if vs, expected := v.String(), MustParse(s).String(); vs != expected {
+				t.Fatalf("unexpected value for %q; got %s; want %s", s, vs, expected)
+			}
+		}
+
+		f(`{"a": [1, 2, 3], "b": {"c": "d\n"}, "e": "fA"}`)
+		f(`[{"x": 1}, [], {}, "y\"z"]`)
+		f(`{"a": [], "b": {}}`)
+		f(`[[[1]], {"a": {"b": [true, null]}}]`)
+		f(`"foo"`)
+		for _, s := range []string{smallFixture, mediumFixture, largeFixture, canadaFixture, citmFixture, twitterFixture} {
+			f(s)
+		}
+
+		// Lazy values must not leak into subsequent parsing.
+		if _, err := p.ParseLazy(nil, `{"a": [1, 2], "b": {"c": 3}}`); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		f(`{"a": [1, 2], "b": {"c": 3}}`)
+	})
+
+	t.Run("allocs", func(t *testing.T) {
+		for _, s := range []string{smallFixture, mediumFixture, largeFixture, canadaFixture, citmFixture, twitterFixture} {
+			if _, err := p.Parse(s); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			n := testing.AllocsPerRun(10, func() {
+				if _, err := p.Parse(s); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			})
+			if n != 0 {
+				t.Fatalf("unexpected number of allocations when parsing %q; got %v; want 0", startEndString(s), n)
+			}
+		}
+	})
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/wundergraph/go-arena"
 )
 
 func BenchmarkParseRawString(b *testing.B) {
@@ -99,7 +101,6 @@ func BenchmarkObjectGet(b *testing.B) {
 }
 
 func benchmarkObjectGet(b *testing.B, itemsCount, lookupsCount int) {
-	var benchPool Parser
 	b.StopTimer()
 	var ss []string
 	for i := 0; i < itemsCount; i++ {
@@ -114,6 +115,8 @@ func benchmarkObjectGet(b *testing.B, itemsCount, lookupsCount int) {
 	b.SetBytes(int64(len(s)))
 
 	b.RunParallel(func(pb *testing.PB) {
+		// Parser cannot be used from concurrent goroutines.
+		var benchPool Parser
 		for pb.Next() {
 			v, err := benchPool.Parse(s)
 			if err != nil {
@@ -211,13 +214,17 @@ func benchmarkParse(b *testing.B, s string) {
 	b.Run("fastjson-get", func(b *testing.B) {
 		benchmarkFastJSONParseGet(b, s)
 	})
+	b.Run("fastjson-indexed", func(b *testing.B) {
+		benchmarkFastJSONParseIndexed(b, s)
+	})
 }
 
 func benchmarkFastJSONParse(b *testing.B, s string) {
-	var benchPool Parser
 	b.ReportAllocs()
 	b.SetBytes(int64(len(s)))
 	b.RunParallel(func(pb *testing.PB) {
+		// Parser cannot be used from concurrent goroutines.
+		var benchPool Parser
 		for pb.Next() {
 			v, err := benchPool.Parse(s)
 			if err != nil {
@@ -230,11 +237,30 @@ func benchmarkFastJSONParse(b *testing.B, s string) {
 	})
 }
 
+func benchmarkFastJSONParseIndexed(b *testing.B, s string) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(s)))
+	b.RunParallel(func(pb *testing.PB) {
+		// Parser cannot be used from concurrent goroutines.
+		var benchPool Parser
+		for pb.Next() {
+			v, err := benchPool.ParseIndexed(nil, s)
+			if err != nil {
+				panic(fmt.Errorf("unexpected error: %s", err))
+			}
+			if v.Type() != TypeObject {
+				panic(fmt.Errorf("unexpected value type; got %s; want %s", v.Type(), TypeObject))
+			}
+		}
+	})
+}
+
 func benchmarkFastJSONParseGet(b *testing.B, s string) {
-	var benchPool Parser
 	b.ReportAllocs()
 	b.SetBytes(int64(len(s)))
 	b.RunParallel(func(pb *testing.PB) {
+		// Parser cannot be used from concurrent goroutines.
+		var benchPool Parser
 		var n int
 		for pb.Next() {
 			v, err := benchPool.Parse(s)
@@ -317,3 +343,67 @@ func benchmarkStdJSONParseEmptyStruct(b *testing.B, s string) {
 		}
 	})
 }
+
+func BenchmarkParseError(b *testing.B) {
+	for _, s := range []string{
+		strings.Repeat(`[`, MaxDepth) + "x",
+		strings.Repeat(`{"a":`, MaxDepth+1),
+	} {
+		b.Run(s[:5], func(b *testing.B) {
+			benchmarkParseError(b, s)
+		})
+	}
+}
+
+func benchmarkParseError(b *testing.B, s string) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(s)))
+	b.RunParallel(func(pb *testing.PB) {
+		var p Parser
+		for pb.Next() {
+			if _, err := p.Parse(s); err == nil {
+				panic(fmt.Errorf("expecting non-nil error for %q", s))
+			}
+		}
+	})
+}
+
+func BenchmarkParseArenaUsage(b *testing.B) {
+	for _, fixture := range []struct {
+		name string
+		s    string
+	}{
+		{"small", smallFixture},
+		{"medium", mediumFixture},
+		{"large", largeFixture},
+		{"canada", canadaFixture},
+		{"citm", citmFixture},
+		{"twitter", twitterFixture},
+	} {
+		b.Run(fixture.name, func(b *testing.B) {
+			benchmarkParseArenaUsage(b, fixture.s)
+		})
+	}
+}
+
+func benchmarkParseArenaUsage(b *testing.B, s string) {
+	var p Parser
+	a := arena.NewMonotonicArena(arena.WithMinBufferSize(1024 * 1024 * 4))
+	b.SetBytes(int64(len(s)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	n := 0
+	for i := 0; i < b.N; i++ {
+		v, err := p.ParseWithArena(a, s)
+		if err != nil {
+			b.Fatalf("cannot parse json: %s", err)
+		}
+		if v.Type() != TypeObject && v.Type() != TypeArray {
+			b.Fatalf("unexpected value type: %s", v.Type())
+		}
+		n = a.(interface{ Len() int }).Len()
+		a.Reset()
+	}
+	// Report the arena memory used per parsed document.
+	b.ReportMetric(float64(n), "arena-B/op")
+}
@@ -0,0 +1,324 @@
+package astjson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/wundergraph/go-arena"
+)
+
+// ParsePartial parses the prefix of a JSON document, which is still
+// being received, for instance from a streamed LLM response.
+//
+// The returned Value is the tree implied by the prefix:
+//
+//   - Unterminated strings are closed. An incomplete escape sequence
+//     or UTF-8 char at the end of the string is dropped.
+//   - Unterminated objects and arrays are closed.
+//   - Object members without a value, including incomplete keys, are dropped.
+//   - Incomplete true, false, null, Inf and NaN literals are dropped.
+//   - Trailing '-', '+', '.', 'e' and 'E' chars are dropped from the number
+//     at the end of the prefix.
+//
+// The values, which may still change when more input arrives, are marked
+// as incomplete. See Value.IsComplete. Parsing a longer prefix of the same
+// document extends the returned tree: complete values stay unchanged,
+// while incomplete strings, objects and arrays may only grow.
+//
+// nil Value is returned if the prefix doesn't contain a value yet.
+// ParseError is returned if the prefix isn't a prefix of valid JSON.
+//
+// Values are allocated from a. The returned Value references prefix.
+func ParsePartial(a arena.Arena, prefix string) (*Value, error) {
+	pp := partialParser{
+		p: Parser{
+			a: a,
+		},
+	}
+	v, tail, err := pp.parseValue(skipWS(prefix), 0)
+	if err != nil {
+		return nil, newParseError(prefix, tail, err, pp.p.path)
+	}
+	tail = skipWS(tail)
+	if len(tail) > 0 {
+		return nil, newParseError(prefix, tail, errUnexpectedTail, nil)
+	}
+	return v, nil
+}
+
+// ParsePartialBytes parses the prefix of a JSON document.
+//
+// See ParsePartial for details.
+func ParsePartialBytes(a arena.Arena, prefix []byte) (*Value, error) {
+	return ParsePartial(a, b2s(prefix))
+}
+
+// IsComplete returns false if the v has been parsed by ParsePartial
+// and may change when more input arrives.
+//
+// Objects and arrays are incomplete if they are unterminated.
+// Strings are incomplete if they are unterminated, numbers are incomplete
+// if they end the parsed prefix.
+func (v *Value) IsComplete() bool {
+	ext := v.ext()
+	return ext == nil || !ext.incomplete
+}
+
+// partialParser holds the state of a single ParsePartial call.
+type partialParser struct {
+	p Parser
+}
+
+// parseValue parses the value at the start of s.
+//
+// nil Value and empty tail are returned if s ends before the value is known.
+// Incomplete values are returned with empty tail.
+func (pp *partialParser) parseValue(s string, depth int) (*Value, string, error) {
+	if len(s) == 0 {
+		return nil, s, nil
+	}
+	depth++
+	if err := pp.p.l.enter(depth); err != nil {
+		return nil, s, err
+	}
+
+	switch s[0] {
+	case '"':
+		v := pp.p.newValue()
+		v.t = TypeString
+		ss, tail, err := parseRawString(s[1:])
+		if err != nil {
+			v.s = pp.p.unescapeString(trimPartialString(ss))
+			pp.p.getExt(v).incomplete = true
+			return v, "", nil
+		}
+		v.s = pp.p.unescapeString(ss)
+		return v, tail, nil
+	case '{':
+		return pp.parseObject(s[1:], depth)
+	case '[':
+		return pp.parseArray(s[1:], depth)
+	case 't':
+		return parsePartialLiteral(s, "true", valueTrue)
+	case 'f':
+		return parsePartialLiteral(s, "false", valueFalse)
+	case 'n':
+		if len(s) > 1 && s[1]|0x20 == 'a' {
+			return pp.parseNumber(s)
+		}
+		return parsePartialLiteral(s, "null", valueNull)
+	default:
+		return pp.parseNumber(s)
+	}
+}
+
+// parseNumber parses the number at the start of s.
+//
+// Like Parser, it accepts Inf and NaN in any case.
+func (pp *partialParser) parseNumber(s string) (*Value, string, error) {
+	ns, tail, err := parseRawNumber(s)
+	if err != nil {
+		if isPartialSpecialNumber(s) {
+			return nil, "", nil
+		}
+		return nil, tail, fmt.Errorf("cannot parse number: %w", err)
+	}
+	v := pp.p.newValue()
+	v.t = TypeNumber
+	v.s = ns
+	if len(tail) == 0 {
+		if c := ns[len(ns)-1] | 0x20; c == 'f' || c == 'n' {
+			// Inf and NaN cannot be continued.
+			return v, tail, nil
+		}
+		// More digits may follow.
+		v.s = strings.TrimRight(ns, "-+.eE")
+		if len(v.s) == 0 {
+			return nil, tail, nil
+		}
+		pp.p.getExt(v).incomplete = true
+	}
+	return v, tail, nil
+}
+
+// isPartialSpecialNumber returns true if s is a prefix of Inf or NaN
+// with optional sign.
+func isPartialSpecialNumber(s string) bool {
+	if s[0] == '-' || s[0] == '+' {
+		s = s[1:]
+	}
+	return len(s) < len("nan") && (strings.EqualFold(s, "inf"[:len(s)]) || strings.EqualFold(s, "nan"[:len(s)]))
+}
+
+// parsePartialLiteral parses the literal lit at the start of s.
+func parsePartialLiteral(s, lit string, v *Value) (*Value, string, error) {
+	if strings.HasPrefix(s, lit) {
+		return v, s[len(lit):], nil
+	}
+	if strings.HasPrefix(lit, s) {
+		return nil, "", nil
+	}
+	return nil, s, fmt.Errorf("unexpected value found: %q", s)
+}
+
+func (pp *partialParser) parseArray(s string, depth int) (*Value, string, error) {
+	arr := pp.p.newArray()
+	pp.p.getExt(arr).incomplete = true
+
+	s = skipWS(s)
+	if len(s) == 0 {
+		return arr, s, nil
+	}
+	if s[0] == ']' {
+		arr.x.ext.incomplete = false
+		return arr, s[1:], nil
+	}
+	for {
+		var v *Value
+		var err error
+
+		s = skipWS(s)
+		v, s, err = pp.parseValue(s, depth)
+		if err != nil {
+			pp.p.path.pushIndex(len(arr.x.a))
+			return nil, s, err
+		}
+		if v == nil {
+			return arr, s, nil
+		}
+		arr.x.a = arena.SliceAppend(pp.p.a, arr.x.a, v)
+		if !v.IsComplete() {
+			return arr, s, nil
+		}
+
+		s = skipWS(s)
+		if len(s) == 0 {
+			return arr, s, nil
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] == ']' {
+			arr.x.ext.incomplete = false
+			return arr, s[1:], nil
+		}
+		return nil, s, errMissingArrayComma
+	}
+}
+
+func (pp *partialParser) parseObject(s string, depth int) (*Value, string, error) {
+	o := pp.p.newObject()
+	pp.p.getExt(o).incomplete = true
+
+	s = skipWS(s)
+	if len(s) == 0 {
+		return o, s, nil
+	}
+	if s[0] == '}' {
+		o.x.ext.incomplete = false
+		return o, s[1:], nil
+	}
+	for {
+		var err error
+
+		// Parse key.
+		s = skipWS(s)
+		if len(s) == 0 {
+			return o, s, nil
+		}
+		if s[0] != '"' {
+			return nil, s, errMissingKeyQuote
+		}
+		var k string
+		k, s, err = parseRawKey(s[1:])
+		if err != nil {
+			// The key is incomplete.
+			return o, "", nil
+		}
+		s = skipWS(s)
+		if len(s) == 0 {
+			return o, s, nil
+		}
+		if s[0] != ':' {
+			return nil, s, errMissingColon
+		}
+		s = s[1:]
+
+		// Parse value
+		var v *Value
+		s = skipWS(s)
+		v, s, err = pp.parseValue(s, depth)
+		if err != nil {
+			pp.p.path.pushRawKey(k)
+			return nil, s, err
+		}
+		if v == nil {
+			// Drop the key without value.
+			return o, s, nil
+		}
+		kv := pp.p.getKV(&o.x.o)
+		kv.k = k
+		kv.v = v
+		if !v.IsComplete() {
+			return o, s, nil
+		}
+
+		s = skipWS(s)
+		if len(s) == 0 {
+			return o, s, nil
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] == '}' {
+			o.x.ext.incomplete = false
+			return o, s[1:], nil
+		}
+		return nil, s, errMissingObjectComma
+	}
+}
+
+// trimPartialString drops the incomplete escape sequence or UTF-8 char
+// at the end of the raw contents s of an unterminated string.
+//
+// A trailing high surrogate escape sequence is dropped too, since
+// the low surrogate may follow it.
+func trimPartialString(s string) string {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			continue
+		}
+		if i+1 >= len(s) {
+			return s[:i]
+		}
+		if s[i+1] != 'u' {
+			i++
+			continue
+		}
+		if i+6 > len(s) {
+			return s[:i]
+		}
+		x, err := strconv.ParseUint(s[i+2:i+6], 16, 16)
+		if err == nil && utf16.IsSurrogate(rune(x)) && x < 0xdc00 {
+			if tail := s[i+6:]; len(tail) < 6 && strings.HasPrefix(`\u`, tail[:min(len(tail), 2)]) {
+				return s[:i]
+			}
+		}
+		i += 5
+	}
+
+	// Drop the incomplete UTF-8 char.
+	n := len(s) - 1
+	for n > 0 && n > len(s)-utf8.UTFMax && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	if n >= 0 && !utf8.FullRuneInString(s[n:]) {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,220 @@
+package astjson
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestParsePartial(t *testing.T) {
+	f := func(prefix, expected string, complete bool) {
+		t.Helper()
+
+		for _, a := range []arena.Arena{nil, arena.NewMonotonicArena(arena.WithMinBufferSize(1024))} {
+			v, err := ParsePartial(a, prefix)
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %s", prefix, err)
+			}
+			if v == nil {
+				if expected != "" {
+					t.Fatalf("unexpected nil value for %q; want %s", prefix, expected)
+				}
+				continue
+			}
+			if result := v.String(); result != expected {
+				t.Fatalf("unexpected result for %q; got %s; want %s", prefix, result, expected)
+			}
+			if v.IsComplete() != complete {
+				t.Fatalf("unexpected IsComplete for %q; got %v; want %v", prefix, v.IsComplete(), complete)
+			}
+		}
+	}
+
+	// Nothing yet
+	f(``, ``, false)
+	f(`  `, ``, false)
+	f(`-`, ``, false)
+	f(`tr`, ``, false)
+	f(`n`, ``, false)
+
+	// Scalars
+	f(`true`, `true`, true)
+	f(`12`, `12`, false)
+	f(`12 `, `12`, true)
+	f(`1.`, `1`, false)
+	f(`1.5e+`, `1.5`, false)
+	f(`"foo"`, `"foo"`, true)
+	f(`"fo`, `"fo"`, false)
+	f(`"a\`, `"a"`, false)
+	f(`"a\n`, `"a\n"`, false)
+	f(`"a\u00`, `"a"`, false)
+	f(`"ab`, `"ab"`, false)
+	f(`"a\ud834`, `"a"`, false)
+	f(`"a\ud834\u`, `"a"`, false)
+	f(`"a𝄞`, `"a𝄞"`, false)
+	f("\"a\xf0\x9d\x84", `"a"`, false)
+
+	f(`na`, ``, false)
+	f(`NA`, ``, false)
+	f(`-in`, ``, false)
+	f(`nan`, `nan`, true)
+	f(`NaN`, `NaN`, true)
+	f(`-Inf`, `-Inf`, true)
+	f(`[nan, -inf`, `[nan,-inf]`, false)
+	f(`{"a": na`, `{}`, false)
+
+	// Arrays
+	f(`[`, `[]`, false)
+	f(`[]`, `[]`, true)
+	f(`[1, 2`, `[1,2]`, false)
+	f(`[1, 2,`, `[1,2]`, false)
+	f(`[1, tr`, `[1]`, false)
+	f(`[1, "ab`, `[1,"ab"]`, false)
+	f(`[[1], [2`, `[[1],[2]]`, false)
+
+	// Objects
+	f(`{`, `{}`, false)
+	f(`{}`, `{}`, true)
+	f(`{"a`, `{}`, false)
+	f(`{"a"`, `{}`, false)
+	f(`{"a":`, `{}`, false)
+	f(`{"a": nu`, `{}`, false)
+	f(`{"a": 1, "b": "x`, `{"a":1,"b":"x"}`, false)
+	f(`{"a": {"b": [1, {"c": tr`, `{"a":{"b":[1,{}]}}`, false)
+	f(`{"a": {"b": 1}, "c": true}`, `{"a":{"b":1},"c":true}`, true)
+}
+
+func TestParsePartialIsComplete(t *testing.T) {
+	v, err := ParsePartial(nil, `{"a": [1, 2], "b": {"c": "x"}, "d": [3`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v.IsComplete() {
+		t.Fatalf("the top-level object must be incomplete")
+	}
+	if !v.Get("a").IsComplete() || !v.Get("a", "0").IsComplete() || !v.Get("b").IsComplete() || !v.Get("b", "c").IsComplete() {
+		t.Fatalf("the terminated values must be complete")
+	}
+	if v.Get("d").IsComplete() || v.Get("d", "0").IsComplete() {
+		t.Fatalf("the unterminated values must be incomplete")
+	}
+
+	var p Parser
+	v, err = p.Parse(`[1, "a"]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !v.IsComplete() || !v.Get("1").IsComplete() {
+		t.Fatalf("parsed values must be complete")
+	}
+}
+
+func TestParsePartialError(t *testing.T) {
+	f := func(prefix string, offset int) {
+		t.Helper()
+
+		_, err := ParsePartial(nil, prefix)
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("expecting ParseError for %q; got %v", prefix, err)
+		}
+		if pe.Offset != offset {
+			t.Fatalf("unexpected error offset for %q; got %d; want %d", prefix, pe.Offset, offset)
+		}
+	}
+
+	f(`x`, 0)
+	f(`[1 2`, 3)
+	f(`{"a" 1`, 5)
+	f(`{1`, 1)
+	f(`[1}`, 2)
+	f(`{"a": tx`, 6)
+	f(`1 2`, 2)
+	f(`[nx`, 1)
+	f(`[nanx`, 4)
+	f(`-ix`, 0)
+}
+
+func TestParsePartialMonotonic(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		var prev *Value
+		for i := 0; i <= len(s); i++ {
+			v, err := ParsePartial(nil, s[:i])
+			if err != nil {
+				t.Fatalf("unexpected error for prefix %q: %s", s[:i], err)
+			}
+			if !isPartialExtension(prev, v) {
+				t.Fatalf("the tree for prefix %q doesn't extend the tree for the previous prefix; got %s; previous %s", s[:i], v, prev)
+			}
+			prev = v
+		}
+		if !prev.IsComplete() {
+			t.Fatalf("the tree for the whole input %q must be complete", s)
+		}
+		var p Parser
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", s, err)
+		}
+		if prev.String() != v.String() {
+			t.Fatalf("unexpected tree for the whole input; got %s; want %s", prev, v)
+		}
+	}
+
+	f(`{"foo": [1, 23.5e-3, "bar\"é𝄞"], "x": {"y": null, "z": [true, false]}, "": []}`)
+	f(`[{"a": "multi-byte ä€𝄞"}, -1, {}]`)
+	f(`[nan, NaN, -Inf, {"a": null, "b": inf}]`)
+
+	data, err := os.ReadFile("testdata/small.json")
+	if err != nil {
+		t.Fatalf("cannot read testdata: %s", err)
+	}
+	f(string(data))
+}
+
+// isPartialExtension returns true if the tree v extends the tree prev
+// returned by ParsePartial for a shorter prefix.
+func isPartialExtension(prev, v *Value) bool {
+	if prev == nil {
+		return true
+	}
+	if v == nil || prev.t != v.t {
+		return false
+	}
+	if prev.IsComplete() {
+		return prev.String() == v.String()
+	}
+	switch prev.t {
+	case TypeString:
+		return strings.HasPrefix(v.s, prev.s)
+	case TypeNumber:
+		return true
+	case TypeArray:
+		if len(v.x.a) < len(prev.x.a) {
+			return false
+		}
+		for i := range prev.x.a {
+			if !isPartialExtension(prev.x.a[i], v.x.a[i]) {
+				return false
+			}
+		}
+		return true
+	case TypeObject:
+		if len(v.x.o.kvs) < len(prev.x.o.kvs) {
+			return false
+		}
+		for i, kv := range prev.x.o.kvs {
+			if kv.k != v.x.o.kvs[i].k || !isPartialExtension(kv.v, v.x.o.kvs[i].v) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,284 @@
+package astjson
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/wundergraph/go-arena"
+)
+
+// ProjectionWildcard matches any object key or array index in projection paths.
+const ProjectionWildcard = "*"
+
+// Projection is a compiled set of paths for ParseWithProjection.
+//
+// Projection may be used from concurrent goroutines.
+type Projection struct {
+	root projectionNode
+}
+
+// projectionNode contains the selected children of a value.
+type projectionNode struct {
+	// all is set if the whole value is selected.
+	all bool
+
+	// keys contains the selected object keys and array indexes.
+	keys map[string]*projectionNode
+
+	// wildcard is set if every object key and array index is selected.
+	wildcard *projectionNode
+
+	// indexes contains the keys, which are array indexes, in ascending order.
+	indexes []projectionIndex
+}
+
+// projectionIndex is the selected array index.
+type projectionIndex struct {
+	i int
+	n *projectionNode
+}
+
+// CompileProjection compiles the given paths into a Projection.
+//
+// Every path is a list of keys in the same format as for Value.Get:
+// array indexes may be represented as decimal numbers.
+// ProjectionWildcard matches any object key or array index.
+// An empty path selects the whole value.
+func CompileProjection(paths ...[]string) *Projection {
+	var proj Projection
+	for _, path := range paths {
+		n := &proj.root
+		for _, key := range path {
+			n = n.child(key)
+		}
+		n.all = true
+	}
+	proj.root.normalize()
+	return &proj
+}
+
+func (n *projectionNode) child(key string) *projectionNode {
+	if key == ProjectionWildcard {
+		if n.wildcard == nil {
+			n.wildcard = &projectionNode{}
+		}
+		return n.wildcard
+	}
+	if n.keys == nil {
+		n.keys = make(map[string]*projectionNode)
+	}
+	c := n.keys[key]
+	if c == nil {
+		c = &projectionNode{}
+		n.keys[key] = c
+	}
+	return c
+}
+
+// normalize merges the wildcard into the explicitly selected keys,
+// so lookup needs to check only a single node.
+func (n *projectionNode) normalize() {
+	if n.all {
+		n.keys = nil
+		n.wildcard = nil
+		return
+	}
+	if n.wildcard != nil {
+		for _, c := range n.keys {
+			c.merge(n.wildcard)
+		}
+		n.wildcard.normalize()
+	}
+	for key, c := range n.keys {
+		c.normalize()
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && strconv.Itoa(i) == key {
+			n.indexes = append(n.indexes, projectionIndex{i: i, n: c})
+		}
+	}
+	sort.Slice(n.indexes, func(i, j int) bool {
+		return n.indexes[i].i < n.indexes[j].i
+	})
+}
+
+func (n *projectionNode) merge(src *projectionNode) {
+	if src.all {
+		n.all = true
+	}
+	for key, c := range src.keys {
+		n.child(key).merge(c)
+	}
+	if src.wildcard != nil {
+		n.child(ProjectionWildcard).merge(src.wildcard)
+	}
+}
+
+func (n *projectionNode) lookup(key string) *projectionNode {
+	if c, ok := n.keys[key]; ok {
+		return c
+	}
+	return n.wildcard
+}
+
+// ParseWithProjection parses s containing JSON, but builds Values only
+// for the paths selected by proj and for their ancestors.
+//
+// s is validated completely. Object members, which aren't selected,
+// are omitted from the parsed objects. Array items, which aren't selected,
+// are replaced with null, so indexes of the selected items are preserved.
+// Items after the last selected index are omitted.
+//
+// The returned value is valid until the next call to Parse*.
+func (p *Parser) ParseWithProjection(a arena.Arena, s string, proj *Projection) (*Value, error) {
+	p.proj = &proj.root
+	return p.parse(a, s)
+}
+
+// parseProjected parses the value at the start of s, which is selected by n.
+func (p *Parser) parseProjected(s string, depth int, n *projectionNode) (*Value, string, error) {
+	if n.all || len(s) == 0 || (s[0] != '{' && s[0] != '[') {
+		return p.parseValue(s, depth)
+	}
+	depth++
+	if err := p.l.enter(depth); err != nil {
+		return nil, s, err
+	}
+	if s[0] == '{' {
+		return p.parseObjectProjected(s[1:], depth, n)
+	}
+	return p.parseArrayProjected(s[1:], depth, n)
+}
+
+func (p *Parser) parseArrayProjected(s string, depth int, n *projectionNode) (*Value, string, error) {
+	arr := p.newArray()
+
+	s = skipWS(s)
+	if len(s) == 0 {
+		return nil, s, errMissingArrayEnd
+	}
+	if s[0] == ']' {
+		return arr, s[1:], nil
+	}
+
+	// next is the position of the next selected index in n.indexes.
+	next := 0
+	for i := 0; ; i++ {
+		var v *Value
+		var err error
+
+		s = skipWS(s)
+		if err = p.l.checkItems(i + 1); err != nil {
+			return nil, s, err
+		}
+		c := n.wildcard
+		if next < len(n.indexes) && n.indexes[next].i == i {
+			c = n.indexes[next].n
+			next++
+		}
+		switch {
+		case c != nil:
+			v, s, err = p.parseProjected(s, depth, c)
+		case next < len(n.indexes):
+			v = valueNull
+			s, err = p.skipValue(s, depth)
+		default:
+			// The item is after the last selected index.
+			s, err = p.skipValue(s, depth)
+		}
+		if err != nil {
+			p.path.pushIndex(i)
+			return nil, s, err
+		}
+		if v != nil {
+			arr.x.a = arena.SliceAppend(p.a, arr.x.a, v)
+		}
+
+		s = skipWS(s)
+		if len(s) == 0 {
+			return nil, s, errArrayEnd
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] == ']' {
+			return arr, s[1:], nil
+		}
+		return nil, s, errMissingArrayComma
+	}
+}
+
+func (p *Parser) parseObjectProjected(s string, depth int, n *projectionNode) (*Value, string, error) {
+	o := p.newObject()
+
+	s = skipWS(s)
+	if len(s) == 0 {
+		return nil, s, errMissingObjectEnd
+	}
+	if s[0] == '}' {
+		return o, s[1:], nil
+	}
+
+	start := len(p.l.dk.keys)
+	for i := 0; ; i++ {
+		var k string
+		var err error
+		if err = p.l.checkMembers(i + 1); err != nil {
+			return nil, s, err
+		}
+
+		// Parse key.
+		s = skipWS(s)
+		if len(s) == 0 || s[0] != '"' {
+			return nil, s, errMissingKeyQuote
+		}
+		ks := s
+		k, s, err = parseRawKey(s[1:])
+		if err != nil {
+			return nil, s, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		if err = p.l.checkString(k); err != nil {
+			return nil, ks, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		p.l.pushKey(k)
+		s = skipWS(s)
+		if len(s) == 0 || s[0] != ':' {
+			return nil, s, errMissingColon
+		}
+		s = s[1:]
+
+		// Parse value
+		s = skipWS(s)
+		if c := n.lookup(p.unescapeString(k)); c != nil {
+			kv := p.getKV(&o.x.o)
+			kv.k = k
+			p.internKey(kv)
+			kv.v, s, err = p.parseProjected(s, depth, c)
+		} else {
+			s, err = p.skipValue(s, depth)
+		}
+		if err != nil {
+			p.path.pushRawKey(k)
+			return nil, s, err
+		}
+		s = skipWS(s)
+		if len(s) == 0 {
+			return nil, s, errObjectEnd
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] == '}' {
+			if err = p.l.checkKeys(start); err != nil {
+				return nil, s, err
+			}
+			if err = p.dedupKeys(&o.x.o); err != nil {
+				return nil, s, err
+			}
+			p.indexObject(&o.x.o)
+			return o, s[1:], nil
+		}
+		return nil, s, errMissingObjectComma
+	}
+}
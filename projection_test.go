@@ -0,0 +1,146 @@
+package astjson
+
+import (
+	"testing"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestParseWithProjection(t *testing.T) {
+	var p Parser
+	a := arena.NewMonotonicArena()
+
+	f := func(s string, proj *Projection, expected string) {
+		t.Helper()
+		v, err := p.ParseWithProjection(a, s, proj)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", s, err)
+		}
+		if vs := v.String(); vs != expected {
+			t.Fatalf("unexpected value for %q; got %s; want %s", s, vs, expected)
+		}
+	}
+
+	t.Run("paths", func(t *testing.T) {
+		s := `{"a": {"b": 1, "c": [1, 2]}, "d": "x", "e": {"f": null}}`
+		f(s, CompileProjection([]string{"a", "b"}), `{"a":{"b":1}}`)
+		f(s, CompileProjection([]string{"a", "c"}, []string{"d"}), `{"a":{"c":[1,2]},"d":"x"}`)
+		f(s, CompileProjection([]string{"a"}, []string{"a", "b"}), `{"a":{"b":1,"c":[1,2]}}`)
+		f(s, CompileProjection([]string{"missing"}), `{}`)
+		f(s, CompileProjection([]string{"d", "x"}), `{"d":"x"}`)
+		f(s, CompileProjection([]string{}), `{"a":{"b":1,"c":[1,2]},"d":"x","e":{"f":null}}`)
+		f(s, CompileProjection(), `{}`)
+		f(`123`, CompileProjection([]string{"a"}), `123`)
+	})
+
+	t.Run("arrays", func(t *testing.T) {
+		s := `[{"id": 1, "x": 2}, {"id": 3, "x": 4}, {"id": 5}]`
+		f(s, CompileProjection([]string{"*", "id"}), `[{"id":1},{"id":3},{"id":5}]`)
+		f(s, CompileProjection([]string{"1", "x"}), `[null,{"x":4}]`)
+		f(s, CompileProjection([]string{"0", "id"}, []string{"01"}, []string{"-1"}), `[{"id":1}]`)
+		f(s, CompileProjection([]string{"5"}), `[null,null,null]`)
+		f(s, CompileProjection([]string{"*", "id"}, []string{"0", "x"}), `[{"id":1,"x":2},{"id":3},{"id":5}]`)
+		f(s, CompileProjection([]string{"2"}), `[null,null,{"id":5}]`)
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		s := `{"a": {"x": 1, "y": 2}, "b": {"x": 3, "z": 4}}`
+		f(s, CompileProjection([]string{"*", "x"}), `{"a":{"x":1},"b":{"x":3}}`)
+		f(s, CompileProjection([]string{"*", "x"}, []string{"b", "z"}), `{"a":{"x":1},"b":{"x":3,"z":4}}`)
+		f(s, CompileProjection([]string{"*", "x"}, []string{"b"}), `{"a":{"x":1},"b":{"x":3,"z":4}}`)
+	})
+
+	t.Run("escaped keys", func(t *testing.T) {
+		v, err := p.ParseWithProjection(a, `{"foo": {"b\"r": 1, "x": 2}}`, CompileProjection([]string{"foo", `b"r`}))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if n := v.GetInt("foo", `b"r`); n != 1 {
+			t.Fatalf("unexpected int; got %d; want %d", n, 1)
+		}
+		if v.Exists("foo", "x") {
+			t.Fatalf("unselected key must be omitted")
+		}
+	})
+
+	t.Run("fixtures", func(t *testing.T) {
+		proj := CompileProjection([]string{"statuses", "*", "id"}, []string{"search_metadata", "count"})
+		v, err := p.ParseWithProjection(a, twitterFixture, proj)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		expected := MustParse(twitterFixture)
+		if n, expectedN := v.GetInt("search_metadata", "count"), expected.GetInt("search_metadata", "count"); n != expectedN {
+			t.Fatalf("unexpected count; got %d; want %d", n, expectedN)
+		}
+		statuses := v.GetArray("statuses")
+		expectedStatuses := expected.GetArray("statuses")
+		if len(statuses) != len(expectedStatuses) {
+			t.Fatalf("unexpected number of statuses; got %d; want %d", len(statuses), len(expectedStatuses))
+		}
+		for i, st := range statuses {
+			if st.GetObject().Len() != 1 {
+				t.Fatalf("unexpected status #%d: %s", i, st)
+			}
+			if id, expectedID := st.GetInt64("id"), expectedStatuses[i].GetInt64("id"); id != expectedID {
+				t.Fatalf("unexpected id for status #%d; got %d; want %d", i, id, expectedID)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		proj := CompileProjection([]string{"a", "b"}, []string{"*", "0"})
+		f := func(s string) {
+			t.Helper()
+			_, err := p.ParseWithProjection(a, s, proj)
+			if err == nil {
+				t.Fatalf("expecting non-nil error when parsing %q", s)
+			}
+			_, expectedErr := p.Parse(s)
+			if err.Error() != expectedErr.Error() {
+				t.Fatalf("unexpected error for %q; got %q; want %q", s, err, expectedErr)
+			}
+		}
+
+		f(``)
+		f(`{"a": {"b": 1, "c": tru}}`)
+		f(`{"x": [1, 2}`)
+		f(`{"a": {"b": [1 2]}}`)
+		f(`{"a" 1}`)
+		f(`[[1], [2,]]`)
+		f(`{"a":{}}}`)
+		f(`{"x": "unclosed}`)
+	})
+}
+
+func BenchmarkParseWithProjection(b *testing.B) {
+	fileData := getFromFile("testdata/twitter.json")
+	proj := CompileProjection([]string{"statuses", "*", "id"})
+	f := func(b *testing.B, parse func(p *Parser, a arena.Arena) (*Value, error)) {
+		var p Parser
+		a := arena.NewMonotonicArena(arena.WithMinBufferSize(1024 * 1024 * 2))
+		b.SetBytes(int64(len(fileData)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			v, err := parse(&p, a)
+			if err != nil {
+				b.Fatalf("cannot parse json: %s", err)
+			}
+			for _, st := range v.GetArray("statuses") {
+				_ = st.GetInt64("id")
+			}
+			a.Reset()
+		}
+	}
+	b.Run("full", func(b *testing.B) {
+		f(b, func(p *Parser, a arena.Arena) (*Value, error) {
+			return p.ParseWithArena(a, fileData)
+		})
+	})
+	b.Run("projection", func(b *testing.B) {
+		f(b, func(p *Parser, a arena.Arena) (*Value, error) {
+			return p.ParseWithProjection(a, fileData, proj)
+		})
+	})
+}
@@ -0,0 +1,205 @@
+package astjson
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/wundergraph/go-arena"
+)
+
+// PushParser parses a single JSON value, which is passed to it in chunks.
+//
+// Chunks may be split at arbitrary positions, including the middle
+// of strings, escape sequences and numbers. Every chunk is parsed as soon
+// as it is written, so parsing overlaps with reading the input.
+//
+// Only the bytes of an incomplete token are retained between chunks.
+// The resulting Value is allocated from the arena passed to NewPushParser
+// and doesn't reference the written chunks.
+//
+// PushParser may be re-used for subsequent parsing via Reset.
+//
+// PushParser cannot be used from concurrent goroutines.
+type PushParser struct {
+	d Decoder
+	a arena.Arena
+
+	// stack contains the objects and arrays being parsed.
+	stack []*Value
+
+	// kv is the object entry waiting for its value.
+	kv *kv
+
+	// root is the parsed top-level value.
+	root *Value
+
+	// done is set when the top-level value has been parsed.
+	done bool
+
+	err error
+}
+
+// NewPushParser returns a PushParser, which allocates parsed values from a.
+func NewPushParser(a arena.Arena) *PushParser {
+	p := &PushParser{}
+	p.Reset(a)
+	return p
+}
+
+// Reset prepares p for parsing a new JSON value allocated from a.
+func (p *PushParser) Reset(a arena.Arena) {
+	p.d.reset()
+	p.d.b = p.d.b[:0]
+	p.a = a
+	clear(p.stack)
+	p.stack = p.stack[:0]
+	p.kv = nil
+	p.root = nil
+	p.done = false
+	p.err = nil
+}
+
+// Write parses the next chunk of the input.
+//
+// The chunk isn't retained by p after returning, so it may be re-used.
+// Syntax errors are reported as soon as they are detected.
+func (p *PushParser) Write(chunk []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.d.eof {
+		return fmt.Errorf("cannot write to PushParser after Finish")
+	}
+	// Move the incomplete token to the start of the buffer only if this costs
+	// no more than the consumed bytes, so every byte is copied O(1) times.
+	consumed := len(p.d.b) - len(p.d.s)
+	if consumed > 0 && (len(p.d.s) == 0 || consumed >= pushParserCompactSize && consumed >= len(p.d.s)) {
+		p.d.discard()
+		n := copy(p.d.b, p.d.s)
+		p.d.b = p.d.b[:n]
+		consumed = 0
+	}
+	p.d.b = append(p.d.b, chunk...)
+	p.d.s = b2s(p.d.b[consumed:])
+	return p.parse()
+}
+
+// pushParserCompactSize is the minimum size of the consumed input,
+// which is dropped from the buffer of PushParser.
+const pushParserCompactSize = 4096
+
+// Finish signals the end of the input and returns the parsed value.
+//
+// The returned value is valid until the arena passed to NewPushParser
+// or Reset is reset.
+func (p *PushParser) Finish() (*Value, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	p.d.eof = true
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	if !p.done {
+		p.err = p.d.syntaxError(fmt.Errorf("unexpected end of JSON"))
+		return nil, p.err
+	}
+	return p.root, nil
+}
+
+func (p *PushParser) parse() error {
+	for {
+		if p.done {
+			p.d.s = skipWS(p.d.s)
+			if len(p.d.s) > 0 {
+				p.err = NewParseError(fmt.Errorf("unexpected tail: %q", startEndString(p.d.s)))
+				return p.err
+			}
+			return nil
+		}
+		t, err := p.d.next()
+		if err == errShortInput || err == io.EOF {
+			// Finish reports the missing value if the input ends here.
+			return nil
+		}
+		if err != nil {
+			p.err = err
+			return err
+		}
+		p.push(t)
+	}
+}
+
+// push adds the token t to the value being parsed.
+func (p *PushParser) push(t Token) {
+	var v *Value
+	switch t.kind {
+	case TokenKey:
+		o := p.stack[len(p.stack)-1]
+		p.kv = o.x.o.getKV(p.a)
+		p.kv.k = copyString(p.a, t.s)
+		return
+	case TokenEndObject, TokenEndArray:
+		p.stack[len(p.stack)-1] = nil
+		p.stack = p.stack[:len(p.stack)-1]
+		p.done = len(p.stack) == 0
+		return
+	case TokenBeginObject:
+		v = arena.Allocate[Value](p.a)
+		v.t = TypeObject
+		v.x = arena.Allocate[node](p.a)
+	case TokenBeginArray:
+		v = arena.Allocate[Value](p.a)
+		v.t = TypeArray
+		v.x = arena.Allocate[node](p.a)
+	case TokenString:
+		v = arena.Allocate[Value](p.a)
+		v.t = TypeString
+		if strings.IndexByte(t.s, '\\') < 0 {
+			// t.s references the input buffer, which is re-used for the next chunks.
+			v.s = copyString(p.a, t.s)
+		} else {
+			v.s = unescapeStringBestEffort(p.a, t.s)
+		}
+	case TokenNumber:
+		v = arena.Allocate[Value](p.a)
+		v.t = TypeNumber
+		v.s = copyString(p.a, t.s)
+	case TokenBool:
+		if t.s == "true" {
+			v = valueTrue
+		} else {
+			v = valueFalse
+		}
+	case TokenNull:
+		v = valueNull
+	}
+
+	switch {
+	case len(p.stack) == 0:
+		p.root = v
+	case p.stack[len(p.stack)-1].t == TypeObject:
+		p.kv.v = v
+		p.kv = nil
+	default:
+		arr := p.stack[len(p.stack)-1]
+		arr.x.a = arena.SliceAppend(p.a, arr.x.a, v)
+	}
+
+	if t.kind == TokenBeginObject || t.kind == TokenBeginArray {
+		p.stack = append(p.stack, v)
+		return
+	}
+	p.done = len(p.stack) == 0
+}
+
+// copyString returns a copy of s allocated from a.
+func copyString(a arena.Arena, s string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	b := arena.AllocateSlice[byte](a, len(s), len(s))
+	copy(b, s)
+	return b2s(b)
+}
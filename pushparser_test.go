@@ -0,0 +1,147 @@
+package astjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestPushParser(t *testing.T) {
+	a := arena.NewMonotonicArena()
+	p := NewPushParser(a)
+
+	parse := func(chunks ...string) (*Value, error) {
+		t.Helper()
+		p.Reset(a)
+		for _, chunk := range chunks {
+			b := []byte(chunk)
+			if err := p.Write(b); err != nil {
+				return nil, err
+			}
+			// Make sure the chunk isn't referenced by the parsed value.
+			for i := range b {
+				b[i] = 'X'
+			}
+		}
+		return p.Finish()
+	}
+
+	t.Run("all split points", func(t *testing.T) {
+		f := func(s string) {
+			t.Helper()
+			expected := MustParse(s).String()
+			for i := 0; i <= len(s); i++ {
+				for j := i; j <= len(s); j++ {
+					v, err := parse(s[:i], s[i:j], s[j:])
+					if err != nil {
+						t.Fatalf("unexpected error when parsing %q split at %d and %d: %s", s, i, j, err)
+					}
+					if vs := v.String(); vs != expected {
+						t.Fatalf("unexpected value for %q split at %d and %d; got %s; want %s", s, i, j, vs, expected)
+					}
+				}
+			}
+		}
+
+		f(`123`)
+		f(` -12.5e+3 `)
+		f(`"foo\"bar\\bazA😀"`)
+		f(`true`)
+		f(`[false, null, nan]`)
+		f(`{"a\"b": {"c": [1, 2.5, "x"]}, "d": {}, "e": []}`)
+	})
+
+	t.Run("one byte chunks", func(t *testing.T) {
+		var chunks []string
+		for i := range mediumFixture {
+			chunks = append(chunks, mediumFixture[i:i+1])
+		}
+		v, err := parse(chunks...)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if vs, expected := v.String(), MustParse(mediumFixture).String(); vs != expected {
+			t.Fatalf("unexpected value; got %s; want %s", vs, expected)
+		}
+	})
+
+	t.Run("long string", func(t *testing.T) {
+		s := `["` + strings.Repeat("x", 1<<20) + `"]`
+		var chunks []string
+		for i := 0; i < len(s); i += 100 {
+			chunks = append(chunks, s[i:min(i+100, len(s))])
+		}
+		v, err := parse(chunks...)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if n := len(v.GetStringBytes("0")); n != 1<<20 {
+			t.Fatalf("unexpected string length; got %d; want %d", n, 1<<20)
+		}
+	})
+
+	t.Run("buffer size", func(t *testing.T) {
+		s := `[` + strings.Repeat(`1234, "foo", `, 1e5) + `null]`
+		p := NewPushParser(a)
+		for i := 0; i < len(s); i += 7 {
+			if err := p.Write([]byte(s[i:min(i+7, len(s))])); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+		if _, err := p.Finish(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		// The consumed input mustn't be retained.
+		if n := cap(p.d.b); n > 4*pushParserCompactSize {
+			t.Fatalf("too big buffer capacity; got %d; want at most %d", n, 4*pushParserCompactSize)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		f := func(chunks ...string) {
+			t.Helper()
+			_, err := parse(chunks...)
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expecting ParseError for %q; got %v", chunks, err)
+			}
+		}
+
+		f()
+		f(`  `)
+		f(`[1, `, `2`)
+		f(`{"foo"`, `: 1,`, `}`)
+		f(`"unclosed`)
+		f(`tr`, `ue1`)
+		f(`[1]`, ` [2]`)
+		f(`{"a":1}x`)
+	})
+
+	t.Run("early error", func(t *testing.T) {
+		p.Reset(a)
+		if err := p.Write([]byte(`[1, 2 3`)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if err := p.Write([]byte(`]`)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if _, err := p.Finish(); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	})
+
+	t.Run("write after finish", func(t *testing.T) {
+		p.Reset(a)
+		if err := p.Write([]byte(`1`)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if _, err := p.Finish(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := p.Write([]byte(`2`)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	})
+}
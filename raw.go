@@ -0,0 +1,94 @@
+package astjson
+
+import (
+	"github.com/wundergraph/go-arena"
+)
+
+// rawValue contains all the memory of a value created by RawValue,
+// so it is allocated at once.
+type rawValue struct {
+	v   Value
+	n   node
+	ext nodeExt
+	lz  lazyState
+}
+
+// RawValue returns a Value for the serialized JSON raw without parsing it.
+//
+// MarshalTo writes raw objects and arrays verbatim, without
+// the surrounding whitespace. They are parsed on the first access
+// via Get, GetObject, GetArray, Object, Array, Set, Del or MergeValues.
+// Raw strings, numbers, true, false and null are parsed immediately.
+//
+// Only the brackets of raw objects and arrays are checked, so ParseError
+// is returned if raw isn't closed or if it is followed by a non-whitespace
+// tail. Other syntax errors are detected on the first access: Object, Array,
+// MergeValues and Value.AppendSnapshot return them, Get, GetObject and
+// GetArray return nil, while Set and Del do nothing.
+// Use ValidatedRawValue for untrusted input, since MarshalTo writes
+// invalid raw objects and arrays as is.
+//
+// Values are allocated from a, also when they are parsed after RawValue returns.
+// The returned Value references raw, so raw mustn't be modified
+// while the returned Value is in use.
+func RawValue(a arena.Arena, raw []byte) (*Value, error) {
+	return newRawValue(a, b2s(raw), false)
+}
+
+// ValidatedRawValue is like RawValue, but it validates raw first.
+//
+// ParseError is returned if raw contains invalid JSON or if it exceeds MaxDepth.
+func ValidatedRawValue(a arena.Arena, raw []byte) (*Value, error) {
+	s := b2s(raw)
+	if err := ValidateWithOptions(s, ParserOptions{}); err != nil {
+		return nil, err
+	}
+	return newRawValue(a, s, true)
+}
+
+func newRawValue(a arena.Arena, raw string, validated bool) (*Value, error) {
+	s := skipWS(raw)
+	if len(s) == 0 || (s[0] != '{' && s[0] != '[') {
+		var p Parser
+		return p.ParseWithArena(a, raw)
+	}
+
+	tail, ok := skipBrackets(s)
+	if !ok {
+		err := errMissingArrayEnd
+		if s[0] == '{' {
+			err = errMissingObjectEnd
+		}
+		return nil, newParseError(raw, tail, err, nil)
+	}
+	if tail = skipWS(tail); len(tail) > 0 {
+		return nil, newParseError(raw, tail, errUnexpectedTail, nil)
+	}
+
+	rv := arena.Allocate[rawValue](a)
+	rv.lz.a = a
+	rv.lz.unvalidated = !validated
+	rv.ext.lz = &rv.lz
+	rv.n.ext = &rv.ext
+	v := &rv.v
+	v.t = TypeObject
+	if s[0] == '[' {
+		v.t = TypeArray
+	}
+	v.s = trimRightWS(s)
+	v.x = &rv.n
+	return v, nil
+}
+
+// trimRightWS removes the trailing whitespace from s.
+func trimRightWS(s string) string {
+	for len(s) > 0 {
+		switch s[len(s)-1] {
+		case ' ', '\t', '\n', '\r':
+			s = s[:len(s)-1]
+		default:
+			return s
+		}
+	}
+	return s
+}
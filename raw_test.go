@@ -0,0 +1,240 @@
+package astjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestRawValue(t *testing.T) {
+	a := arena.NewMonotonicArena()
+
+	t.Run("marshal", func(t *testing.T) {
+		f := func(raw, expected string, typ Type) {
+			t.Helper()
+			for _, a := range []arena.Arena{nil, a} {
+				v, err := RawValue(a, []byte(raw))
+				if err != nil {
+					t.Fatalf("unexpected error for %q: %s", raw, err)
+				}
+				if v.Type() != typ {
+					t.Fatalf("unexpected type for %q; got %s; want %s", raw, v.Type(), typ)
+				}
+				if vs := v.String(); vs != expected {
+					t.Fatalf("unexpected value for %q; got %s; want %s", raw, vs, expected)
+				}
+				vv, err := ValidatedRawValue(a, []byte(raw))
+				if err != nil {
+					t.Fatalf("unexpected error for %q: %s", raw, err)
+				}
+				if vs := vv.String(); vs != expected {
+					t.Fatalf("unexpected validated value for %q; got %s; want %s", raw, vs, expected)
+				}
+			}
+		}
+
+		f(`{ "foo" : [ 1, 2 ] }`, `{ "foo" : [ 1, 2 ] }`, TypeObject)
+		f(" \n[1,  {\"a\": \"b\\u0041\"}]\t ", `[1,  {"a": "b\u0041"}]`, TypeArray)
+		f(`{}`, `{}`, TypeObject)
+		f(` "aA" `, `"aA"`, TypeString)
+		f(`-1.5e3`, `-1.5e3`, TypeNumber)
+		f(`true`, `true`, TypeTrue)
+		f(`false`, `false`, TypeFalse)
+		f(`null`, `null`, TypeNull)
+	})
+
+	t.Run("splice", func(t *testing.T) {
+		cached := []byte(`{"id": 1, "name" : "foo",  "tags": ["a", "b"]}`)
+		v := ObjectValue(a)
+		v.Set(a, "data", mustRawValue(a, cached))
+		v.Set(a, "errors", ArrayValue(a))
+		expected := `{"data":{"id": 1, "name" : "foo",  "tags": ["a", "b"]},"errors":[]}`
+		if vs := v.String(); vs != expected {
+			t.Fatalf("unexpected value; got %s; want %s", vs, expected)
+		}
+		if !v.Get("data").lazy() {
+			t.Fatalf("untouched raw value must stay unparsed")
+		}
+	})
+
+	t.Run("access", func(t *testing.T) {
+		raw := []byte(`{"id": 1, "user": {"name": "foo"}, "tags": ["a", "b"]}`)
+		v := mustRawValue(a, raw)
+		if n := v.GetInt("id"); n != 1 {
+			t.Fatalf("unexpected id; got %d; want %d", n, 1)
+		}
+		if v.lazy() {
+			t.Fatalf("accessed raw value must be parsed")
+		}
+		if !v.x.o.kvs[1].v.lazy() {
+			t.Fatalf("nested values must stay unparsed until they are accessed")
+		}
+		if s := v.GetStringBytes("user", "name"); string(s) != "foo" {
+			t.Fatalf("unexpected name; got %q; want %q", s, "foo")
+		}
+		v.Set(a, "id", IntValue(a, 2))
+		v.Get("tags").SetArrayItem(a, 2, StringValue(a, "c"))
+		expected := `{"id":2,"user":{"name":"foo"},"tags":["a","b","c"]}`
+		if vs := v.String(); vs != expected {
+			t.Fatalf("unexpected value; got %s; want %s", vs, expected)
+		}
+
+		arr := mustRawValue(a, []byte(`[1, [2]]`))
+		items, err := arr.Array()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(items) != 2 || items[1].String() != `[2]` {
+			t.Fatalf("unexpected items: %v", items)
+		}
+		o, err := mustRawValue(a, []byte(`{"a": 1}`)).Object()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if o.Len() != 1 {
+			t.Fatalf("unexpected object length; got %d; want %d", o.Len(), 1)
+		}
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		left := mustRawValue(a, []byte(`{"a": 1, "b": {"c": 2}}`))
+		right := mustRawValue(a, []byte(`{"b": {"d": 3}, "e": [4]}`))
+		v, _, err := MergeValues(a, left, right)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		expected := `{"a":1,"b":{"c":2,"d":3},"e":[4]}`
+		if vs := v.String(); vs != expected {
+			t.Fatalf("unexpected merged value; got %s; want %s", vs, expected)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		f := func(raw string, validatedOnly bool) {
+			t.Helper()
+			_, err := ValidatedRawValue(a, []byte(raw))
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("expecting ParseError for %q; got %v", raw, err)
+			}
+			if validatedOnly {
+				return
+			}
+			_, err = RawValue(a, []byte(raw))
+			if !errors.As(err, &pe) {
+				t.Fatalf("expecting ParseError from RawValue for %q; got %v", raw, err)
+			}
+		}
+
+		f(``, false)
+		f(`  `, false)
+		f(`{"a": 1`, false)
+		f(`[1, "]"`, false)
+		f(`[1, 2] x`, false)
+		f(`{"a": 1} {"b": 2}`, false)
+		f(`{"a": [1, }`, false)
+		f(`tru`, false)
+		f(`{"a": x}`, true)
+		f(strings.Repeat("[", MaxDepth+1)+strings.Repeat("]", MaxDepth+1), true)
+	})
+
+	t.Run("unvalidated", func(t *testing.T) {
+		f := func(raw string) {
+			t.Helper()
+			v := mustRawValue(a, []byte(raw))
+			// The error is returned on every access.
+			for i := 0; i < 2; i++ {
+				var err error
+				if v.Type() == TypeObject {
+					_, err = v.Object()
+				} else {
+					_, err = v.Array()
+				}
+				var pe *ParseError
+				if !errors.As(err, &pe) {
+					t.Fatalf("expecting ParseError for %q; got %v", raw, err)
+				}
+			}
+			if v.Get("0") != nil || v.GetObject() != nil || v.GetArray() != nil {
+				t.Fatalf("unexpected value found in %q", raw)
+			}
+			v.Set(a, "0", IntValue(a, 1))
+			v.Del("0")
+			if _, _, err := MergeValues(a, v, mustRawValue(a, []byte(raw))); err == nil {
+				t.Fatalf("expecting non-nil error when merging %q", raw)
+			}
+			if _, err := v.AppendSnapshot(nil); err == nil {
+				t.Fatalf("expecting non-nil error when creating snapshot of %q", raw)
+			}
+
+			// MarshalTo doesn't validate raw values.
+			if vs := v.String(); vs != raw {
+				t.Fatalf("unexpected value; got %s; want %s", vs, raw)
+			}
+		}
+
+		f(`{"a": x}`)
+		f(`{"a" 1}`)
+		f(`[1, x]`)
+		f(`[1, 2,]`)
+
+		// Invalid nested values are detected when their parent is accessed.
+		f(`{"a": [1, x], "b": 2}`)
+	})
+}
+
+// mustRawValue returns RawValue for raw. It panics on error.
+func mustRawValue(a arena.Arena, raw []byte) *Value {
+	v, err := RawValue(a, raw)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func BenchmarkRawValue(b *testing.B) {
+	cached := []byte(getFromFile("testdata/twitter.json"))
+	a := arena.NewMonotonicArena(arena.WithMinBufferSize(1024 * 1024 * 2))
+	f := func(b *testing.B, newValue func(raw []byte) *Value) {
+		var dst []byte
+		b.SetBytes(int64(len(cached)))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			v := ObjectValue(a)
+			v.Set(a, "data", newValue(cached))
+			dst = v.MarshalTo(dst[:0])
+			a.Reset()
+		}
+	}
+	b.Run("parse", func(b *testing.B) {
+		var p Parser
+		f(b, func(raw []byte) *Value {
+			v, err := p.ParseBytesWithArena(a, raw)
+			if err != nil {
+				b.Fatalf("cannot parse json: %s", err)
+			}
+			return v
+		})
+	})
+	b.Run("raw", func(b *testing.B) {
+		f(b, func(raw []byte) *Value {
+			v, err := RawValue(a, raw)
+			if err != nil {
+				b.Fatalf("cannot create raw value: %s", err)
+			}
+			return v
+		})
+	})
+	b.Run("validated-raw", func(b *testing.B) {
+		f(b, func(raw []byte) *Value {
+			v, err := ValidatedRawValue(a, raw)
+			if err != nil {
+				b.Fatalf("cannot validate json: %s", err)
+			}
+			return v
+		})
+	})
+}
@@ -0,0 +1,85 @@
+package astjson
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/wundergraph/go-arena"
+)
+
+// MaxBytesError is returned when the input read from io.Reader
+// exceeds the configured byte limit.
+type MaxBytesError struct {
+	// Limit is the maximum number of bytes that was allowed.
+	Limit int
+}
+
+func (e *MaxBytesError) Error() string {
+	return fmt.Sprintf("JSON input exceeds the limit of %d bytes", e.Limit)
+}
+
+// Is reports whether target is ErrMaxBytes.
+func (e *MaxBytesError) Is(target error) bool {
+	return target == ErrMaxBytes
+}
+
+// minReadBufSize is the initial size of the buffer used for reading from io.Reader.
+const minReadBufSize = 4096
+
+// readAll reads r until io.EOF into a buffer allocated from a.
+//
+// The buffer grows as needed. A MaxBytesError is returned if r contains
+// more than maxBytes bytes. maxBytes <= 0 disables the limit.
+func readAll(a arena.Arena, r io.Reader, maxBytes int) ([]byte, error) {
+	b := arena.AllocateSlice[byte](a, 0, minReadBufSize)
+	for {
+		if len(b) == cap(b) {
+			b = growBytes(a, b)
+		}
+		buf := b[len(b):cap(b)]
+		if maxBytes > 0 && len(b)+len(buf) > maxBytes+1 {
+			// Read at most a single byte past the limit in order to detect the overflow.
+			buf = buf[:maxBytes+1-len(b)]
+		}
+		n, err := r.Read(buf)
+		b = b[:len(b)+n]
+		if maxBytes > 0 && len(b) > maxBytes {
+			return nil, &MaxBytesError{Limit: maxBytes}
+		}
+		if err == io.EOF {
+			return b, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// growBytes returns b with at least doubled capacity, keeping its contents.
+func growBytes(a arena.Arena, b []byte) []byte {
+	n := len(b)
+	if cap(b) == 0 {
+		return arena.AllocateSlice[byte](a, 0, minReadBufSize)
+	}
+	b = arena.SliceAppend(a, b[:cap(b)], 0)
+	return b[:n]
+}
+
+// chunkSize is the size of chunks read by readChunk.
+const chunkSize = 64 * 1024
+
+// readChunk moves the unconsumed input s, which must point into b,
+// to the beginning of b and appends up to chunkSize bytes read from r to it.
+//
+// b is re-allocated on the heap if it has no room for the next chunk.
+func readChunk(r io.Reader, b []byte, s string) ([]byte, error) {
+	n := copy(b, s)
+	b = b[:n]
+	if cap(b)-n < chunkSize {
+		nb := make([]byte, n, max(2*cap(b), n+chunkSize))
+		copy(nb, b)
+		b = nb
+	}
+	m, err := r.Read(b[n : n+chunkSize])
+	return b[:n+m], err
+}
@@ -0,0 +1,386 @@
+package astjson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wundergraph/go-arena"
+)
+
+// RepairKind is the kind of a fix applied by Repair.
+type RepairKind int
+
+const (
+	// RepairUnquotedKey means quotes have been added around an object key.
+	RepairUnquotedKey RepairKind = iota
+
+	// RepairMissingComma means a missing comma has been inserted
+	// between array items or object members.
+	RepairMissingComma
+
+	// RepairTrailingComma means a comma before ']' or '}' has been dropped.
+	RepairTrailingComma
+
+	// RepairPythonLiteral means True, False or None has been replaced
+	// with true, false or null.
+	RepairPythonLiteral
+
+	// RepairSingleQuotes means a single-quoted string has been converted
+	// to a double-quoted string.
+	RepairSingleQuotes
+
+	// RepairControlChar means an unescaped newline or another control char
+	// in a string has been escaped.
+	RepairControlChar
+)
+
+// String returns string representation of the k.
+func (k RepairKind) String() string {
+	switch k {
+	case RepairUnquotedKey:
+		return "unquoted key"
+	case RepairMissingComma:
+		return "missing comma"
+	case RepairTrailingComma:
+		return "trailing comma"
+	case RepairPythonLiteral:
+		return "Python literal"
+	case RepairSingleQuotes:
+		return "single quotes"
+	case RepairControlChar:
+		return "unescaped control char"
+	default:
+		return "RepairKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// RepairNote describes a fix applied by Repair.
+type RepairNote struct {
+	// Kind is the kind of the fix.
+	Kind RepairKind
+
+	// Offset is the byte offset in the input where the fix has been applied.
+	Offset int
+}
+
+// String returns string representation of the n.
+func (n RepairNote) String() string {
+	return fmt.Sprintf("%s at offset %d", n.Kind, n.Offset)
+}
+
+// Repair parses s containing malformed JSON, which is typically produced
+// by LLMs or by legacy systems.
+//
+// The following fixes are applied to s:
+//
+//   - Quotes are added around unquoted object keys.
+//     The key ends at whitespace, ':' or any other structural char.
+//   - Missing commas are inserted between array items and object members,
+//     which are delimited only by whitespace.
+//   - Trailing commas before ']' and '}' are dropped.
+//   - True, False and None are replaced with true, false and null.
+//   - Single-quoted strings are converted to double-quoted strings.
+//     \' escape sequences are accepted in them.
+//   - Unescaped newlines and other control chars in strings are escaped.
+//
+// Repair returns a note for every applied fix, so the caller may decide
+// whether to trust the result. The notes are empty if s is valid JSON.
+// ParseError is returned if s cannot be repaired with the fixes above.
+// Numbers aren't repaired, so malformed numbers result in ParseError.
+//
+// Values are allocated from a. The returned Value references s.
+func Repair(a arena.Arena, s string) (*Value, []RepairNote, error) {
+	r := repairer{
+		p: Parser{
+			a: a,
+		},
+		src: s,
+	}
+	v, tail, err := r.parseValue(skipWS(s), 0)
+	if err != nil {
+		return nil, r.notes, newParseError(s, tail, err, r.p.path)
+	}
+	tail = skipWS(tail)
+	if len(tail) > 0 {
+		return nil, r.notes, newParseError(s, tail, errUnexpectedTail, nil)
+	}
+	return v, r.notes, nil
+}
+
+// RepairBytes parses b containing malformed JSON.
+//
+// See Repair for details.
+func RepairBytes(a arena.Arena, b []byte) (*Value, []RepairNote, error) {
+	return Repair(a, b2s(b))
+}
+
+// repairer holds the state of a single Repair call.
+type repairer struct {
+	p Parser
+
+	// src is the input passed to Repair.
+	src string
+
+	notes []RepairNote
+}
+
+// note records the fix of the given kind at the start of s.
+func (r *repairer) note(kind RepairKind, s string) {
+	r.notes = append(r.notes, RepairNote{
+		Kind:   kind,
+		Offset: len(r.src) - len(s),
+	})
+}
+
+func (r *repairer) parseValue(s string, depth int) (*Value, string, error) {
+	if len(s) == 0 {
+		return nil, s, errEmptyString
+	}
+	depth++
+	if err := r.p.l.enter(depth); err != nil {
+		return nil, s, err
+	}
+
+	switch c := s[0]; c {
+	case '"', '\'':
+		ss, tail, err := r.parseString(s, c)
+		if err != nil {
+			return nil, tail, fmt.Errorf("cannot parse string: %w", err)
+		}
+		v := r.p.newValue()
+		v.t = TypeString
+		v.s = ss
+		return v, tail, nil
+	case '{':
+		return r.parseObject(s[1:], depth)
+	case '[':
+		return r.parseArray(s[1:], depth)
+	case 't':
+		if !strings.HasPrefix(s, "true") {
+			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		return valueTrue, s[len("true"):], nil
+	case 'f':
+		if !strings.HasPrefix(s, "false") {
+			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		return valueFalse, s[len("false"):], nil
+	case 'n':
+		if !strings.HasPrefix(s, "null") {
+			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		return valueNull, s[len("null"):], nil
+	case 'T':
+		if !strings.HasPrefix(s, "True") {
+			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		r.note(RepairPythonLiteral, s)
+		return valueTrue, s[len("True"):], nil
+	case 'F':
+		if !strings.HasPrefix(s, "False") {
+			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		r.note(RepairPythonLiteral, s)
+		return valueFalse, s[len("False"):], nil
+	case 'N':
+		if !strings.HasPrefix(s, "None") {
+			return nil, s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		r.note(RepairPythonLiteral, s)
+		return valueNull, s[len("None"):], nil
+	default:
+		// Malformed numbers such as 1.2.3 or +1 aren't repaired,
+		// since their intended value is unknown.
+		tail, err := validateNumber(s)
+		if err != nil {
+			return nil, tail, fmt.Errorf("cannot parse number: %w", err)
+		}
+		v := r.p.newValue()
+		v.t = TypeNumber
+		v.s = s[:len(s)-len(tail)]
+		return v, tail, nil
+	}
+}
+
+func (r *repairer) parseArray(s string, depth int) (*Value, string, error) {
+	arr := r.p.newArray()
+
+	// comma is the tail starting from the last comma.
+	var comma string
+	for {
+		var v *Value
+		var err error
+
+		s = skipWS(s)
+		if len(s) == 0 {
+			return nil, s, errMissingArrayEnd
+		}
+		if s[0] == ']' {
+			if len(comma) > 0 {
+				r.note(RepairTrailingComma, comma)
+			}
+			return arr, s[1:], nil
+		}
+		v, s, err = r.parseValue(s, depth)
+		if err != nil {
+			r.p.path.pushIndex(len(arr.x.a))
+			return nil, s, err
+		}
+		arr.x.a = arena.SliceAppend(r.p.a, arr.x.a, v)
+
+		s = skipWS(s)
+		if len(s) == 0 {
+			return nil, s, errArrayEnd
+		}
+		switch s[0] {
+		case ',':
+			comma = s
+			s = s[1:]
+		case ']':
+			return arr, s[1:], nil
+		case '}', ':':
+			return nil, s, errMissingArrayComma
+		default:
+			r.note(RepairMissingComma, s)
+			comma = ""
+		}
+	}
+}
+
+func (r *repairer) parseObject(s string, depth int) (*Value, string, error) {
+	o := r.p.newObject()
+
+	// comma is the tail starting from the last comma.
+	var comma string
+	for {
+		var err error
+
+		// Parse key.
+		s = skipWS(s)
+		if len(s) == 0 {
+			return nil, s, errMissingObjectEnd
+		}
+		if s[0] == '}' {
+			if len(comma) > 0 {
+				r.note(RepairTrailingComma, comma)
+			}
+			return o, s[1:], nil
+		}
+		kv := r.p.getKV(&o.x.o)
+		s, err = r.parseKey(kv, s)
+		if err != nil {
+			return nil, s, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		s = skipWS(s)
+		if len(s) == 0 || s[0] != ':' {
+			return nil, s, errMissingColon
+		}
+		s = s[1:]
+
+		// Parse value
+		s = skipWS(s)
+		kv.v, s, err = r.parseValue(s, depth)
+		if err != nil {
+			r.p.path.pushKey(kv.k)
+			return nil, s, err
+		}
+		s = skipWS(s)
+		if len(s) == 0 {
+			return nil, s, errObjectEnd
+		}
+		switch s[0] {
+		case ',':
+			comma = s
+			s = s[1:]
+		case '}':
+			return o, s[1:], nil
+		case ']', ':':
+			return nil, s, errMissingObjectComma
+		default:
+			r.note(RepairMissingComma, s)
+			comma = ""
+		}
+	}
+}
+
+// parseKey parses the quoted or unquoted object key at the start of s into kv.
+func (r *repairer) parseKey(kv *kv, s string) (string, error) {
+	// The key is stored unescaped, so MarshalTo escapes it if needed.
+	kv.keyUnescaped = true
+	if c := s[0]; c == '"' || c == '\'' {
+		k, tail, err := r.parseString(s, c)
+		if err != nil {
+			return tail, err
+		}
+		kv.k = k
+		return tail, nil
+	}
+
+	n := 0
+	for n < len(s) && !isRepairKeyEnd(s[n]) {
+		n++
+	}
+	if n == 0 {
+		return s, fmt.Errorf("cannot find object key")
+	}
+	r.note(RepairUnquotedKey, s)
+	kv.k = s[:n]
+	return s[n:], nil
+}
+
+// isRepairKeyEnd returns true if c cannot belong to an unquoted object key.
+func isRepairKeyEnd(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', ':', ',', '{', '}', '[', ']', '"', '\'':
+		return true
+	}
+	return false
+}
+
+// parseString parses the string quoted with the given quote at the start of s
+// and returns the unescaped string and the tail.
+func (r *repairer) parseString(s string, quote byte) (string, string, error) {
+	if quote == '\'' {
+		r.note(RepairSingleQuotes, s)
+	}
+	hasQuoteEscape := false
+	i := 1
+	for ; i < len(s); i++ {
+		c := s[i]
+		if c == quote {
+			break
+		}
+		if c == '\\' {
+			i++
+			if i < len(s) && s[i] == '\'' {
+				hasQuoteEscape = true
+			}
+			continue
+		}
+		if c < 0x20 {
+			r.note(RepairControlChar, s[i:])
+		}
+	}
+	if i >= len(s) {
+		return "", "", errMissingQuote
+	}
+	ss := s[1:i]
+	tail := s[i+1:]
+	if hasQuoteEscape {
+		// appendUnescapedString keeps unknown \' escape sequences unchanged,
+		// so they are replaced with quotes beforehand.
+		b := arena.AllocateSlice[byte](r.p.a, 0, len(ss))
+		for j := 0; j < len(ss); j++ {
+			if ss[j] == '\\' && j+1 < len(ss) {
+				j++
+				if ss[j] != '\'' {
+					b = arena.SliceAppend(r.p.a, b, '\\')
+				}
+			}
+			b = arena.SliceAppend(r.p.a, b, ss[j])
+		}
+		ss = b2s(b)
+	}
+	return r.p.unescapeString(ss), tail, nil
+}
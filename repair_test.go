@@ -0,0 +1,124 @@
+package astjson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestRepair(t *testing.T) {
+	f := func(s, expected string, expectedNotes ...RepairNote) {
+		t.Helper()
+
+		for _, a := range []arena.Arena{nil, arena.NewMonotonicArena(arena.WithMinBufferSize(1024))} {
+			v, notes, err := Repair(a, s)
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %s", s, err)
+			}
+			if result := v.String(); result != expected {
+				t.Fatalf("unexpected result for %q; got %s; want %s", s, result, expected)
+			}
+			if !json.Valid([]byte(v.String())) {
+				t.Fatalf("encoding/json rejects the result for %q: %s", s, v)
+			}
+			if fmt.Sprint(notes) != fmt.Sprint(expectedNotes) {
+				t.Fatalf("unexpected notes for %q; got %v; want %v", s, notes, expectedNotes)
+			}
+		}
+	}
+
+	// Valid JSON
+	f(`{"foo": [1, "bar", true, false, null, {"x": -1.5e3}]}`, `{"foo":[1,"bar",true,false,null,{"x":-1.5e3}]}`)
+	f(`"a\"b\\cA"`, `"a\"b\\cA"`)
+	f(`[]`, `[]`)
+	f(`{}`, `{}`)
+
+	// Unquoted keys
+	f(`{foo: 1, bar_baz: 2}`, `{"foo":1,"bar_baz":2}`,
+		RepairNote{Kind: RepairUnquotedKey, Offset: 1},
+		RepairNote{Kind: RepairUnquotedKey, Offset: 9},
+	)
+	f(`{foo-bar:1}`, `{"foo-bar":1}`, RepairNote{Kind: RepairUnquotedKey, Offset: 1})
+
+	// Missing commas
+	f(`[1 2 "a"]`, `[1,2,"a"]`,
+		RepairNote{Kind: RepairMissingComma, Offset: 3},
+		RepairNote{Kind: RepairMissingComma, Offset: 5},
+	)
+	f("{\"a\": 1\n\"b\": {}}", `{"a":1,"b":{}}`, RepairNote{Kind: RepairMissingComma, Offset: 8})
+
+	// Trailing commas
+	f(`[1, 2, ]`, `[1,2]`, RepairNote{Kind: RepairTrailingComma, Offset: 5})
+	f(`{"a": 1,}`, `{"a":1}`, RepairNote{Kind: RepairTrailingComma, Offset: 7})
+
+	// Python literals
+	f(`[True, False, None]`, `[true,false,null]`,
+		RepairNote{Kind: RepairPythonLiteral, Offset: 1},
+		RepairNote{Kind: RepairPythonLiteral, Offset: 7},
+		RepairNote{Kind: RepairPythonLiteral, Offset: 14},
+	)
+
+	// Single quotes
+	f(`{'a': 'b"c'}`, `{"a":"b\"c"}`,
+		RepairNote{Kind: RepairSingleQuotes, Offset: 1},
+		RepairNote{Kind: RepairSingleQuotes, Offset: 6},
+	)
+	f(`'it\'s \\\' \n'`, `"it's \\' \n"`, RepairNote{Kind: RepairSingleQuotes, Offset: 0})
+
+	// Unescaped control chars
+	f("\"a\nb\tc\"", `"a\nb\tc"`,
+		RepairNote{Kind: RepairControlChar, Offset: 2},
+		RepairNote{Kind: RepairControlChar, Offset: 4},
+	)
+
+	// Combined
+	f("{name: 'foo' ok: True\n tags: ['a' 'b',],}", `{"name":"foo","ok":true,"tags":["a","b"]}`,
+		RepairNote{Kind: RepairUnquotedKey, Offset: 1},
+		RepairNote{Kind: RepairSingleQuotes, Offset: 7},
+		RepairNote{Kind: RepairMissingComma, Offset: 13},
+		RepairNote{Kind: RepairUnquotedKey, Offset: 13},
+		RepairNote{Kind: RepairPythonLiteral, Offset: 17},
+		RepairNote{Kind: RepairMissingComma, Offset: 23},
+		RepairNote{Kind: RepairUnquotedKey, Offset: 23},
+		RepairNote{Kind: RepairSingleQuotes, Offset: 30},
+		RepairNote{Kind: RepairMissingComma, Offset: 34},
+		RepairNote{Kind: RepairSingleQuotes, Offset: 34},
+		RepairNote{Kind: RepairTrailingComma, Offset: 37},
+		RepairNote{Kind: RepairTrailingComma, Offset: 39},
+	)
+}
+
+func TestRepairError(t *testing.T) {
+	f := func(s string, kind ErrorKind, offset int) {
+		t.Helper()
+
+		_, _, err := Repair(nil, s)
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("expecting ParseError for %q; got %v", s, err)
+		}
+		if pe.Kind != kind || pe.Offset != offset {
+			t.Fatalf("unexpected error for %q; got kind %s at offset %d; want kind %s at offset %d", s, pe.Kind, pe.Offset, kind, offset)
+		}
+	}
+
+	f(``, ErrorKindUnexpectedEOF, 0)
+	f(`[1, 2`, ErrorKindUnexpectedEOF, 5)
+	f(`{"a": "b`, ErrorKindUnexpectedEOF, 8)
+	f(`{"a" 1}`, ErrorKindSyntax, 5)
+	f(`[1, 2}`, ErrorKindSyntax, 5)
+	f(`{"a": 1]`, ErrorKindSyntax, 7)
+	f(`[1,,2]`, ErrorKindSyntax, 3)
+	f(`[Nope]`, ErrorKindSyntax, 1)
+	f(`{,}`, ErrorKindSyntax, 1)
+	f(`[1] x`, ErrorKindUnexpectedTail, 4)
+	f(`[1.2.3]`, ErrorKindSyntax, 4)
+	f(`[+1]`, ErrorKindSyntax, 1)
+	f(`[NaN]`, ErrorKindSyntax, 1)
+	f(`[01]`, ErrorKindBadNumber, 1)
+	f(`{"a": -}`, ErrorKindBadNumber, 7)
+	f(`1.`, ErrorKindUnexpectedEOF, 2)
+}
@@ -2,6 +2,8 @@ package astjson
 
 import (
 	"errors"
+	"io"
+	"unicode/utf8"
 )
 
 // Scanner scans a series of JSON values. Values may be delimited by whitespace.
@@ -14,7 +16,22 @@ import (
 //
 // Use Parser for parsing only a single JSON value.
 type Scanner struct {
+	// MaxRecordSize limits the size in bytes of a single JSON value
+	// read from io.Reader passed to InitReader.
+	//
+	// Next fails with *MaxBytesError if a value exceeds the limit.
+	// Zero means no limit.
+	MaxRecordSize int
+
+	// Options are applied to every value parsed by Next.
+	//
+	// Options.MaxBytes is ignored. Use MaxRecordSize instead.
+	Options ParserOptions
+
 	// b contains a working copy of json value passed to Init.
+	//
+	// When reading from io.Reader, b contains only the bytes
+	// of the value being parsed and the bytes read after it.
 	b []byte
 
 	// s points to the next JSON value to parse.
@@ -25,6 +42,17 @@ type Scanner struct {
 
 	// v contains the last parsed JSON value.
 	v *Value
+
+	// r is the reader passed to InitReader.
+	r io.Reader
+
+	// rerr contains the error returned by r.
+	rerr error
+
+	// vb tracks the end of the value being read from r.
+	vb valueBoundary
+
+	p Parser
 }
 
 // Init initializes sc with the given s.
@@ -35,6 +63,8 @@ func (sc *Scanner) Init(s string) {
 	sc.s = b2s(sc.b)
 	sc.err = nil
 	sc.v = nil
+	sc.r = nil
+	sc.rerr = nil
 }
 
 // InitBytes initializes sc with the given b.
@@ -44,6 +74,22 @@ func (sc *Scanner) InitBytes(b []byte) {
 	sc.Init(b2s(b))
 }
 
+// InitReader initializes sc with the given r.
+//
+// r may contain multiple JSON values, which may be delimited by whitespace.
+// r is read in fixed-size chunks, and only the bytes of the value being
+// parsed are kept in memory, so arbitrarily long streams may be scanned.
+// Use MaxRecordSize for limiting the size of a single value.
+func (sc *Scanner) InitReader(r io.Reader) {
+	sc.b = sc.b[:0]
+	sc.s = ""
+	sc.err = nil
+	sc.v = nil
+	sc.r = r
+	sc.rerr = nil
+	sc.vb.reset()
+}
+
 // Next parses the next JSON value from s passed to Init.
 //
 // Returns true on success. The parsed value is available via Value call.
@@ -54,16 +100,45 @@ func (sc *Scanner) Next() bool {
 	if sc.err != nil {
 		return false
 	}
+	if sc.r != nil {
+		return sc.nextFromReader()
+	}
 
-	sc.s = skipWS(sc.s)
+	if sc.Options.JSON5 {
+		sc.s = skipJSON5WS(sc.s)
+	} else {
+		sc.s = skipWS(sc.s)
+	}
 	if len(sc.s) == 0 {
 		sc.err = errEOF
 		return false
 	}
+	return sc.parse()
+}
+
+// parse parses the next value at sc.s.
+func (sc *Scanner) parse() bool {
+	sc.p.c.reset()
+	sc.p.l.opts = sc.Options
+	sc.p.l.reset()
+	sc.p.path.reset()
 
-	v, tail, err := parseValue(nil, sc.s, 0)
+	s := sc.s
+	if sc.Options.JSON5 {
+		s = skipJSON5WS(s)
+	}
+	var v *Value
+	var tail string
+	var err error
+	if sc.Options.JSON5 {
+		v, tail, err = sc.p.parseJSON5Value(s, 0)
+	} else {
+		v, tail, err = sc.p.parseValue(s, 0)
+	}
 	if err != nil {
-		sc.err = err
+		// The position is relative to the value, since the bytes of
+		// the previous values may be already dropped when reading from r.
+		sc.err = newParseError(s, tail, err, sc.p.path)
 		return false
 	}
 
@@ -72,7 +147,77 @@ func (sc *Scanner) Next() bool {
 	return true
 }
 
+func (sc *Scanner) nextFromReader() bool {
+	sc.vb.reset()
+	for {
+		sc.s = skipWS(sc.s)
+		if len(sc.s) == 0 {
+			if sc.rerr != nil {
+				sc.setReadError()
+				return false
+			}
+			sc.fill()
+			continue
+		}
+		var n int
+		var ok bool
+		if sc.Options.JSON5 {
+			n, ok = sc.vb.scanJSON5(sc.s)
+		} else {
+			n, ok = sc.vb.scan(sc.s)
+		}
+		if !ok {
+			// The value isn't complete yet.
+			n = len(sc.s)
+		}
+		if sc.MaxRecordSize > 0 && n > sc.MaxRecordSize {
+			sc.err = &MaxBytesError{Limit: sc.MaxRecordSize}
+			return false
+		}
+		if !ok {
+			if sc.rerr == nil {
+				sc.fill()
+				continue
+			}
+			if sc.rerr != io.EOF {
+				sc.setReadError()
+				return false
+			}
+			if sc.Options.JSON5 && len(skipJSON5WS(sc.s)) == 0 {
+				// Only comments are left in r.
+				sc.err = errEOF
+				return false
+			}
+			// The last value in r. Let parse decide whether it is valid.
+		}
+		return sc.parse()
+	}
+}
+
+// fill reads the next chunk from sc.r.
+//
+// The bytes of already returned values are dropped from sc.b.
+func (sc *Scanner) fill() {
+	b, err := readChunk(sc.r, sc.b, sc.s)
+	sc.b = b
+	sc.s = b2s(b)
+	if err != nil {
+		sc.rerr = err
+	}
+}
+
+func (sc *Scanner) setReadError() {
+	if sc.rerr == io.EOF {
+		sc.err = errEOF
+		return
+	}
+	sc.err = sc.rerr
+}
+
 // Error returns the last error.
+//
+// Invalid values result in *ParseError. Its position is relative
+// to the start of the invalid value.
 func (sc *Scanner) Error() error {
 	if sc.err == errEOF {
 		return nil
@@ -88,3 +233,200 @@ func (sc *Scanner) Value() *Value {
 }
 
 var errEOF = errors.New("end of s")
+
+// valueBoundary finds the end of a JSON value in the input,
+// which is fed incrementally.
+//
+// It doesn't validate the value - it only tracks the nesting of objects,
+// arrays and strings, so the value may be parsed once all of its bytes
+// are available.
+type valueBoundary struct {
+	// n is the number of already scanned bytes.
+	n int
+
+	// depth is the current nesting depth of objects and arrays.
+	depth int
+
+	// inString is set while scanning a string.
+	inString bool
+
+	// escaped is set if the previous byte in a string was a backslash.
+	escaped bool
+
+	// The following fields are used only by scanJSON5.
+
+	// quote is the quote char of the string being scanned.
+	quote byte
+
+	// comment is '/' while scanning a line comment
+	// and '*' while scanning a block comment.
+	comment byte
+
+	// scalar is set while scanning a top-level number or literal.
+	scalar bool
+}
+
+func (vb *valueBoundary) reset() {
+	*vb = valueBoundary{}
+}
+
+// scan continues scanning s, which must start with the value
+// and contain the previously scanned bytes.
+//
+// Returns the length of the value in s and true if the value is complete.
+// Scalar values are complete only if they are followed by a delimiter.
+func (vb *valueBoundary) scan(s string) (int, bool) {
+	if len(s) == 0 {
+		return 0, false
+	}
+	if c := s[0]; c != '{' && c != '[' && c != '"' {
+		// Number or literal.
+		for i := max(vb.n, 1); i < len(s); i++ {
+			if isValueDelimiter(s[i]) {
+				return i, true
+			}
+		}
+		vb.n = len(s)
+		return 0, false
+	}
+
+	for i := vb.n; i < len(s); i++ {
+		c := s[i]
+		if vb.inString {
+			switch {
+			case vb.escaped:
+				vb.escaped = false
+			case c == '\\':
+				vb.escaped = true
+			case c == '"':
+				vb.inString = false
+				if vb.depth == 0 {
+					return i + 1, true
+				}
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			vb.inString = true
+		case '{', '[':
+			vb.depth++
+		case '}', ']':
+			vb.depth--
+			if vb.depth <= 0 {
+				return i + 1, true
+			}
+		}
+	}
+	vb.n = len(s)
+	return 0, false
+}
+
+func isValueDelimiter(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '\r', ',', ':', '{', '}', '[', ']', '"':
+		return true
+	}
+	return false
+}
+
+// scanJSON5 is similar to scan, but it accepts JSON5.
+//
+// s may start with whitespace and comments, which are skipped.
+func (vb *valueBoundary) scanJSON5(s string) (int, bool) {
+	for i := vb.n; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case vb.comment == '/':
+			if c == '\n' || c == '\r' {
+				vb.comment = 0
+			} else if c == 0xE2 {
+				// U+2028 and U+2029 terminate line comments too.
+				if len(s)-i < 3 {
+					vb.n = i
+					return 0, false
+				}
+				if s[i+1] == 0x80 && (s[i+2] == 0xA8 || s[i+2] == 0xA9) {
+					vb.comment = 0
+				}
+			}
+			continue
+		case vb.comment == '*':
+			if c == '*' {
+				if i+1 == len(s) {
+					vb.n = i
+					return 0, false
+				}
+				if s[i+1] == '/' {
+					vb.comment = 0
+					i++
+				}
+			}
+			continue
+		case vb.quote != 0:
+			switch {
+			case vb.escaped:
+				vb.escaped = false
+			case c == '\\':
+				vb.escaped = true
+			case c == vb.quote:
+				vb.quote = 0
+				if vb.depth == 0 {
+					return i + 1, true
+				}
+			}
+			continue
+		}
+
+		if c == '/' {
+			if i+1 == len(s) {
+				vb.n = i
+				return 0, false
+			}
+			if s[i+1] == '/' || s[i+1] == '*' {
+				if vb.scalar {
+					return i, true
+				}
+				vb.comment = s[i+1]
+				i++
+				continue
+			}
+		}
+		if c >= utf8.RuneSelf && vb.depth == 0 && !vb.scalar {
+			if !utf8.FullRuneInString(s[i:]) {
+				vb.n = i
+				return 0, false
+			}
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if isJSON5Space(r) {
+				i += size - 1
+				continue
+			}
+		}
+		if vb.scalar {
+			if isValueDelimiter(c) || c == '\'' || c == '\v' || c == '\f' {
+				return i, true
+			}
+			continue
+		}
+
+		switch c {
+		case ' ', '\t', '\n', '\r', '\v', '\f':
+		case '"', '\'':
+			vb.quote = c
+		case '{', '[':
+			vb.depth++
+		case '}', ']':
+			vb.depth--
+			if vb.depth <= 0 {
+				return i + 1, true
+			}
+		default:
+			if vb.depth == 0 {
+				vb.scalar = true
+			}
+		}
+	}
+	vb.n = len(s)
+	return 0, false
+}
@@ -2,8 +2,12 @@ package astjson
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestScanner(t *testing.T) {
@@ -36,4 +40,142 @@ func TestScanner(t *testing.T) {
 			t.Fatalf("Next must return false")
 		}
 	})
+
+	t.Run("parse error", func(t *testing.T) {
+		f := func(name string, next func() bool) {
+			t.Helper()
+			for next() {
+			}
+			var pe *ParseError
+			if !errors.As(sc.Error(), &pe) {
+				t.Fatalf("%s: expecting ParseError; got %v", name, sc.Error())
+			}
+			if pe.Kind != ErrorKindSyntax || pe.Offset != 12 || pe.Line != 2 || pe.Column != 3 {
+				t.Fatalf("%s: unexpected position; got kind %s, offset %d, line %d, column %d; want kind %s, offset 12, line 2, column 3",
+					name, pe.Kind, pe.Offset, pe.Line, pe.Column, ErrorKindSyntax)
+			}
+			if path := pe.PathString(); path != "$.a[1]" {
+				t.Fatalf("%s: unexpected path; got %s; want %s", name, path, "$.a[1]")
+			}
+		}
+
+		s := "[1] {\"a\": [2,\n  x]}"
+		sc.Init(s)
+		f("Init", sc.Next)
+		sc.InitReader(iotest.OneByteReader(strings.NewReader(s)))
+		f("InitReader", sc.Next)
+	})
+}
+
+func TestScannerInitReader(t *testing.T) {
+	var sc Scanner
+
+	t.Run("success", func(t *testing.T) {
+		s := `[] {} "" 123 {"foo":[1,"b\"]ar"]} true null -1.5e3` + "\n"
+		sc.InitReader(iotest.OneByteReader(strings.NewReader(s)))
+		var bb bytes.Buffer
+		for sc.Next() {
+			v := sc.Value()
+			fmt.Fprintf(&bb, "%s|", v)
+		}
+		if err := sc.Error(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		expected := `[]|{}|""|123|{"foo":[1,"b\"]ar"]}|true|null|-1.5e3|`
+		if s := bb.String(); s != expected {
+			t.Fatalf("unexpected string obtained; got %q; want %q", s, expected)
+		}
+	})
+
+	t.Run("json lines", func(t *testing.T) {
+		var sb strings.Builder
+		for i := 0; i < 10000; i++ {
+			fmt.Fprintf(&sb, "{\"id\":%d,\"name\":\"item %d\"}\n", i, i)
+		}
+		sc.InitReader(strings.NewReader(sb.String()))
+		n := 0
+		for sc.Next() {
+			if id := sc.Value().GetInt("id"); id != n {
+				t.Fatalf("unexpected id; got %d; want %d", id, n)
+			}
+			n++
+		}
+		if err := sc.Error(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if n != 10000 {
+			t.Fatalf("unexpected number of values; got %d; want %d", n, 10000)
+		}
+		if cap(sc.b) > 2*chunkSize {
+			t.Fatalf("unexpected buffer size; got %d; want up to %d", cap(sc.b), 2*chunkSize)
+		}
+	})
+
+	t.Run("value spanning chunks", func(t *testing.T) {
+		s := `{"big":"` + strings.Repeat("x", 3*chunkSize) + `"} 42`
+		sc.InitReader(strings.NewReader(s))
+		if !sc.Next() {
+			t.Fatalf("cannot parse the first value: %v", sc.Error())
+		}
+		if n := len(sc.Value().GetStringBytes("big")); n != 3*chunkSize {
+			t.Fatalf("unexpected string length; got %d; want %d", n, 3*chunkSize)
+		}
+		if !sc.Next() {
+			t.Fatalf("cannot parse the second value: %v", sc.Error())
+		}
+		if n := sc.Value().GetInt(); n != 42 {
+			t.Fatalf("unexpected number; got %d; want %d", n, 42)
+		}
+		if sc.Next() {
+			t.Fatalf("Next must return false")
+		}
+		if err := sc.Error(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		sc.InitReader(strings.NewReader(`[] sdfdsfdf`))
+		for sc.Next() {
+		}
+		if err := sc.Error(); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	})
+
+	t.Run("truncated value", func(t *testing.T) {
+		sc.InitReader(strings.NewReader(`{"foo":[1,2`))
+		if sc.Next() {
+			t.Fatalf("Next must return false")
+		}
+		if err := sc.Error(); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		sc.InitReader(io.MultiReader(strings.NewReader(`{"foo":`), iotest.ErrReader(iotest.ErrTimeout)))
+		if sc.Next() {
+			t.Fatalf("Next must return false")
+		}
+		if err := sc.Error(); !errors.Is(err, iotest.ErrTimeout) {
+			t.Fatalf("unexpected error; got %v; want %v", err, iotest.ErrTimeout)
+		}
+	})
+
+	t.Run("max record size", func(t *testing.T) {
+		sc.InitReader(strings.NewReader(`[1,2] [1,2,3,4,5,6] [1]`))
+		sc.MaxRecordSize = 10
+		defer func() { sc.MaxRecordSize = 0 }()
+		if !sc.Next() {
+			t.Fatalf("cannot parse the first value: %v", sc.Error())
+		}
+		if sc.Next() {
+			t.Fatalf("Next must return false")
+		}
+		var mbe *MaxBytesError
+		if !errors.As(sc.Error(), &mbe) {
+			t.Fatalf("expecting MaxBytesError; got %v", sc.Error())
+		}
+	})
 }
@@ -0,0 +1,349 @@
+package astjson
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+
+	"github.com/wundergraph/go-arena"
+)
+
+// Snapshot layout:
+//
+//	magic    [4]byte  "AJSN"
+//	version  uint8    snapshotVersion
+//	reserved [3]byte  zero
+//	checksum uint32   CRC-32C of the rest of the snapshot
+//	values   uint64   the number of strings, numbers, objects and arrays
+//	nodes    uint64   the number of objects and arrays
+//	members  uint64   the total number of object members
+//	items    uint64   the total number of array items
+//	root     value
+//
+// Integers are little-endian. A value starts with its Type byte.
+// Strings and numbers are followed by the uvarint length and the contents.
+// Objects are followed by the uvarint number of members and the members,
+// which consist of the length-prefixed unescaped key and the value.
+// Arrays are followed by the uvarint number of items and the items.
+const (
+	snapshotMagic      = "AJSN"
+	snapshotVersion    = 1
+	snapshotHeaderSize = 4 + 4 + 4 + 4*8
+
+	// snapshotChecksumStart is the offset of the checksummed part.
+	snapshotChecksumStart = 12
+)
+
+var snapshotTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrInvalidSnapshot is returned by LoadSnapshot for corrupted snapshots
+// and snapshots created by incompatible versions of the package.
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// AppendSnapshot appends the binary snapshot of v to dst and returns the result.
+//
+// The snapshot may be loaded via LoadSnapshot much faster than parsing
+// the marshaled v. Lazy objects and arrays and packed arrays are parsed
+// like Get does. Spans and IsComplete flags aren't stored.
+//
+// An error is returned if v is nested deeper than MaxDepth,
+// since LoadSnapshot rejects such snapshots. dst is returned unchanged then.
+func (v *Value) AppendSnapshot(dst []byte) ([]byte, error) {
+	start := len(dst)
+	dst = append(dst, snapshotMagic...)
+	dst = append(dst, snapshotVersion, 0, 0, 0)
+	for len(dst)-start < snapshotHeaderSize {
+		dst = append(dst, 0)
+	}
+
+	var w snapshotWriter
+	dst, err := w.appendValue(dst, v, 0)
+	if err != nil {
+		return dst[:start], fmt.Errorf("cannot create snapshot: %w", err)
+	}
+
+	h := dst[start : start+snapshotHeaderSize]
+	binary.LittleEndian.PutUint64(h[12:], uint64(w.values))
+	binary.LittleEndian.PutUint64(h[20:], uint64(w.nodes))
+	binary.LittleEndian.PutUint64(h[28:], uint64(w.members))
+	binary.LittleEndian.PutUint64(h[36:], uint64(w.items))
+	binary.LittleEndian.PutUint32(h[8:], crc32.Checksum(dst[start+snapshotChecksumStart:], snapshotTable))
+	return dst, nil
+}
+
+// snapshotWriter counts the values written by AppendSnapshot.
+type snapshotWriter struct {
+	values  int
+	nodes   int
+	members int
+	items   int
+}
+
+func (w *snapshotWriter) appendValue(dst []byte, v *Value, depth int) ([]byte, error) {
+	depth++
+	if depth > MaxDepth {
+		return dst, limitError(ErrMaxDepth, MaxDepth)
+	}
+	if v.lazy() {
+		if err := v.materialize(); err != nil {
+			return dst, err
+		}
+	}
+	// The Type values are a part of the snapshot format.
+	dst = append(dst, byte(v.t))
+	switch v.t {
+	case TypeObject:
+		w.values++
+		w.nodes++
+		var kvs []*kv
+		if v.x != nil {
+			kvs = v.x.o.kvs
+		}
+		w.members += len(kvs)
+		dst = binary.AppendUvarint(dst, uint64(len(kvs)))
+		for _, kv := range kvs {
+			k := kv.k
+			if !kv.keyUnescaped {
+				k = unescapeStringBestEffort(nil, k)
+			}
+			dst = appendSnapshotString(dst, k)
+			var err error
+			if dst, err = w.appendValue(dst, kv.v, depth); err != nil {
+				return dst, err
+			}
+		}
+	case TypeArray:
+		w.values++
+		w.nodes++
+		var a []*Value
+		if v.x != nil {
+			a = v.x.a
+		}
+		w.items += len(a)
+		dst = binary.AppendUvarint(dst, uint64(len(a)))
+		for _, vv := range a {
+			var err error
+			if dst, err = w.appendValue(dst, vv, depth); err != nil {
+				return dst, err
+			}
+		}
+	case TypeString, TypeNumber:
+		w.values++
+		dst = appendSnapshotString(dst, v.s)
+	}
+	return dst, nil
+}
+
+func appendSnapshotString(dst []byte, s string) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(s)))
+	return append(dst, s...)
+}
+
+// LoadSnapshot loads the Value from the snapshot b created by Value.AppendSnapshot.
+//
+// Values are allocated from a in a few big chunks. Strings and object keys
+// of the returned Value reference b, so b mustn't be modified
+// while the returned Value is in use.
+//
+// ErrInvalidSnapshot is returned if b is corrupted or if it has been
+// created by an incompatible version of the package.
+func LoadSnapshot(a arena.Arena, b []byte) (*Value, error) {
+	if len(b) < snapshotHeaderSize || string(b[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidSnapshot)
+	}
+	if b[4] != snapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d; want %d", ErrInvalidSnapshot, b[4], snapshotVersion)
+	}
+	if b[5]|b[6]|b[7] != 0 {
+		return nil, fmt.Errorf("%w: non-zero reserved bytes", ErrInvalidSnapshot)
+	}
+	if crc32.Checksum(b[snapshotChecksumStart:], snapshotTable) != binary.LittleEndian.Uint32(b[8:]) {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidSnapshot)
+	}
+
+	// Every value, member and item occupies at least a byte,
+	// so the counts cannot exceed the size of the snapshot.
+	body := b[snapshotHeaderSize:]
+	var counts [4]int
+	for i := range counts {
+		n := binary.LittleEndian.Uint64(b[12+8*i:])
+		if n > uint64(len(body)) {
+			return nil, fmt.Errorf("%w: too big count %d", ErrInvalidSnapshot, n)
+		}
+		counts[i] = int(n)
+	}
+
+	l := snapshotLoader{
+		a:     a,
+		b:     body,
+		vs:    allocateSnapshotSlice[Value](a, counts[0]),
+		ns:    allocateSnapshotSlice[node](a, counts[1]),
+		kvs:   allocateSnapshotSlice[kv](a, counts[2]),
+		kps:   allocateSnapshotSlice[*kv](a, counts[2]),
+		items: allocateSnapshotSlice[*Value](a, counts[3]),
+	}
+	v, err := l.loadValue(0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w at offset %d", ErrInvalidSnapshot, err, len(b)-len(l.b))
+	}
+	if len(l.b) > 0 {
+		return nil, fmt.Errorf("%w: unexpected tail at offset %d", ErrInvalidSnapshot, len(b)-len(l.b))
+	}
+	return v, nil
+}
+
+// allocateSnapshotSlice returns a slice with n items allocated from a.
+//
+// nil is returned for zero n, since arenas don't support empty allocations.
+func allocateSnapshotSlice[T any](a arena.Arena, n int) []T {
+	if n == 0 {
+		return nil
+	}
+	return arena.AllocateSlice[T](a, n, n)
+}
+
+// snapshotLoader holds the state of a single LoadSnapshot call.
+type snapshotLoader struct {
+	// a is the arena for the indexes of large objects.
+	a arena.Arena
+
+	// b is the unread part of the snapshot.
+	b []byte
+
+	// vs, ns, kvs, kps and items contain the memory for the remaining values.
+	vs    []Value
+	ns    []node
+	kvs   []kv
+	kps   []*kv
+	items []*Value
+}
+
+var errSnapshotCounts = errors.New("counts mismatch")
+
+func (l *snapshotLoader) loadValue(depth int) (*Value, error) {
+	depth++
+	if depth > MaxDepth {
+		return nil, limitError(ErrMaxDepth, MaxDepth)
+	}
+	if len(l.b) == 0 {
+		return nil, errors.New("unexpected end of snapshot")
+	}
+	t := Type(l.b[0])
+	l.b = l.b[1:]
+
+	switch t {
+	case TypeNull:
+		return valueNull, nil
+	case TypeTrue:
+		return valueTrue, nil
+	case TypeFalse:
+		return valueFalse, nil
+	case TypeString, TypeNumber:
+		v, err := l.newValue(t)
+		if err != nil {
+			return nil, err
+		}
+		v.s, err = l.readString()
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	case TypeObject:
+		v, err := l.newContainer(t)
+		if err != nil {
+			return nil, err
+		}
+		n, err := l.readLen()
+		if err != nil {
+			return nil, err
+		}
+		if n > len(l.kvs) {
+			return nil, errSnapshotCounts
+		}
+		// The full slice expressions prevent appends from overwriting
+		// the members of the next objects.
+		kvs := l.kps[:n:n]
+		l.kps = l.kps[n:]
+		for i := range kvs {
+			kv := &l.kvs[0]
+			l.kvs = l.kvs[1:]
+			if kv.k, err = l.readString(); err != nil {
+				return nil, err
+			}
+			kv.keyUnescaped = true
+			if kv.v, err = l.loadValue(depth); err != nil {
+				return nil, err
+			}
+			kvs[i] = kv
+		}
+		v.x.o.kvs = kvs
+		v.x.o.attachIndex(l.a)
+		return v, nil
+	case TypeArray:
+		v, err := l.newContainer(t)
+		if err != nil {
+			return nil, err
+		}
+		n, err := l.readLen()
+		if err != nil {
+			return nil, err
+		}
+		if n > len(l.items) {
+			return nil, errSnapshotCounts
+		}
+		a := l.items[:n:n]
+		l.items = l.items[n:]
+		for i := range a {
+			if a[i], err = l.loadValue(depth); err != nil {
+				return nil, err
+			}
+		}
+		v.x.a = a
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unknown value type %d", t)
+	}
+}
+
+func (l *snapshotLoader) newValue(t Type) (*Value, error) {
+	if len(l.vs) == 0 {
+		return nil, errSnapshotCounts
+	}
+	v := &l.vs[0]
+	l.vs = l.vs[1:]
+	v.t = t
+	return v, nil
+}
+
+func (l *snapshotLoader) newContainer(t Type) (*Value, error) {
+	if len(l.ns) == 0 {
+		return nil, errSnapshotCounts
+	}
+	v, err := l.newValue(t)
+	if err != nil {
+		return nil, err
+	}
+	v.x = &l.ns[0]
+	l.ns = l.ns[1:]
+	return v, nil
+}
+
+func (l *snapshotLoader) readLen() (int, error) {
+	n, size := binary.Uvarint(l.b)
+	if size <= 0 || n > uint64(len(l.b)-size) {
+		return 0, errors.New("invalid length")
+	}
+	l.b = l.b[size:]
+	return int(n), nil
+}
+
+func (l *snapshotLoader) readString() (string, error) {
+	n, err := l.readLen()
+	if err != nil {
+		return "", err
+	}
+	s := b2s(l.b[:n])
+	l.b = l.b[n:]
+	return s, nil
+}
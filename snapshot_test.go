@@ -0,0 +1,249 @@
+package astjson
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestSnapshot(t *testing.T) {
+	a := arena.NewMonotonicArena()
+
+	t.Run("roundtrip", func(t *testing.T) {
+		f := func(s string) {
+			t.Helper()
+			v := MustParse(s)
+			b := mustAppendSnapshot(v, nil)
+			loaded, err := LoadSnapshot(a, b)
+			if err != nil {
+				t.Fatalf("unexpected error when loading snapshot of %q: %s", startEndString(s), err)
+			}
+			if ls, expected := loaded.String(), v.String(); ls != expected {
+				t.Fatalf("unexpected loaded value; got %s; want %s", startEndString(ls), startEndString(expected))
+			}
+
+			// Loading must work without an arena and after a prefix.
+			b = mustAppendSnapshot(v, []byte("prefix"))
+			loaded, err = LoadSnapshot(nil, b[len("prefix"):])
+			if err != nil {
+				t.Fatalf("unexpected error when loading snapshot of %q without arena: %s", startEndString(s), err)
+			}
+			if ls, expected := loaded.String(), v.String(); ls != expected {
+				t.Fatalf("unexpected loaded value without arena; got %s; want %s", startEndString(ls), startEndString(expected))
+			}
+		}
+
+		f(`null`)
+		f(`true`)
+		f(`false`)
+		f(`-12.5e+3`)
+		f(`NaN`)
+		f(`""`)
+		f(`"foo\"bar\\bazA😀"`)
+		f(`[]`)
+		f(`{}`)
+		f(`[[], {}, [[]], {"a": {}}, "", null]`)
+		f(`{"a\"bA": {"c": [1, 2.5, "x"]}, "d": {}, "e": [], "f": null, "": true}`)
+		f(smallFixture)
+		f(mediumFixture)
+		f(largeFixture)
+		f(canadaFixture)
+		f(citmFixture)
+		f(twitterFixture)
+	})
+
+	t.Run("zero copy", func(t *testing.T) {
+		b := mustAppendSnapshot(MustParse(`{"foo": ["bar", 123]}`), nil)
+		v, err := LoadSnapshot(a, b)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		for _, s := range []string{v.x.o.kvs[0].k, v.x.o.kvs[0].v.x.a[0].s, v.x.o.kvs[0].v.x.a[1].s} {
+			p := uintptr(unsafe.Pointer(unsafe.StringData(s)))
+			start := uintptr(unsafe.Pointer(&b[0]))
+			if p < start || p >= start+uintptr(len(b)) {
+				t.Fatalf("string %q doesn't reference the snapshot", s)
+			}
+		}
+	})
+
+	t.Run("modify", func(t *testing.T) {
+		b := mustAppendSnapshot(MustParse(`{"a": [1, 2], "b": [3], "c": {"d": 1}, "e": {"f": 2}}`), nil)
+		v, err := LoadSnapshot(a, b)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// Appending to loaded objects and arrays mustn't overwrite their neighbors.
+		v.Get("a").SetArrayItem(a, 2, IntValue(a, 4))
+		v.Get("c").Set(a, "g", IntValue(a, 5))
+		v.Set(a, "h", TrueValue(a))
+		v.Del("e")
+		if s, expected := v.String(), `{"a":[1,2,4],"b":[3],"c":{"d":1,"g":5},"h":true}`; s != expected {
+			t.Fatalf("unexpected value; got %s; want %s", s, expected)
+		}
+	})
+
+	t.Run("lazy and packed", func(t *testing.T) {
+		s := `{"a": {"b": [1, 2, 3]}, "c": [[1.5, 2, 3], {"d": "e"}]}`
+		var p Parser
+		v, err := p.ParseLazy(a, s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		pp := NewParser(ParserOptions{MinPackedArrayLength: 2})
+		pv, err := pp.ParseWithArena(a, s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		for _, v := range []*Value{v, pv} {
+			loaded, err := LoadSnapshot(a, mustAppendSnapshot(v, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if ls, expected := loaded.String(), MustParse(s).String(); ls != expected {
+				t.Fatalf("unexpected loaded value; got %s; want %s", ls, expected)
+			}
+		}
+	})
+
+	t.Run("built values", func(t *testing.T) {
+		v := ObjectValue(a)
+		v.Set(a, "a\"b", StringValue(a, "c\nd"))
+		v.Set(a, "e", ArrayValue(a))
+		v.Get("e").SetArrayItem(a, 0, FloatValue(a, 1.5))
+		v.Set(a, "f", FalseValue(a))
+		loaded, err := LoadSnapshot(a, mustAppendSnapshot(v, nil))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if ls, expected := loaded.String(), v.String(); ls != expected {
+			t.Fatalf("unexpected loaded value; got %s; want %s", ls, expected)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		f := func(b []byte) {
+			t.Helper()
+			_, err := LoadSnapshot(a, b)
+			if !errors.Is(err, ErrInvalidSnapshot) {
+				t.Fatalf("unexpected error; got %v; want %v", err, ErrInvalidSnapshot)
+			}
+		}
+
+		valid := mustAppendSnapshot(MustParse(`{"a": [1, "b", {"c": true}], "d": null}`), nil)
+		f(nil)
+		f(valid[:snapshotHeaderSize-1])
+		f([]byte(strings.Repeat("x", len(valid))))
+
+		// Every truncated or corrupted snapshot must be rejected.
+		for i := range valid {
+			f(valid[:i])
+			b := append([]byte{}, valid...)
+			b[i] ^= 0x20
+			f(b)
+		}
+		f(append(append([]byte{}, valid...), 0))
+
+		// Wrong version.
+		b := append([]byte{}, valid...)
+		b[4] = snapshotVersion + 1
+		_, err := LoadSnapshot(a, b)
+		if err == nil || !strings.Contains(err.Error(), "unsupported version") {
+			t.Fatalf("unexpected error for wrong version: %v", err)
+		}
+
+		// Snapshots with valid checksums, but invalid contents.
+		resum := func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[8:], crc32.Checksum(b[snapshotChecksumStart:], snapshotTable))
+			return b
+		}
+		for i := 0; i < 4; i++ {
+			for _, n := range []uint64{0, 1, 1 << 62} {
+				b := append([]byte{}, valid...)
+				binary.LittleEndian.PutUint64(b[12+8*i:], n)
+				f(resum(b))
+			}
+		}
+		for i := snapshotHeaderSize; i < len(valid); i++ {
+			for _, c := range []byte{0, 0x7f, 0xff} {
+				b := append([]byte{}, valid...)
+				b[i] = c
+				if _, err := LoadSnapshot(a, resum(b)); err != nil && !errors.Is(err, ErrInvalidSnapshot) {
+					t.Fatalf("unexpected error: %s", err)
+				}
+			}
+		}
+
+		// Too deep snapshot.
+		deep := MustParse(strings.Repeat("[", MaxDepth) + strings.Repeat("]", MaxDepth))
+		if _, err := LoadSnapshot(a, mustAppendSnapshot(deep, nil)); err != nil {
+			t.Fatalf("unexpected error for snapshot with max depth: %s", err)
+		}
+		tooDeep := ArrayValue(a)
+		tooDeep.SetArrayItem(a, 0, deep)
+		dst, err := tooDeep.AppendSnapshot([]byte("prefix"))
+		if !errors.Is(err, ErrMaxDepth) {
+			t.Fatalf("unexpected error for too deep value; got %v; want %v", err, ErrMaxDepth)
+		}
+		if string(dst) != "prefix" {
+			t.Fatalf("unexpected dst after error; got %q; want %q", dst, "prefix")
+		}
+
+		// Snapshots of too deep values are rejected even if they have been
+		// created by other means.
+		b = mustAppendSnapshot(deep, nil)
+		b = append(b[:snapshotHeaderSize], append([]byte{byte(TypeArray), 1}, b[snapshotHeaderSize:]...)...)
+		binary.LittleEndian.PutUint64(b[12:], binary.LittleEndian.Uint64(b[12:])+1)
+		binary.LittleEndian.PutUint64(b[20:], binary.LittleEndian.Uint64(b[20:])+1)
+		binary.LittleEndian.PutUint64(b[36:], binary.LittleEndian.Uint64(b[36:])+1)
+		_, err = LoadSnapshot(a, resum(b))
+		if !errors.Is(err, ErrInvalidSnapshot) || !errors.Is(err, ErrMaxDepth) {
+			t.Fatalf("unexpected error for too deep snapshot: %v", err)
+		}
+	})
+}
+
+// mustAppendSnapshot appends the snapshot of v to dst. It panics on error.
+func mustAppendSnapshot(v *Value, dst []byte) []byte {
+	dst, err := v.AppendSnapshot(dst)
+	if err != nil {
+		panic(err)
+	}
+	return dst
+}
+
+func BenchmarkLoadSnapshot(b *testing.B) {
+	for _, name := range []string{"canada", "citm_catalog", "twitter"} {
+		s := getFromFile(fmt.Sprintf("testdata/%s.json", name))
+		snapshot := mustAppendSnapshot(MustParse(s), nil)
+		a := arena.NewMonotonicArena(arena.WithMinBufferSize(1024 * 1024 * 32))
+		b.Run(name+"/parse", func(b *testing.B) {
+			var p Parser
+			b.SetBytes(int64(len(s)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := p.ParseWithArena(a, s); err != nil {
+					b.Fatalf("cannot parse json: %s", err)
+				}
+				a.Reset()
+			}
+		})
+		b.Run(name+"/snapshot", func(b *testing.B) {
+			b.SetBytes(int64(len(s)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := LoadSnapshot(a, snapshot); err != nil {
+					b.Fatalf("cannot load snapshot: %s", err)
+				}
+				a.Reset()
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package astjson
+
+import (
+	"github.com/wundergraph/go-arena"
+)
+
+// Span is the position of a parsed value or object key in the input.
+type Span struct {
+	// Start is the byte offset of the first char.
+	Start int
+
+	// End is the byte offset following the last char.
+	End int
+}
+
+// Len returns the length of the sp in bytes.
+func (sp Span) Len() int {
+	return sp.End - sp.Start
+}
+
+// Span returns the position of the v in the input passed to Parse*.
+//
+// false is returned if the v has been parsed without ParserOptions.Spans
+// or if it has been created after parsing.
+func (v *Value) Span() (Span, bool) {
+	if v == nil {
+		return Span{}, false
+	}
+	ext := v.ext()
+	if ext == nil || ext.sp == nil {
+		return Span{}, false
+	}
+	return *ext.sp, true
+}
+
+// KeySpan returns the position of the given key in the input passed to Parse*,
+// including quotes.
+//
+// false is returned if the key isn't found, if the o has been parsed
+// without ParserOptions.Spans or if the key has been set after parsing.
+func (o *Object) KeySpan(key string) (Span, bool) {
+	if o == nil {
+		return Span{}, false
+	}
+	for _, kv := range o.kvs {
+		if !kv.keyUnescaped {
+			o.unescapeKey(nil, kv)
+		}
+		if kv.k == key {
+			return kv.keySpan()
+		}
+	}
+	return Span{}, false
+}
+
+// LineColumn returns the 1-based line and column of the byte
+// at the given offset in s.
+//
+// The column is counted in bytes. The offset is clamped to the bounds of s.
+func LineColumn(s string, offset int) (int, int) {
+	offset = max(0, min(offset, len(s)))
+	return lineColumn(s, offset)
+}
+
+// newSpan returns the span of the input from the start of s up to the start of tail.
+func (p *Parser) newSpan(s, tail string) *Span {
+	var sp *Span
+	if p.a != nil {
+		sp = arena.Allocate[Span](p.a)
+	} else {
+		p.c.sps = append(p.c.sps, Span{})
+		sp = &p.c.sps[len(p.c.sps)-1]
+	}
+	sp.Start = len(p.src) - len(s)
+	sp.End = len(p.src) - len(tail)
+	return sp
+}
+
+// setKeySpan records the span sp of the key of kv.
+//
+// kv.v must be already parsed with a span, so it has ext.
+func (p *Parser) setKeySpan(kv *kv, sp *Span) {
+	ext := kv.v.x.ext
+	ext.ksp = sp
+	ext.kv = kv
+}
+
+// keySpan returns the span of the key of kv.
+//
+// false is returned if the key or the value has been set after parsing.
+func (kv *kv) keySpan() (Span, bool) {
+	if kv.v == nil {
+		return Span{}, false
+	}
+	ext := kv.v.ext()
+	if ext == nil || ext.ksp == nil || ext.kv != kv {
+		return Span{}, false
+	}
+	return *ext.ksp, true
+}
+
+// withSpan records the span of v parsed from s up to tail.
+//
+// The shared true, false and null values are replaced with new values,
+// since they cannot hold spans.
+func (p *Parser) withSpan(v *Value, s, tail string) *Value {
+	if v == valueTrue || v == valueFalse || v == valueNull {
+		nv := p.newValue()
+		nv.t = v.t
+		v = nv
+	}
+	p.getExt(v).sp = p.newSpan(s, tail)
+	return v
+}
@@ -0,0 +1,151 @@
+package astjson
+
+import (
+	"testing"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestValueSpan(t *testing.T) {
+	f := func(t *testing.T, opts ParserOptions, parse func(p *Parser, s string) (*Value, error)) {
+		t.Helper()
+
+		s := "{\n  \"a\": [1, true, null],\n  \"b\\u0063\": {\"d\": \"foo\"}\n}"
+		p := NewParser(opts)
+		v, err := parse(p, s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		checkSpan := func(v *Value, expected string) {
+			t.Helper()
+			sp, ok := v.Span()
+			if !ok {
+				t.Fatalf("missing span for %s", v)
+			}
+			if raw := s[sp.Start:sp.End]; raw != expected {
+				t.Fatalf("unexpected span %+v; got %q; want %q", sp, raw, expected)
+			}
+		}
+		checkKeySpan := func(o *Object, key, expected string) {
+			t.Helper()
+			sp, ok := o.KeySpan(key)
+			if !ok {
+				t.Fatalf("missing span for key %q", key)
+			}
+			if raw := s[sp.Start:sp.End]; raw != expected {
+				t.Fatalf("unexpected span %+v for key %q; got %q; want %q", sp, key, raw, expected)
+			}
+		}
+
+		checkSpan(v, s)
+		checkSpan(v.Get("a"), "[1, true, null]")
+		checkSpan(v.Get("a", "0"), "1")
+		checkSpan(v.Get("a", "1"), "true")
+		checkSpan(v.Get("a", "2"), "null")
+		checkSpan(v.Get("bc"), `{"d": "foo"}`)
+		checkSpan(v.Get("bc", "d"), `"foo"`)
+		checkKeySpan(v.GetObject(), "a", `"a"`)
+		checkKeySpan(v.GetObject(), "bc", `"b\u0063"`)
+		checkKeySpan(v.GetObject("bc"), "d", `"d"`)
+		if _, ok := v.GetObject().KeySpan("missing"); ok {
+			t.Fatalf("unexpected span for missing key")
+		}
+
+		// Shared literals must not be modified.
+		if _, ok := valueTrue.Span(); ok {
+			t.Fatalf("unexpected span for the shared true value")
+		}
+
+		sp, _ := v.Get("bc", "d").Span()
+		line, column := LineColumn(s, sp.Start)
+		if line != 3 || column != 20 {
+			t.Fatalf("unexpected position; got line %d, column %d; want line 3, column 20", line, column)
+		}
+	}
+
+	t.Run("Parse", func(t *testing.T) {
+		f(t, ParserOptions{Spans: true}, func(p *Parser, s string) (*Value, error) {
+			return p.Parse(s)
+		})
+	})
+	t.Run("ParseWithArena", func(t *testing.T) {
+		f(t, ParserOptions{Spans: true}, func(p *Parser, s string) (*Value, error) {
+			return p.ParseWithArena(arena.NewMonotonicArena(), s)
+		})
+	})
+	t.Run("ParseIndexed", func(t *testing.T) {
+		f(t, ParserOptions{Spans: true}, func(p *Parser, s string) (*Value, error) {
+			return p.ParseIndexed(nil, s)
+		})
+	})
+	t.Run("JSON5", func(t *testing.T) {
+		f(t, ParserOptions{Spans: true, JSON5: true}, func(p *Parser, s string) (*Value, error) {
+			return p.Parse(s)
+		})
+	})
+}
+
+func TestKeySpanAfterSet(t *testing.T) {
+	p := NewParser(ParserOptions{Spans: true})
+	v, err := p.Parse(`{"a": 1, "b": {"c": 2}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	o := v.GetObject()
+	if _, ok := o.KeySpan("a"); !ok {
+		t.Fatalf("missing span for key %q", "a")
+	}
+
+	v.Set(nil, "a", MustParse(`3`))
+	if _, ok := o.KeySpan("a"); ok {
+		t.Fatalf("unexpected span for the key with a new value")
+	}
+	v.Set(nil, "moved", v.Get("b", "c"))
+	if _, ok := o.KeySpan("moved"); ok {
+		t.Fatalf("unexpected span for the key set after parsing")
+	}
+	if sp, ok := v.GetObject("b").KeySpan("c"); !ok || sp.Start != 15 {
+		t.Fatalf("unexpected span for key %q; got %+v", "c", sp)
+	}
+}
+
+func TestValueSpanDisabled(t *testing.T) {
+	var p Parser
+	v, err := p.Parse(`{"a": 1}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := v.Span(); ok {
+		t.Fatalf("unexpected span without ParserOptions.Spans")
+	}
+	if _, ok := v.GetObject().KeySpan("a"); ok {
+		t.Fatalf("unexpected key span without ParserOptions.Spans")
+	}
+
+	p2 := NewParser(ParserOptions{Spans: true})
+	v, err = p2.ParseLazy(nil, `{"a": {"b": 1}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := v.Get("a", "b").Span(); ok {
+		t.Fatalf("unexpected span in lazy mode")
+	}
+}
+
+func TestLineColumn(t *testing.T) {
+	f := func(s string, offset, line, column int) {
+		t.Helper()
+		l, c := LineColumn(s, offset)
+		if l != line || c != column {
+			t.Fatalf("unexpected position of offset %d in %q; got line %d, column %d; want line %d, column %d", offset, s, l, c, line, column)
+		}
+	}
+	f("", 0, 1, 1)
+	f("abc", 2, 1, 3)
+	f("a\nbc", 1, 1, 2)
+	f("a\nbc", 2, 2, 1)
+	f("a\nbc", 3, 2, 2)
+	f("a\nbc", 100, 2, 3)
+	f("a\nbc", -1, 1, 1)
+}
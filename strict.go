@@ -0,0 +1,87 @@
+package astjson
+
+import (
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// validateStrictString validates the raw string s according to RFC 8259.
+//
+// s must contain the string contents without the surrounding quotes.
+func validateStrictString(s string) error {
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= 0x20 && c < utf8.RuneSelf && c != '\\' {
+			// Fast path - printable ASCII char.
+			i++
+			continue
+		}
+		if c < 0x20 {
+			return errorf(ErrorKindBadString, "string cannot contain control char 0x%02X", c)
+		}
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				return errorf(ErrorKindBadString, "invalid UTF-8 byte 0x%02X", c)
+			}
+			i += size
+			continue
+		}
+
+		// Escape sequence.
+		if i+1 >= len(s) {
+			return errorf(ErrorKindBadEscape, "missing escaped char after backslash")
+		}
+		switch ch := s[i+1]; ch {
+		case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+			i += 2
+		case 'u':
+			x, err := parseEscapedRune(s[i:])
+			if err != nil {
+				return err
+			}
+			i += 6
+			if !utf16.IsSurrogate(x) {
+				continue
+			}
+			// See https://en.wikipedia.org/wiki/UTF-16#U+D800_to_U+DFFF
+			if x >= 0xDC00 {
+				return errorf(ErrorKindBadEscape, `unpaired surrogate \u%s`, s[i-4:i])
+			}
+			x1, err := parseEscapedRune(s[i:])
+			if err != nil || x1 < 0xDC00 || x1 > 0xDFFF {
+				return errorf(ErrorKindBadEscape, `unpaired surrogate \u%s`, s[i-4:i])
+			}
+			i += 6
+		default:
+			return errorf(ErrorKindBadEscape, `unknown escape sequence \%c`, ch)
+		}
+	}
+	return nil
+}
+
+// parseEscapedRune parses the \uXXXX escape sequence at the start of s.
+func parseEscapedRune(s string) (rune, error) {
+	if len(s) < 6 || s[0] != '\\' || s[1] != 'u' {
+		return 0, errorf(ErrorKindBadEscape, `too short escape sequence: %q`, s)
+	}
+	xs := s[2:6]
+	x, err := strconv.ParseUint(xs, 16, 16)
+	if err != nil {
+		return 0, errorf(ErrorKindBadEscape, `invalid escape sequence \u%s: %s`, xs, err)
+	}
+	return rune(x), nil
+}
+
+// validateStrictNumber validates the raw number s according to RFC 8259.
+func validateStrictNumber(s string) error {
+	tail, err := validateNumber(s)
+	if err != nil {
+		return err
+	}
+	if len(tail) > 0 {
+		return errorf(ErrorKindBadNumber, "unexpected char: %q", tail[:1])
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package astjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// strictConformanceTests are modelled on https://github.com/nst/JSONTestSuite .
+//
+// y_* tests must be accepted, n_* tests must be rejected in strict mode.
+// i_* tests are implementation-defined; the expected strict result is given
+// by their prefix after i_: i_y_* are accepted, i_n_* are rejected.
+var strictConformanceTests = map[string]string{
+	"y_array_arraysWithSpaces":              `[[]   ]`,
+	"y_array_empty":                         `[]`,
+	"y_array_empty-string":                  `[""]`,
+	"y_array_ending_with_newline":           "[\"a\"]\n",
+	"y_array_false":                         `[false]`,
+	"y_array_heterogeneous":                 `[null, 1, "1", {}]`,
+	"y_array_null":                          `[null]`,
+	"y_array_with_leading_space":            ` [1]`,
+	"y_array_with_several_null":             `[1,null,null,null,2]`,
+	"y_number":                              `[123e65]`,
+	"y_number_0e+1":                         `[0e+1]`,
+	"y_number_0e1":                          `[0e1]`,
+	"y_number_after_space":                  `[ 4]`,
+	"y_number_double_close_to_zero":         `[-0.000000000000000000000000000000000000000000000000000000000000000000000000000001]`,
+	"y_number_int_with_exp":                 `[20e1]`,
+	"y_number_minus_zero":                   `[-0]`,
+	"y_number_negative_int":                 `[-123]`,
+	"y_number_real_capital_e_neg_exp":       `[1E-2]`,
+	"y_number_real_exponent":                `[123e45]`,
+	"y_number_real_fraction_exponent":       `[123.456e78]`,
+	"y_number_simple_real":                  `[123.456789]`,
+	"y_object_basic":                        `{"asd":"sdf"}`,
+	"y_object_duplicated_key":               `{"a":"b","a":"c"}`,
+	"y_object_empty_key":                    `{"":0}`,
+	"y_object_escaped_null_in_key":          `{"foo\u0000bar": 42}`,
+	"y_object_extreme_numbers":              `{ "min": -1.0e+28, "max": 1.0e+28 }`,
+	"y_object_long_strings":                 `{"x":[{"id": "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"}], "id": "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"}`,
+	"y_object_string_unicode":               `{"title":"\u041f\u043e\u043b\u0442\u043e\u0440\u0430 \u0417\u0435\u043c\u043b\u0435\u043a\u043e\u043f\u0430" }`,
+	"y_object_with_newlines":                "{\n\"a\": \"b\"\n}",
+	"y_string_1_2_3_bytes_UTF-8_sequences":  `["\u0060\u012a\u12AB"]`,
+	"y_string_accepted_surrogate_pair":      `["\uD801\udc37"]`,
+	"y_string_allowed_escapes":              `["\"\\\/\b\f\n\r\t"]`,
+	"y_string_backslash_and_u_escaped_zero": `["\\u0000"]`,
+	"y_string_escaped_control_character":    `["\u0012"]`,
+	"y_string_in_array_with_leading_space":  `[ "asd"]`,
+	"y_string_last_surrogates_1_and_2":      `["\uDBFF\uDFFF"]`,
+	"y_string_nonCharacterInUTF-8_U+FFFF":   "[\"\xef\xbf\xbf\"]",
+	"y_string_unescaped_char_delete":        "[\"\x7f\"]",
+	"y_string_unicode_2":                    `["⍂㈴⍂"]`,
+	"y_string_utf8":                         `["€𝄞"]`,
+	"y_structure_lonely_int":                `42`,
+	"y_structure_lonely_string":             `"asd"`,
+	"y_structure_true_in_array":             `[true]`,
+	"y_structure_whitespace_array":          " [] ",
+
+	"n_array_1_true_without_comma":             `[1 true]`,
+	"n_array_comma_and_number":                 `[,1]`,
+	"n_array_extra_comma":                      `["",]`,
+	"n_array_incomplete":                       `["x"`,
+	"n_array_unclosed":                         `[""`,
+	"n_incomplete_false":                       `[fals]`,
+	"n_incomplete_null":                        `[nul]`,
+	"n_number_++":                              `[++1234]`,
+	"n_number_+1":                              `[+1]`,
+	"n_number_+Inf":                            `[+Inf]`,
+	"n_number_-01":                             `[-01]`,
+	"n_number_-NaN":                            `[-NaN]`,
+	"n_number_.-1":                             `[.-1]`,
+	"n_number_.2e-3":                           `[.2e-3]`,
+	"n_number_0.e1":                            `[0.e1]`,
+	"n_number_0_capital_E+":                    `[0E+]`,
+	"n_number_1.0e-":                           `[1.0e-]`,
+	"n_number_2.e3":                            `[2.e3]`,
+	"n_number_Inf":                             `[Inf]`,
+	"n_number_NaN":                             `[NaN]`,
+	"n_number_infinity":                        `[Infinity]`,
+	"n_number_minus_infinity":                  `[-Infinity]`,
+	"n_number_nan":                             `[nan]`,
+	"n_number_neg_int_starting_with_zero":      `[-012]`,
+	"n_number_real_without_fractional":         `[1.]`,
+	"n_number_with_leading_zero":               `[012]`,
+	"n_number_1_000":                           `[1 000.0]`,
+	"n_number_minus_sign_with_trailing":        `[-foo]`,
+	"n_number_double_minus":                    `[1--2]`,
+	"n_number_hex":                             `[0x1]`,
+	"n_object_bad_value":                       `["x", truth]`,
+	"n_object_missing_colon":                   `{"a" b}`,
+	"n_object_non_string_key":                  `{1:1}`,
+	"n_object_single_quote":                    `{'a':0}`,
+	"n_object_trailing_comma":                  `{"id":0,}`,
+	"n_object_unquoted_key":                    `{a: "b"}`,
+	"n_object_bad_escape_in_key":               `{"\x": 1}`,
+	"n_object_invalid_utf8_in_key":             "{\"\xff\": 1}",
+	"n_object_control_char_in_key":             "{\"a\x01\": 1}",
+	"n_string_1_surrogate_then_escape":         `["\uD800\"]`,
+	"n_string_escape_x":                        `["\x00"]`,
+	"n_string_escaped_backslash_bad":           `["\\\"]`,
+	"n_string_escaped_emoji":                   `["\🌀"]`,
+	"n_string_incomplete_escape":               `["\"]`,
+	"n_string_incomplete_surrogate":            `["\uD834\uDd"]`,
+	"n_string_invalid-utf-8-in-escape":         "[\"\\u\xe5\"]",
+	"n_string_invalid_backslash_esc":           `["\a"]`,
+	"n_string_invalid_unicode_escape":          `["\uqqqq"]`,
+	"n_string_invalid_utf8_after_escape":       "[\"\\\xe5\"]",
+	"n_string_single_quote":                    `['single quote']`,
+	"n_string_unescaped_ctrl_char":             "[\"a\x00a\"]",
+	"n_string_unescaped_newline":               "[\"new\nline\"]",
+	"n_string_unescaped_tab":                   "[\"\t\"]",
+	"n_string_start_escape_unclosed":           `["\`,
+	"n_structure_UTF8_BOM_no_data":             "\xef\xbb\xbf",
+	"n_structure_angle_bracket_null":           `[<null>]`,
+	"n_structure_capitalized_True":             `[True]`,
+	"n_structure_close_unopened_array":         `1]`,
+	"n_structure_double_array":                 `[][]`,
+	"n_structure_end_array":                    `]`,
+	"n_structure_lone-open-bracket":            `[`,
+	"n_structure_no_data":                      ``,
+	"n_structure_null-byte-outside-string":     "[\x00]",
+	"n_structure_object_with_trailing_garbage": `{"a": true} "x"`,
+	"n_structure_unclosed_object":              `{"asd":"asd"`,
+	"n_structure_whitespace_formfeed":          "[\f]",
+
+	"i_y_number_huge_exp":                          `[0.4e00669999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999999969999999006]`,
+	"i_y_number_real_underflow":                    `[123e-10000000]`,
+	"i_y_number_very_big_negative_int":             `[-237462374673276894279832749832423479823246327846]`,
+	"i_y_string_UTF-8_noncharacter":                "[\"\xf4\x8f\xbf\xbf\"]",
+	"i_n_string_1st_surrogate_but_2nd_missing":     `["\uDADA"]`,
+	"i_n_string_1st_valid_surrogate_2nd_invalid":   `["\uD888\u1234"]`,
+	"i_n_string_incomplete_surrogate_and_escape":   `["\uD800\n"]`,
+	"i_n_string_invalid_lonely_surrogate":          `["\ud800"]`,
+	"i_n_string_inverted_surrogates_U+1D11E":       `["\uDd1e\uD834"]`,
+	"i_n_string_lone_second_surrogate":             `["\uDFAA"]`,
+	"i_n_string_UTF-8_invalid_sequence":            "[\"\xe6\x97\xa5\xd1\x88\xfa\"]",
+	"i_n_string_UTF8_surrogate_U+D800":             "[\"\xed\xa0\x80\"]",
+	"i_n_string_iso_latin_1":                       "[\"\xe9\"]",
+	"i_n_string_lone_utf8_continuation_byte":       "[\"\x81\"]",
+	"i_n_string_not_in_unicode_range":              "[\"\xf4\xbf\xbf\xbf\"]",
+	"i_n_string_overlong_sequence_2_bytes":         "[\"\xc0\xaf\"]",
+	"i_n_string_truncated-utf-8":                   "[\"\xe0\xff\"]",
+	"i_n_string_invalid_utf-8_in_object_value":     "{\"a\": \"\xff\"}",
+	"i_n_string_lone_surrogate_in_nested_object":   `{"a": [{"b": "\uD800"}]}`,
+	"i_n_structure_UTF-8_BOM_empty_object":         "\xef\xbb\xbf{}",
+	"i_n_number_in_object_nan":                     `{"a": NaN}`,
+	"i_n_number_in_object_inf":                     `{"a": -inf}`,
+	"i_n_number_in_object_double_dot":              `{"a": 1.2.3}`,
+	"i_n_number_in_object_trailing_exponent_minus": `{"a": 1e5-}`,
+}
+
+func TestStrictConformance(t *testing.T) {
+	opts := ParserOptions{
+		Strict: true,
+	}
+	p := NewParser(opts)
+	parsers := map[string]func(s string) (*Value, error){
+		"Parse": func(s string) (*Value, error) {
+			return p.Parse(s)
+		},
+		"ParseLazy": func(s string) (*Value, error) {
+			return p.ParseLazy(nil, s)
+		},
+		"ParseIndexed": func(s string) (*Value, error) {
+			return p.ParseIndexed(nil, s)
+		},
+		"ParseWithProjection": func(s string) (*Value, error) {
+			return p.ParseWithProjection(nil, s, CompileProjection([]string{ProjectionWildcard}))
+		},
+	}
+
+	for name, s := range strictConformanceTests {
+		t.Run(name, func(t *testing.T) {
+			accept := strings.HasPrefix(name, "y_") || strings.HasPrefix(name, "i_y_")
+
+			err := ValidateWithOptions(s, opts)
+			if accept && err != nil {
+				t.Fatalf("ValidateWithOptions: unexpected error for %q: %s", s, err)
+			}
+			if !accept && err == nil {
+				t.Fatalf("ValidateWithOptions: expecting non-nil error for %q", s)
+			}
+
+			for pname, parse := range parsers {
+				v, err := parse(s)
+				if !accept {
+					if err == nil {
+						t.Fatalf("%s: expecting non-nil error for %q", pname, s)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("%s: unexpected error for %q: %s", pname, s, err)
+				}
+
+				// The marshaled value must be accepted by other parsers
+				// and must remain unchanged after re-parsing.
+				b := v.MarshalTo(nil)
+				if !json.Valid(b) {
+					t.Fatalf("%s: encoding/json rejects the marshaled %q: %q", pname, s, b)
+				}
+				if err := ValidateWithOptions(b2s(b), opts); err != nil {
+					t.Fatalf("%s: cannot validate the marshaled %q: %s", pname, b, err)
+				}
+				var p2 Parser
+				v2, err := p2.ParseBytes(b)
+				if err != nil {
+					t.Fatalf("%s: cannot parse the marshaled %q: %s", pname, b, err)
+				}
+				// Lazy values are marshaled verbatim, so compare compacted JSON.
+				var want, got bytes.Buffer
+				if err := json.Compact(&want, b); err != nil {
+					t.Fatalf("%s: cannot compact %q: %s", pname, b, err)
+				}
+				if err := json.Compact(&got, v2.MarshalTo(nil)); err != nil {
+					t.Fatalf("%s: cannot compact the re-parsed %q: %s", pname, b, err)
+				}
+				if got.String() != want.String() {
+					t.Fatalf("%s: unexpected value after re-parsing %q; got %q; want %q", pname, s, got.String(), want.String())
+				}
+			}
+		})
+	}
+}
+
+func TestStrictDefaultMode(t *testing.T) {
+	// The default mode keeps accepting the non-standard input.
+	for _, s := range []string{
+		`[NaN]`,
+		`[-Inf]`,
+		`[1.2.3]`,
+		`["\x"]`,
+		`["\uD800"]`,
+		"[\"\xff\"]",
+	} {
+		var p Parser
+		if _, err := p.Parse(s); err != nil {
+			t.Fatalf("unexpected error for %q: %s", s, err)
+		}
+		sp := NewParser(ParserOptions{Strict: true})
+		if _, err := sp.Parse(s); err == nil {
+			t.Fatalf("expecting non-nil error for %q in strict mode", s)
+		}
+	}
+}
+
+func TestValidateStrictString(t *testing.T) {
+	f := func(s, expectedErr string) {
+		t.Helper()
+		err := validateStrictString(s)
+		if expectedErr == "" {
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %s", s, err)
+			}
+			return
+		}
+		if err == nil || !strings.Contains(err.Error(), expectedErr) {
+			t.Fatalf("unexpected error for %q; got %v; want %q", s, err, expectedErr)
+		}
+	}
+
+	f(``, "")
+	f(`foo bar`, "")
+	f(`\"\\\/\b\f\n\r\t`, "")
+	f(`\u0000\uffff`, "")
+	f(`\uD834\uDD1E`, "")
+	f("привет", "")
+	f("a\x1fb", "control char 0x1F")
+	f("a\xffb", "invalid UTF-8 byte 0xFF")
+	f(`\x`, `unknown escape sequence \x`)
+	f(`\u12`, "too short escape sequence")
+	f(`\u12zz`, `invalid escape sequence \u12zz`)
+	f(`\uD834`, `unpaired surrogate \uD834`)
+	f(`\uD834\n`, `unpaired surrogate \uD834`)
+	f(`\uD834\u0041`, `unpaired surrogate \uD834`)
+	f(`\uDD1E\uD834`, `unpaired surrogate \uDD1E`)
+	f(`\`, "missing escaped char")
+}
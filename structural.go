@@ -0,0 +1,169 @@
+package astjson
+
+import (
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// blockClass contains bitmaps of the chars found in a 64-byte block.
+//
+// Bit i corresponds to the i-th byte of the block.
+type blockClass struct {
+	quote     uint64
+	backslash uint64
+
+	// op contains '{', '}', '[', ']', ':' and ','.
+	op uint64
+
+	// ws contains ' ', '\t', '\n' and '\r'.
+	ws uint64
+}
+
+// structuralBatchSize is the number of 64-byte blocks classified at once.
+const structuralBatchSize = 64
+
+const (
+	charQuote = 1 << iota
+	charBackslash
+	charOp
+	charWS
+)
+
+var charClasses = func() (t [256]uint8) {
+	t['"'] = charQuote
+	t['\\'] = charBackslash
+	for _, c := range "{}[]:," {
+		t[c] = charOp
+	}
+	for _, c := range " \t\n\r" {
+		t[c] = charWS
+	}
+	return t
+}()
+
+// classifyBlocksGeneric fills dst with the classes of the 64-byte blocks in s.
+func classifyBlocksGeneric(dst []blockClass, s string) {
+	for i := range dst {
+		b := s[i*64 : i*64+64]
+		var bc blockClass
+		for j := 0; j < 64; j++ {
+			c := uint64(charClasses[b[j]])
+			bc.quote |= (c & charQuote) << j
+			bc.backslash |= (c & charBackslash >> 1) << j
+			bc.op |= (c & charOp >> 2) << j
+			bc.ws |= (c & charWS >> 3) << j
+		}
+		dst[i] = bc
+	}
+}
+
+// structuralScanner carries the state of buildStructuralIndex between blocks.
+type structuralScanner struct {
+	// prevEscaped is 1 if the first char of the next block is escaped.
+	prevEscaped uint64
+
+	// prevInString is all ones if the next block starts inside a string.
+	prevInString uint64
+
+	// prevScalar is 1 if the previous block ends with a scalar char other than quote.
+	prevScalar uint64
+}
+
+// buildStructuralIndex appends to dst the positions of the structural
+// chars in s and returns the result.
+//
+// Structural chars are '{', '}', '[', ']', ':' and ',' outside strings,
+// opening quotes of strings and the first chars of other scalar values.
+// Closing quotes aren't included, so every position starts a token.
+//
+// false is returned if s ends inside a string or if it is too big
+// for the index. The index must not be used in this case.
+func buildStructuralIndex(dst []uint32, s string) ([]uint32, bool) {
+	if uint64(len(s)) >= math.MaxUint32 {
+		return dst, false
+	}
+	var sc structuralScanner
+	var blocks [structuralBatchSize]blockClass
+	n := len(s) / 64
+	for i := 0; i < n; i += structuralBatchSize {
+		batch := blocks[:min(n-i, structuralBatchSize)]
+		classifyBlocks(batch, s[i*64:(i+len(batch))*64])
+		for j := range batch {
+			dst = sc.appendStructurals(dst, &batch[j], uint32((i+j)*64))
+		}
+	}
+	if tail := s[n*64:]; len(tail) > 0 {
+		// Pad the last block with whitespace.
+		var buf [64]byte
+		copy(buf[:], tail)
+		for i := len(tail); i < len(buf); i++ {
+			buf[i] = ' '
+		}
+		classifyBlocksGeneric(blocks[:1], b2s(buf[:]))
+		dst = sc.appendStructurals(dst, &blocks[0], uint32(n*64))
+	}
+	return dst, sc.prevInString == 0
+}
+
+// appendStructurals appends the positions of the structural chars
+// in the block bc starting at offset to dst.
+func (sc *structuralScanner) appendStructurals(dst []uint32, bc *blockClass, offset uint32) []uint32 {
+	escaped := sc.findEscaped(bc.backslash)
+	quote := bc.quote &^ escaped
+
+	// Every unescaped quote toggles the in-string state.
+	inString := prefixXor(quote) ^ sc.prevInString
+	sc.prevInString = uint64(int64(inString) >> 63)
+
+	// The first char of a run of scalar chars starts a scalar value.
+	scalar := ^(bc.op | bc.ws)
+	nonQuoteScalar := scalar &^ quote
+	followsNonQuoteScalar := nonQuoteScalar<<1 | sc.prevScalar
+	sc.prevScalar = nonQuoteScalar >> 63
+	scalarStart := scalar &^ followsNonQuoteScalar
+
+	// Drop everything inside strings including closing quotes.
+	stringTail := inString ^ quote
+	structurals := (bc.op | scalarStart) &^ stringTail
+
+	n := len(dst)
+	dst = slices.Grow(dst, 64)[:n+bits.OnesCount64(structurals)]
+	for structurals != 0 {
+		dst[n] = offset + uint32(bits.TrailingZeros64(structurals))
+		structurals &= structurals - 1
+		n++
+	}
+	return dst
+}
+
+// findEscaped returns the chars escaped by the given backslashes.
+//
+// A char is escaped if it is preceded by an odd number of backslashes.
+func (sc *structuralScanner) findEscaped(backslash uint64) uint64 {
+	const evenBits = 0x5555555555555555
+
+	// The first backslash doesn't start an escape sequence if it is escaped.
+	backslash &^= sc.prevEscaped
+	followsEscape := backslash<<1 | sc.prevEscaped
+
+	// Sequences starting at odd bits are cleared out by the addition,
+	// so only sequences starting at even bits remain.
+	oddSequenceStarts := backslash &^ evenBits &^ followsEscape
+	sequencesStartingOnEvenBits, carry := bits.Add64(oddSequenceStarts, backslash, 0)
+	sc.prevEscaped = carry
+	invertMask := sequencesStartingOnEvenBits << 1
+
+	return (evenBits ^ invertMask) & followsEscape
+}
+
+// prefixXor returns the bitmap, where bit i is the xor of bits 0..i of x.
+func prefixXor(x uint64) uint64 {
+	x ^= x << 1
+	x ^= x << 2
+	x ^= x << 4
+	x ^= x << 8
+	x ^= x << 16
+	x ^= x << 32
+	return x
+}
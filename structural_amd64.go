@@ -0,0 +1,28 @@
+//go:build amd64 && !purego
+
+package astjson
+
+import (
+	"unsafe"
+)
+
+// hasAVX2 is set if the CPU and the OS support AVX2.
+var hasAVX2 = cpuHasAVX2()
+
+func cpuHasAVX2() bool
+
+// classifyBlocksAVX2 fills n blockClass items at dst with the classes
+// of the n 64-byte blocks at src.
+//
+//go:noescape
+func classifyBlocksAVX2(src *byte, n int, dst *blockClass)
+
+// classifyBlocks fills dst with the classes of the 64-byte blocks in s.
+func classifyBlocks(dst []blockClass, s string) {
+	if !hasAVX2 || len(dst) == 0 {
+		classifyBlocksGeneric(dst, s)
+		return
+	}
+	_ = s[len(dst)*64-1]
+	classifyBlocksAVX2(unsafe.StringData(s), len(dst), &dst[0])
+}
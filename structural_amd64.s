@@ -0,0 +1,142 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// func cpuHasAVX2() bool
+TEXT ·cpuHasAVX2(SB), NOSPLIT, $0-1
+	// CPUID leaf 7 must be supported.
+	XORL AX, AX
+	XORL CX, CX
+	CPUID
+	CMPL AX, $7
+	JB   no
+
+	// CPUID.1:ECX must contain OSXSAVE (bit 27) and AVX (bit 28).
+	MOVL $1, AX
+	XORL CX, CX
+	CPUID
+	ANDL $0x18000000, CX
+	CMPL CX, $0x18000000
+	JNE  no
+
+	// The OS must save XMM and YMM registers.
+	XORL CX, CX
+	XGETBV
+	ANDL $6, AX
+	CMPL AX, $6
+	JNE  no
+
+	// CPUID.7.0:EBX must contain AVX2 (bit 5).
+	MOVL $7, AX
+	XORL CX, CX
+	CPUID
+	ANDL $0x20, BX
+	JZ   no
+
+	MOVB $1, ret+0(FP)
+	RET
+
+no:
+	MOVB $0, ret+0(FP)
+	RET
+
+// BROADCAST fills all the bytes of y with c.
+#define BROADCAST(c, y) \
+	MOVL $c, AX; \
+	MOVQ AX, X15; \
+	VPBROADCASTB X15, y
+
+// func classifyBlocksAVX2(src *byte, n int, dst *blockClass)
+TEXT ·classifyBlocksAVX2(SB), NOSPLIT, $0-24
+	MOVQ src+0(FP), SI
+	MOVQ n+8(FP), CX
+	MOVQ dst+16(FP), DI
+
+	BROADCAST(0x22, Y0) // '"'
+	BROADCAST(0x5c, Y1) // '\\'
+	BROADCAST(0x20, Y2) // ' '
+	BROADCAST(0x7b, Y3) // '{'
+	BROADCAST(0x7d, Y4) // '}'
+	BROADCAST(0x2c, Y5) // ','
+	BROADCAST(0x3a, Y6) // ':'
+	BROADCAST(0x09, Y7) // '\t'
+	BROADCAST(0x0a, Y8) // '\n'
+	BROADCAST(0x0d, Y9) // '\r'
+
+loop:
+	TESTQ CX, CX
+	JZ    done
+
+	VMOVDQU (SI), Y10
+	VMOVDQU 32(SI), Y11
+
+	// Quotes.
+	VPCMPEQB  Y0, Y10, Y12
+	VPMOVMSKB Y12, AX
+	VPCMPEQB  Y0, Y11, Y12
+	VPMOVMSKB Y12, BX
+	SHLQ      $32, BX
+	ORQ       BX, AX
+	MOVQ      AX, 0(DI)
+
+	// Backslashes.
+	VPCMPEQB  Y1, Y10, Y12
+	VPMOVMSKB Y12, AX
+	VPCMPEQB  Y1, Y11, Y12
+	VPMOVMSKB Y12, BX
+	SHLQ      $32, BX
+	ORQ       BX, AX
+	MOVQ      AX, 8(DI)
+
+	// Structural ops. '[' and ']' turn into '{' and '}' after setting bit 0x20.
+	VPOR      Y2, Y10, Y12
+	VPCMPEQB  Y3, Y12, Y13
+	VPCMPEQB  Y4, Y12, Y12
+	VPOR      Y12, Y13, Y13
+	VPCMPEQB  Y5, Y10, Y12
+	VPOR      Y12, Y13, Y13
+	VPCMPEQB  Y6, Y10, Y12
+	VPOR      Y12, Y13, Y13
+	VPMOVMSKB Y13, AX
+	VPOR      Y2, Y11, Y12
+	VPCMPEQB  Y3, Y12, Y13
+	VPCMPEQB  Y4, Y12, Y12
+	VPOR      Y12, Y13, Y13
+	VPCMPEQB  Y5, Y11, Y12
+	VPOR      Y12, Y13, Y13
+	VPCMPEQB  Y6, Y11, Y12
+	VPOR      Y12, Y13, Y13
+	VPMOVMSKB Y13, BX
+	SHLQ      $32, BX
+	ORQ       BX, AX
+	MOVQ      AX, 16(DI)
+
+	// Whitespace.
+	VPCMPEQB  Y2, Y10, Y13
+	VPCMPEQB  Y7, Y10, Y12
+	VPOR      Y12, Y13, Y13
+	VPCMPEQB  Y8, Y10, Y12
+	VPOR      Y12, Y13, Y13
+	VPCMPEQB  Y9, Y10, Y12
+	VPOR      Y12, Y13, Y13
+	VPMOVMSKB Y13, AX
+	VPCMPEQB  Y2, Y11, Y13
+	VPCMPEQB  Y7, Y11, Y12
+	VPOR      Y12, Y13, Y13
+	VPCMPEQB  Y8, Y11, Y12
+	VPOR      Y12, Y13, Y13
+	VPCMPEQB  Y9, Y11, Y12
+	VPOR      Y12, Y13, Y13
+	VPMOVMSKB Y13, BX
+	SHLQ      $32, BX
+	ORQ       BX, AX
+	MOVQ      AX, 24(DI)
+
+	ADDQ $64, SI
+	ADDQ $32, DI
+	DECQ CX
+	JMP  loop
+
+done:
+	VZEROUPPER
+	RET
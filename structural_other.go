@@ -0,0 +1,8 @@
+//go:build !amd64 || purego
+
+package astjson
+
+// classifyBlocks fills dst with the classes of the 64-byte blocks in s.
+func classifyBlocks(dst []blockClass, s string) {
+	classifyBlocksGeneric(dst, s)
+}
@@ -0,0 +1,225 @@
+package astjson
+
+import (
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wundergraph/go-arena"
+)
+
+// buildStructuralIndexSlow is a byte-by-byte reference implementation
+// of buildStructuralIndex.
+func buildStructuralIndexSlow(s string) ([]uint32, bool) {
+	var idx []uint32
+	inString := false
+	escaped := false
+	prevScalar := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		isQuote := c == '"' && !escaped
+		escaped = c == '\\' && !escaped
+		if inString {
+			if isQuote {
+				inString = false
+			}
+			prevScalar = !isQuote
+			continue
+		}
+		switch {
+		case isQuote:
+			if !prevScalar {
+				idx = append(idx, uint32(i))
+			}
+			inString = true
+			prevScalar = false
+		case charClasses[c] == charOp:
+			idx = append(idx, uint32(i))
+			prevScalar = false
+		case charClasses[c] == charWS:
+			prevScalar = false
+		default:
+			if !prevScalar {
+				idx = append(idx, uint32(i))
+			}
+			prevScalar = true
+		}
+	}
+	return idx, !inString
+}
+
+func TestClassifyBlocks(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	alphabet := []byte(" \t\n\r\"\\{}[]:,ax0\x00\xff")
+	b := make([]byte, 64*structuralBatchSize)
+	for n := 0; n < 100; n++ {
+		for i := range b {
+			b[i] = alphabet[r.Intn(len(alphabet))]
+		}
+		blocks := make([]blockClass, 1+r.Intn(structuralBatchSize))
+		expected := make([]blockClass, len(blocks))
+		classifyBlocks(blocks, b2s(b[:len(blocks)*64]))
+		classifyBlocksGeneric(expected, b2s(b[:len(blocks)*64]))
+		if !slices.Equal(blocks, expected) {
+			t.Fatalf("unexpected block classes for %q", b[:len(blocks)*64])
+		}
+	}
+}
+
+func TestBuildStructuralIndex(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		idx, ok := buildStructuralIndex(nil, s)
+		expectedIdx, expectedOK := buildStructuralIndexSlow(s)
+		if ok != expectedOK {
+			t.Fatalf("unexpected ok for %q; got %v; want %v", startEndString(s), ok, expectedOK)
+		}
+		if ok && !slices.Equal(idx, expectedIdx) {
+			t.Fatalf("unexpected index for %q;\ngot  %v\nwant %v", startEndString(s), idx, expectedIdx)
+		}
+	}
+
+	f(``)
+	f(`{}`)
+	f(`  [1, "a", true, null, {"b": -1.5e3}]  `)
+	f(`"unclosed`)
+	f(`"a\"b"`)
+	f(`"a\\"b"`)
+	f(`"a\\\"b"`)
+	f(`1"a,b"x`)
+	f(`"a""b"`)
+	f(`\"a`)
+	f(`{"foo` + strings.Repeat(`\\`, 40) + `":` + strings.Repeat(`\\`, 61) + `"}`)
+	f(strings.Repeat(`{"key": "value with \" and [brackets]", "n": 123},`, 20))
+
+	for _, s := range []string{smallFixture, mediumFixture, largeFixture, canadaFixture, citmFixture, twitterFixture} {
+		f(s)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	alphabet := []byte(" \"\\{}[]:,ax0")
+	for n := 0; n < 1000; n++ {
+		b := make([]byte, r.Intn(300))
+		for i := range b {
+			b[i] = alphabet[r.Intn(len(alphabet))]
+		}
+		f(string(b))
+	}
+}
+
+func TestParseIndexed(t *testing.T) {
+	var p Parser
+	a := arena.NewMonotonicArena()
+
+	f := func(s string) {
+		t.Helper()
+		v, err := p.ParseIndexed(a, s)
+		var pp Parser
+		expectedV, expectedErr := pp.Parse(s)
+		if expectedErr != nil {
+			if err == nil || err.Error() != expectedErr.Error() {
+				t.Fatalf("unexpected error for %q; got %v; want %q", startEndString(s), err, expectedErr)
+			}
+		} else {
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %s", startEndString(s), err)
+			}
+			if vs, expected := v.String(), expectedV.String(); vs != expected {
+				t.Fatalf("unexpected value for %q; got %s; want %s", startEndString(s), vs, expected)
+			}
+		}
+
+		err = ValidateIndexed(s)
+		expectedErr = Validate(s)
+		if expectedErr != nil {
+			if err == nil || err.Error() != expectedErr.Error() {
+				t.Fatalf("unexpected validation error for %q; got %v; want %q", startEndString(s), err, expectedErr)
+			}
+		} else if err != nil {
+			t.Fatalf("unexpected validation error for %q: %s", startEndString(s), err)
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		f(`{}`)
+		f(`[]`)
+		f(` [ 1 , "a" , true , false , null , { "b" : [ ] } ] `)
+		f(`"foo\"bar\\"`)
+		f(`-1.5e-3`)
+		f(`[NaN, nan, -Inf, inf]`)
+		f(`{"a\"b": "c\u0041", "d": {"e": [1, [2, [3]]]}}`)
+		f(strings.Repeat("[", MaxDepth) + strings.Repeat("]", MaxDepth))
+		for _, s := range []string{smallFixture, mediumFixture, largeFixture, canadaFixture, citmFixture, twitterFixture} {
+			f(s)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		f(``)
+		f(`   `)
+		f(`[`)
+		f(`{`)
+		f(`]`)
+		f(`[1,]`)
+		f(`[1 2]`)
+		f(`[1]]`)
+		f(`[1] x`)
+		f(`{"a"}`)
+		f(`{"a" 1}`)
+		f(`{"a": 1,}`)
+		f(`{"a": 1 "b": 2}`)
+		f(`{1: 2}`)
+		f(`{"a": }`)
+		f(`"unclosed`)
+		f(`"a\"`)
+		f(`1"a"`)
+		f(`"a""b"`)
+		f(`[tru]`)
+		f(`[truex]`)
+		f(`[nulll]`)
+		f(`[1x]`)
+		f(`[1.2.3]`)
+		f(`[01]`)
+		f(`-`)
+		f(`[-]`)
+		f(`[0,+]`)
+		f(`{"a":-}`)
+		f(`["\x"]`)
+		f("[\"\x01\"]")
+		f("{\"\x01\": 1}")
+		f(`["\u12"]`)
+		f(strings.Repeat("[", MaxDepth+1) + strings.Repeat("]", MaxDepth+1))
+	})
+
+	t.Run("mutations", func(t *testing.T) {
+		r := rand.New(rand.NewSource(1))
+		alphabet := []byte(" \"\\{}[]:,a0-+.etfn")
+		for n := 0; n < 2000; n++ {
+			b := []byte(mediumFixture)
+			for i := 0; i < 1+r.Intn(3); i++ {
+				b[r.Intn(len(b))] = alphabet[r.Intn(len(alphabet))]
+			}
+			f(string(b))
+		}
+	})
+}
+
+func FuzzParseIndexed(f *testing.F) {
+	for _, s := range []string{`{}`, `[1, "a", true, null]`, `{"a": [-1.5e3, {"b": nan}]}`, `[-]`, `[0,+]`, `{"a":-}`, mediumFixture} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		var p, pp Parser
+		v, err := p.ParseIndexed(nil, s)
+		expectedV, expectedErr := pp.Parse(s)
+		if (err != nil) != (expectedErr != nil) {
+			t.Fatalf("unexpected error for %q; got %v; want %v", s, err, expectedErr)
+		}
+		if err == nil {
+			if vs, expected := v.String(), expectedV.String(); vs != expected {
+				t.Fatalf("unexpected value for %q; got %s; want %s", s, vs, expected)
+			}
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package astjson
+
+import (
+	"testing"
+)
+
+func BenchmarkBuildStructuralIndex(b *testing.B) {
+	b.Run("canada", func(b *testing.B) {
+		benchmarkBuildStructuralIndex(b, canadaFixture)
+	})
+	b.Run("citm", func(b *testing.B) {
+		benchmarkBuildStructuralIndex(b, citmFixture)
+	})
+	b.Run("twitter", func(b *testing.B) {
+		benchmarkBuildStructuralIndex(b, twitterFixture)
+	})
+}
+
+func benchmarkBuildStructuralIndex(b *testing.B, s string) {
+	b.Run("classify", func(b *testing.B) {
+		blocks := make([]blockClass, len(s)/64)
+		b.SetBytes(int64(len(blocks) * 64))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			classifyBlocks(blocks, s)
+		}
+	})
+	b.Run("classify-generic", func(b *testing.B) {
+		blocks := make([]blockClass, len(s)/64)
+		b.SetBytes(int64(len(blocks) * 64))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			classifyBlocksGeneric(blocks, s)
+		}
+	})
+	b.Run("index", func(b *testing.B) {
+		var idx []uint32
+		b.SetBytes(int64(len(s)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			idx, _ = buildStructuralIndex(idx[:0], s)
+		}
+	})
+}
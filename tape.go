@@ -0,0 +1,704 @@
+package astjson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wundergraph/astjson/fastfloat"
+	"github.com/wundergraph/go-arena"
+)
+
+// Tape is a read-only parsed JSON stored in a flat representation.
+//
+// Unlike the tree of Values returned by Parser, the parsed JSON is stored
+// in a single []uint64 tape and a single buffer with unescaped strings,
+// so parsing doesn't allocate per value and reading walks contiguous memory.
+// Values are accessed via Cursor. Use Cursor.Value for obtaining a Value,
+// which may be modified.
+//
+// Tape may be re-used for subsequent parsing. Repeated parsing of similar
+// documents doesn't allocate.
+//
+// Tape cannot be used from concurrent goroutines.
+type Tape struct {
+	// tape contains a single entry per object, array, true, false and null
+	// and two entries per string, object key and number.
+	// See the tape* tags for the layout of entries.
+	tape []uint64
+
+	// buf contains the unescaped strings and object keys
+	// and the raw numbers referenced by tape.
+	buf []byte
+
+	l    limits
+	path errorPath
+}
+
+// Tape entries contain the tag in the upper 8 bits and the payload
+// in the lower 56 bits.
+//
+// The payload of objects and arrays contains the number of their members
+// or items in the upper 24 bits and the index of the entry following
+// their last member or item in the lower 32 bits. The number saturates
+// at tapeMaxCount. The payload of strings and numbers is their offset
+// in buf. The next entry contains their length.
+const (
+	tapeObject uint64 = iota + 1
+	tapeArray
+	tapeString
+	tapeNumber
+	tapeTrue
+	tapeFalse
+	tapeNull
+
+	tapeTagShift    = 56
+	tapePayloadMask = 1<<tapeTagShift - 1
+
+	tapeCountShift = 32
+	tapeEndMask    = 1<<tapeCountShift - 1
+	tapeMaxCount   = 1<<(tapeTagShift-tapeCountShift) - 1
+)
+
+// NewTape returns a Tape, which enforces the given opts.
+//
+// Only the limits, Strict and DuplicateKeysError are enforced.
+// Other duplicate keys are kept, and Cursor.Get returns the value
+// of the first member with the given key. The remaining opts are ignored.
+func NewTape(opts ParserOptions) *Tape {
+	return &Tape{
+		l: limits{
+			opts: opts,
+		},
+	}
+}
+
+// Parse parses s containing JSON into t and returns the top-level value.
+//
+// The returned Cursor is valid until the next Parse call on t.
+// It doesn't reference s.
+func (t *Tape) Parse(s string) (Cursor, error) {
+	if err := t.l.checkBytes(s); err != nil {
+		return Cursor{}, err
+	}
+	// The tape has at most len(s)+1 entries,
+	// so the indexes of entries fit the container entries.
+	if uint64(len(s)) >= tapeEndMask {
+		return Cursor{}, fmt.Errorf("cannot parse JSON longer than %d bytes into Tape", tapeEndMask-1)
+	}
+	t.tape = t.tape[:0]
+	t.buf = t.buf[:0]
+	t.l.reset()
+	t.path.reset()
+
+	tail, err := t.parseValue(skipWS(s), 0)
+	if err != nil {
+		return Cursor{}, newParseError(s, tail, err, t.path)
+	}
+	tail = skipWS(tail)
+	if len(tail) > 0 {
+		return Cursor{}, newParseError(s, tail, errUnexpectedTail, nil)
+	}
+	return Cursor{t: t}, nil
+}
+
+// ParseBytes parses b containing JSON into t and returns the top-level value.
+//
+// See Parse for details.
+func (t *Tape) ParseBytes(b []byte) (Cursor, error) {
+	return t.Parse(b2s(b))
+}
+
+func (t *Tape) parseValue(s string, depth int) (string, error) {
+	if len(s) == 0 {
+		return s, errEmptyString
+	}
+	depth++
+	if err := t.l.enter(depth); err != nil {
+		return s, err
+	}
+
+	switch s[0] {
+	case '"':
+		ss, tail, err := parseRawString(s[1:])
+		if err != nil {
+			return tail, fmt.Errorf("cannot parse string: %w", err)
+		}
+		if err := t.l.checkString(ss); err != nil {
+			return s, fmt.Errorf("cannot parse string: %w", err)
+		}
+		t.appendString(ss)
+		return tail, nil
+	case '{':
+		return t.parseObject(s[1:], depth)
+	case '[':
+		return t.parseArray(s[1:], depth)
+	case 't':
+		if len(s) < len("true") || s[:len("true")] != "true" {
+			return s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		t.tape = append(t.tape, tapeTrue<<tapeTagShift)
+		return s[len("true"):], nil
+	case 'f':
+		if len(s) < len("false") || s[:len("false")] != "false" {
+			return s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		t.tape = append(t.tape, tapeFalse<<tapeTagShift)
+		return s[len("false"):], nil
+	case 'n':
+		if len(s) < len("null") || s[:len("null")] != "null" {
+			if len(s) >= 3 && strings.EqualFold(s[:3], "nan") {
+				if err := t.l.checkNumber(s[:3]); err != nil {
+					return s, fmt.Errorf("cannot parse number: %w", err)
+				}
+				t.appendRaw(tapeNumber, s[:3])
+				return s[3:], nil
+			}
+			return s, fmt.Errorf("unexpected value found: %q", s)
+		}
+		t.tape = append(t.tape, tapeNull<<tapeTagShift)
+		return s[len("null"):], nil
+	default:
+		ns, tail, err := parseRawNumber(s)
+		if err != nil {
+			return tail, fmt.Errorf("cannot parse number: %w", err)
+		}
+		if err := t.l.checkNumber(ns); err != nil {
+			return s, fmt.Errorf("cannot parse number: %w", err)
+		}
+		t.appendRaw(tapeNumber, ns)
+		return tail, nil
+	}
+}
+
+func (t *Tape) parseArray(s string, depth int) (string, error) {
+	s = skipWS(s)
+	if len(s) == 0 {
+		return s, errMissingArrayEnd
+	}
+	start := t.beginContainer(tapeArray)
+	if s[0] == ']' {
+		t.endContainer(start, 0)
+		return s[1:], nil
+	}
+
+	for n := 0; ; n++ {
+		var err error
+		s = skipWS(s)
+		if err = t.l.checkItems(n + 1); err != nil {
+			return s, err
+		}
+		s, err = t.parseValue(s, depth)
+		if err != nil {
+			t.path.pushIndex(n)
+			return s, err
+		}
+
+		s = skipWS(s)
+		if len(s) == 0 {
+			return s, errArrayEnd
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] == ']' {
+			t.endContainer(start, n+1)
+			return s[1:], nil
+		}
+		return s, errMissingArrayComma
+	}
+}
+
+func (t *Tape) parseObject(s string, depth int) (string, error) {
+	s = skipWS(s)
+	if len(s) == 0 {
+		return s, errMissingObjectEnd
+	}
+	start := t.beginContainer(tapeObject)
+	if s[0] == '}' {
+		t.endContainer(start, 0)
+		return s[1:], nil
+	}
+
+	keysStart := len(t.l.dk.keys)
+	for n := 0; ; n++ {
+		var err error
+		if err = t.l.checkMembers(n + 1); err != nil {
+			return s, err
+		}
+
+		// Parse key.
+		s = skipWS(s)
+		if len(s) == 0 || s[0] != '"' {
+			return s, errMissingKeyQuote
+		}
+		ks := s
+		var k string
+		k, s, err = parseRawKey(s[1:])
+		if err != nil {
+			return s, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		if err = t.l.checkString(k); err != nil {
+			return ks, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		t.l.pushKey(k)
+		t.appendString(k)
+		s = skipWS(s)
+		if len(s) == 0 || s[0] != ':' {
+			return s, errMissingColon
+		}
+		s = s[1:]
+
+		// Parse value
+		s = skipWS(s)
+		s, err = t.parseValue(s, depth)
+		if err != nil {
+			t.path.pushRawKey(k)
+			return s, err
+		}
+		s = skipWS(s)
+		if len(s) == 0 {
+			return s, errObjectEnd
+		}
+		if s[0] == ',' {
+			s = s[1:]
+			continue
+		}
+		if s[0] == '}' {
+			if err = t.l.checkKeys(keysStart); err != nil {
+				return s, err
+			}
+			t.endContainer(start, n+1)
+			return s[1:], nil
+		}
+		return s, errMissingObjectComma
+	}
+}
+
+// beginContainer appends the entry of an object or array with the given tag
+// and returns its index. The entry is completed by endContainer.
+func (t *Tape) beginContainer(tag uint64) int {
+	t.tape = append(t.tape, tag<<tapeTagShift)
+	return len(t.tape) - 1
+}
+
+// endContainer stores the number n of members or items and the index
+// of the entry following the container started at the start index.
+func (t *Tape) endContainer(start, n int) {
+	t.tape[start] |= uint64(min(n, tapeMaxCount))<<tapeCountShift | uint64(len(t.tape))
+}
+
+// appendString appends the entries of the raw string s after unescaping it.
+func (t *Tape) appendString(s string) {
+	n := strings.IndexByte(s, '\\')
+	if n < 0 {
+		t.appendRaw(tapeString, s)
+		return
+	}
+	start := len(t.buf)
+	t.buf = appendUnescapedString(nil, t.buf, s, n)
+	t.tape = append(t.tape, tapeString<<tapeTagShift|uint64(start), uint64(len(t.buf)-start))
+}
+
+// appendRaw appends the entries of s with the given tag.
+func (t *Tape) appendRaw(tag uint64, s string) {
+	t.tape = append(t.tape, tag<<tapeTagShift|uint64(len(t.buf)), uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+// Cursor points to a value in Tape.
+//
+// The zero Cursor means a missing value. Cursor methods may be called on it.
+//
+// Cursor is valid until the next Parse call on the Tape returned it.
+type Cursor struct {
+	t *Tape
+	i int
+}
+
+// tag returns the tag of the entry pointed by c.
+func (c Cursor) tag() uint64 {
+	return c.t.tape[c.i] >> tapeTagShift
+}
+
+// payload returns the payload of the entry pointed by c.
+func (c Cursor) payload() int {
+	return int(c.t.tape[c.i] & tapePayloadMask)
+}
+
+// end returns the index of the entry following the object or array pointed by c.
+func (c Cursor) end() int {
+	return c.payload() & tapeEndMask
+}
+
+// next returns the index of the entry following the value pointed by c.
+func (c Cursor) next() int {
+	switch c.tag() {
+	case tapeObject, tapeArray:
+		return c.end()
+	case tapeString, tapeNumber:
+		return c.i + 2
+	default:
+		return c.i + 1
+	}
+}
+
+// bytes returns the contents of the string or number pointed by c.
+func (c Cursor) bytes() []byte {
+	start := c.payload()
+	n := int(c.t.tape[c.i+1])
+	return c.t.buf[start : start+n : start+n]
+}
+
+// Exists returns true if the field exists for the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+func (c Cursor) Exists(keys ...string) bool {
+	return c.Get(keys...).t != nil
+}
+
+// Type returns the type of the value pointed by c.
+//
+// TypeNull is returned for the zero Cursor.
+func (c Cursor) Type() Type {
+	if c.t == nil {
+		return TypeNull
+	}
+	switch c.tag() {
+	case tapeObject:
+		return TypeObject
+	case tapeArray:
+		return TypeArray
+	case tapeString:
+		return TypeString
+	case tapeNumber:
+		return TypeNumber
+	case tapeTrue:
+		return TypeTrue
+	case tapeFalse:
+		return TypeFalse
+	default:
+		return TypeNull
+	}
+}
+
+// Len returns the number of members of the object or items of the array
+// pointed by c.
+//
+// It takes O(1) time unless the length exceeds 16M.
+// 0 is returned for other values.
+func (c Cursor) Len() int {
+	if c.t == nil {
+		return 0
+	}
+	tag := c.tag()
+	if tag != tapeObject && tag != tapeArray {
+		return 0
+	}
+	if n := c.payload() >> tapeCountShift; n < tapeMaxCount {
+		return n
+	}
+	// The number has been saturated.
+	n := 0
+	end := c.end()
+	for i := c.i + 1; i < end; i = (Cursor{t: c.t, i: i}).next() {
+		n++
+	}
+	if tag == tapeObject {
+		// Keys and values are counted separately.
+		n /= 2
+	}
+	return n
+}
+
+// Get returns the value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+// Nested objects and arrays are skipped in O(1), but finding the n-th item
+// takes O(n) steps, so use VisitItems for iterating over arrays.
+//
+// The zero Cursor is returned for non-existing keys path.
+func (c Cursor) Get(keys ...string) Cursor {
+	if c.t == nil {
+		return c
+	}
+	for _, key := range keys {
+		switch c.tag() {
+		case tapeObject:
+			c = c.member(key)
+		case tapeArray:
+			n, err := strconv.Atoi(key)
+			if err != nil || n < 0 {
+				return Cursor{}
+			}
+			c = c.item(n)
+		default:
+			return Cursor{}
+		}
+		if c.t == nil {
+			return c
+		}
+	}
+	return c
+}
+
+// member returns the value of the first member with the given key
+// in the object pointed by c.
+func (c Cursor) member(key string) Cursor {
+	end := c.end()
+	for i := c.i + 1; i < end; {
+		k := Cursor{t: c.t, i: i}
+		v := Cursor{t: c.t, i: i + 2}
+		if b2s(k.bytes()) == key {
+			return v
+		}
+		i = v.next()
+	}
+	return Cursor{}
+}
+
+// item returns the n-th item of the array pointed by c.
+func (c Cursor) item(n int) Cursor {
+	end := c.end()
+	for i := c.i + 1; i < end; n-- {
+		v := Cursor{t: c.t, i: i}
+		if n == 0 {
+			return v
+		}
+		i = v.next()
+	}
+	return Cursor{}
+}
+
+// Visit calls f for each member of the object pointed by c
+// in the original order of the parsed JSON.
+//
+// f isn't called if c doesn't point to an object.
+// f cannot hold key after returning.
+func (c Cursor) Visit(f func(key []byte, v Cursor)) {
+	if c.t == nil || c.tag() != tapeObject {
+		return
+	}
+	end := c.end()
+	for i := c.i + 1; i < end; {
+		k := Cursor{t: c.t, i: i}
+		v := Cursor{t: c.t, i: i + 2}
+		f(k.bytes(), v)
+		i = v.next()
+	}
+}
+
+// VisitItems calls f for each item of the array pointed by c.
+//
+// f isn't called if c doesn't point to an array.
+func (c Cursor) VisitItems(f func(i int, v Cursor)) {
+	if c.t == nil || c.tag() != tapeArray {
+		return
+	}
+	end := c.end()
+	n := 0
+	for i := c.i + 1; i < end; n++ {
+		v := Cursor{t: c.t, i: i}
+		f(n, v)
+		i = v.next()
+	}
+}
+
+// GetFloat64 returns float64 value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// 0 is returned for non-existing keys path or for invalid value type.
+func (c Cursor) GetFloat64(keys ...string) float64 {
+	c = c.Get(keys...)
+	if c.Type() != TypeNumber {
+		return 0
+	}
+	return fastfloat.ParseBestEffort(b2s(c.bytes()))
+}
+
+// GetInt returns int value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// 0 is returned for non-existing keys path or for invalid value type.
+func (c Cursor) GetInt(keys ...string) int {
+	n := c.GetInt64(keys...)
+	nn := int(n)
+	if int64(nn) != n {
+		return 0
+	}
+	return nn
+}
+
+// GetUint returns uint value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// 0 is returned for non-existing keys path or for invalid value type.
+func (c Cursor) GetUint(keys ...string) uint {
+	n := c.GetUint64(keys...)
+	nn := uint(n)
+	if uint64(nn) != n {
+		return 0
+	}
+	return nn
+}
+
+// GetInt64 returns int64 value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// 0 is returned for non-existing keys path or for invalid value type.
+func (c Cursor) GetInt64(keys ...string) int64 {
+	c = c.Get(keys...)
+	if c.Type() != TypeNumber {
+		return 0
+	}
+	return fastfloat.ParseInt64BestEffort(b2s(c.bytes()))
+}
+
+// GetUint64 returns uint64 value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// 0 is returned for non-existing keys path or for invalid value type.
+func (c Cursor) GetUint64(keys ...string) uint64 {
+	c = c.Get(keys...)
+	if c.Type() != TypeNumber {
+		return 0
+	}
+	return fastfloat.ParseUint64BestEffort(b2s(c.bytes()))
+}
+
+// GetStringBytes returns string value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// nil is returned for non-existing keys path or for invalid value type.
+//
+// The returned string is valid until Parse is called on the Tape returned c.
+func (c Cursor) GetStringBytes(keys ...string) []byte {
+	c = c.Get(keys...)
+	if c.Type() != TypeString {
+		return nil
+	}
+	return c.bytes()
+}
+
+// GetBool returns bool value by the given keys path.
+//
+// Array indexes may be represented as decimal numbers in keys.
+//
+// false is returned for non-existing keys path or for invalid value type.
+func (c Cursor) GetBool(keys ...string) bool {
+	return c.Get(keys...).Type() == TypeTrue
+}
+
+// MarshalTo appends marshaled value pointed by c to dst and returns the result.
+//
+// Nothing is appended for the zero Cursor.
+func (c Cursor) MarshalTo(dst []byte) []byte {
+	if c.t == nil {
+		return dst
+	}
+	switch c.tag() {
+	case tapeObject:
+		dst = append(dst, '{')
+		end := c.end()
+		for i := c.i + 1; i < end; {
+			if i > c.i+1 {
+				dst = append(dst, ',')
+			}
+			k := Cursor{t: c.t, i: i}
+			v := Cursor{t: c.t, i: i + 2}
+			dst = escapeString(dst, b2s(k.bytes()))
+			dst = append(dst, ':')
+			dst = v.MarshalTo(dst)
+			i = v.next()
+		}
+		return append(dst, '}')
+	case tapeArray:
+		dst = append(dst, '[')
+		end := c.end()
+		for i := c.i + 1; i < end; {
+			if i > c.i+1 {
+				dst = append(dst, ',')
+			}
+			v := Cursor{t: c.t, i: i}
+			dst = v.MarshalTo(dst)
+			i = v.next()
+		}
+		return append(dst, ']')
+	case tapeString:
+		return escapeString(dst, b2s(c.bytes()))
+	case tapeNumber:
+		return append(dst, c.bytes()...)
+	case tapeTrue:
+		return append(dst, "true"...)
+	case tapeFalse:
+		return append(dst, "false"...)
+	case tapeNull:
+		return append(dst, "null"...)
+	default:
+		panic(fmt.Errorf("BUG: unexpected tape tag: %d", c.tag()))
+	}
+}
+
+// String returns string representation of the value pointed by c.
+//
+// The function is for debugging purposes only. It isn't optimized for speed.
+// See MarshalTo instead.
+func (c Cursor) String() string {
+	b := c.MarshalTo(nil)
+	// It is safe converting b to string without allocation, since b is no longer
+	// reachable after this line.
+	return b2s(b)
+}
+
+// Value returns the value pointed by c as a Value allocated from a,
+// which may be modified.
+//
+// nil is returned for the zero Cursor. The strings of the returned Value
+// are copied from the Tape, so it stays valid after the next Parse call
+// on the Tape returned c.
+func (c Cursor) Value(a arena.Arena) *Value {
+	if c.t == nil {
+		return nil
+	}
+	switch c.tag() {
+	case tapeObject:
+		v := ObjectValue(a)
+		end := c.end()
+		for i := c.i + 1; i < end; {
+			k := Cursor{t: c.t, i: i}
+			vv := Cursor{t: c.t, i: i + 2}
+			kv := v.x.o.getKV(a)
+			kv.k = copyString(a, b2s(k.bytes()))
+			kv.keyUnescaped = true
+			kv.v = vv.Value(a)
+			i = vv.next()
+		}
+		return v
+	case tapeArray:
+		v := ArrayValue(a)
+		end := c.end()
+		for i := c.i + 1; i < end; {
+			vv := Cursor{t: c.t, i: i}
+			v.x.a = arena.SliceAppend(a, v.x.a, vv.Value(a))
+			i = vv.next()
+		}
+		return v
+	case tapeString:
+		return StringValue(a, copyString(a, b2s(c.bytes())))
+	case tapeNumber:
+		return NumberValue(a, copyString(a, b2s(c.bytes())))
+	case tapeTrue:
+		return valueTrue
+	case tapeFalse:
+		return valueFalse
+	default:
+		return valueNull
+	}
+}
@@ -0,0 +1,315 @@
+package astjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wundergraph/go-arena"
+)
+
+func TestTape(t *testing.T) {
+	var tp Tape
+
+	t.Run("marshal", func(t *testing.T) {
+		f := func(s, expected string) {
+			t.Helper()
+			c, err := tp.Parse(s)
+			if err != nil {
+				t.Fatalf("unexpected error when parsing %q: %s", s, err)
+			}
+			if cs := c.String(); cs != expected {
+				t.Fatalf("unexpected value for %q; got %s; want %s", s, cs, expected)
+			}
+		}
+
+		f(`123`, `123`)
+		f(` -12.5e+3 `, `-12.5e+3`)
+		f(`NaN`, `NaN`)
+		f(`"foo\"bar\\bazA😀A"`, `"foo\"bar\\bazA😀A"`)
+		f(`true`, `true`)
+		f(`false`, `false`)
+		f(`null`, `null`)
+		f(`[]`, `[]`)
+		f(`{}`, `{}`)
+		f(`[[], {}, [[]], {"a": {}}]`, `[[],{},[[]],{"a":{}}]`)
+		f(`{"a\"b": {"c": [1, 2.5, "x"]}, "d": {}, "e": [], "f": null}`, `{"a\"b":{"c":[1,2.5,"x"]},"d":{},"e":[],"f":null}`)
+	})
+
+	t.Run("fixtures", func(t *testing.T) {
+		for _, s := range []string{smallFixture, mediumFixture, largeFixture, canadaFixture, citmFixture, twitterFixture} {
+			c, err := tp.Parse(s)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			v := MustParse(s)
+			if cs, vs := c.String(), v.String(); cs != vs {
+				t.Fatalf("unexpected value; got %s; want %s", startEndString(cs), startEndString(vs))
+			}
+			if cs, vs := c.Value(nil).String(), v.String(); cs != vs {
+				t.Fatalf("unexpected converted value; got %s; want %s", startEndString(cs), startEndString(vs))
+			}
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		c, err := tp.Parse(`{"foo": [123, "bar", {"baz": true, "x": 1.5}], "a\nb": "cA", "n": null, "big": 12345678901234567890, "neg": -5, "dup": 1, "dup": 2}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if n := c.GetInt("foo", "0"); n != 123 {
+			t.Fatalf("unexpected GetInt; got %d; want %d", n, 123)
+		}
+		if s := c.GetStringBytes("foo", "1"); string(s) != "bar" {
+			t.Fatalf("unexpected GetStringBytes; got %q; want %q", s, "bar")
+		}
+		if !c.GetBool("foo", "2", "baz") {
+			t.Fatalf("unexpected GetBool; got false; want true")
+		}
+		if f := c.GetFloat64("foo", "2", "x"); f != 1.5 {
+			t.Fatalf("unexpected GetFloat64; got %v; want %v", f, 1.5)
+		}
+		if s := c.GetStringBytes("a\nb"); string(s) != "cA" {
+			t.Fatalf("unexpected GetStringBytes for escaped key; got %q; want %q", s, "cA")
+		}
+		if n := c.GetUint64("big"); n != 12345678901234567890 {
+			t.Fatalf("unexpected GetUint64; got %d; want %d", n, uint64(12345678901234567890))
+		}
+		if n := c.GetInt64("neg"); n != -5 {
+			t.Fatalf("unexpected GetInt64; got %d; want %d", n, -5)
+		}
+		if n := c.GetUint("neg"); n != 0 {
+			t.Fatalf("unexpected GetUint for negative number; got %d; want 0", n)
+		}
+		if n := c.GetInt("dup"); n != 1 {
+			t.Fatalf("unexpected GetInt for duplicate key; got %d; want %d", n, 1)
+		}
+		if tp := c.Get("n").Type(); tp != TypeNull {
+			t.Fatalf("unexpected type; got %s; want %s", tp, TypeNull)
+		}
+		if !c.Exists("n") || !c.Exists("foo", "2", "baz") || !c.Exists() {
+			t.Fatalf("existing fields must exist")
+		}
+		for _, keys := range [][]string{{"missing"}, {"foo", "3"}, {"foo", "-1"}, {"foo", "x"}, {"foo", "0", "x"}, {"n", "x"}} {
+			if c.Exists(keys...) {
+				t.Fatalf("unexpected field found for %q", keys)
+			}
+			if vv := c.Get(keys...); vv.Type() != TypeNull || vv.String() != "" || vv.Len() != 0 || vv.Value(nil) != nil {
+				t.Fatalf("unexpected missing value for %q", keys)
+			}
+		}
+		if n := c.Len(); n != 7 {
+			t.Fatalf("unexpected object length; got %d; want %d", n, 7)
+		}
+		if n := c.Get("foo").Len(); n != 3 {
+			t.Fatalf("unexpected array length; got %d; want %d", n, 3)
+		}
+		if n := c.Get("foo", "0").Len(); n != 0 {
+			t.Fatalf("unexpected number length; got %d; want 0", n)
+		}
+		if n := c.GetInt("foo"); n != 0 {
+			t.Fatalf("unexpected GetInt for array; got %d; want 0", n)
+		}
+		if s := c.GetStringBytes("foo", "0"); s != nil {
+			t.Fatalf("unexpected GetStringBytes for number; got %q; want nil", s)
+		}
+	})
+
+	t.Run("visit", func(t *testing.T) {
+		c, err := tp.Parse(`{"a": [1, [2, 3], {"b": 4}], "c": {}, "d": "e"}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var members []string
+		c.Visit(func(key []byte, v Cursor) {
+			members = append(members, string(key)+"="+v.String())
+		})
+		if s, expected := strings.Join(members, " "), `a=[1,[2,3],{"b":4}] c={} d="e"`; s != expected {
+			t.Fatalf("unexpected members; got %s; want %s", s, expected)
+		}
+		var items []string
+		c.Get("a").VisitItems(func(i int, v Cursor) {
+			if v.String() != c.Get("a").Get(string(rune('0'+i))).String() {
+				t.Fatalf("unexpected item %d: %s", i, v)
+			}
+			items = append(items, v.String())
+		})
+		if s, expected := strings.Join(items, " "), `1 [2,3] {"b":4}`; s != expected {
+			t.Fatalf("unexpected items; got %s; want %s", s, expected)
+		}
+		c.Get("a").Visit(func(key []byte, v Cursor) {
+			t.Fatalf("unexpected Visit call for array")
+		})
+		c.VisitItems(func(i int, v Cursor) {
+			t.Fatalf("unexpected VisitItems call for object")
+		})
+	})
+
+	t.Run("len", func(t *testing.T) {
+		f := func(s string, expected int) {
+			t.Helper()
+			c, err := tp.Parse(s)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if n := c.Len(); n != expected {
+				t.Fatalf("unexpected length for %q; got %d; want %d", startEndString(s), n, expected)
+			}
+		}
+
+		f(`[]`, 0)
+		f(`{}`, 0)
+		f(`[[1, 2], {"a": [3]}, "x"]`, 3)
+		f(`{"a": {"b": 1, "c": 2}, "d": [], "e": null}`, 3)
+		f(`[`+strings.Repeat(`0,`, 1000)+`0]`, 1001)
+		if testing.Short() {
+			t.Skip("skipping the test for saturated lengths in short mode")
+		}
+		// The lengths above tapeMaxCount are counted by walking the tape.
+		f(`[`+strings.Repeat(`0,`, tapeMaxCount)+`0]`, tapeMaxCount+1)
+	})
+
+	t.Run("value", func(t *testing.T) {
+		c, err := tp.Parse(`{"a\"b": [1, "x", true, false, null, {}], "c": {"d": 2}}`)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		a := arena.NewMonotonicArena()
+		v := c.Value(a)
+		if _, err := tp.Parse(`{"a\"b": [3, "y"]}`); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// The converted value must be independent of the Tape.
+		if s, expected := v.String(), `{"a\"b":[1,"x",true,false,null,{}],"c":{"d":2}}`; s != expected {
+			t.Fatalf("unexpected value; got %s; want %s", s, expected)
+		}
+		if n := v.GetInt("c", "d"); n != 2 {
+			t.Fatalf("unexpected GetInt; got %d; want %d", n, 2)
+		}
+		v.Get("c").Set(a, "e", StringValue(a, "f"))
+		v.Del(`a"b`)
+		if s, expected := v.String(), `{"c":{"d":2,"e":"f"}}`; s != expected {
+			t.Fatalf("unexpected modified value; got %s; want %s", s, expected)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		f := func(s string) {
+			t.Helper()
+			_, err := tp.Parse(s)
+			if err == nil {
+				t.Fatalf("expecting non-nil error when parsing %q", s)
+			}
+			_, expectedErr := (&Parser{}).Parse(s)
+			if expectedErr == nil {
+				t.Fatalf("expecting non-nil error from Parser when parsing %q", s)
+			}
+			if err.Error() != expectedErr.Error() {
+				t.Fatalf("unexpected error when parsing %q; got %s; want %s", s, err, expectedErr)
+			}
+		}
+
+		f(``)
+		f(` `)
+		f(`[`)
+		f(`[1,`)
+		f(`[1 2]`)
+		f(`{`)
+		f(`{"a"`)
+		f(`{"a":`)
+		f(`{"a": 1`)
+		f(`{"a": 1 "b": 2}`)
+		f(`{a: 1}`)
+		f(`{"a": [1, {"b": tru}]}`)
+		f(`"foo`)
+		f(`-x`)
+		f(`nul`)
+		f(`1 2`)
+		f(strings.Repeat(`[`, MaxDepth+1) + strings.Repeat(`]`, MaxDepth+1))
+	})
+
+	t.Run("options", func(t *testing.T) {
+		f := func(opts ParserOptions, s string, expectedErr error) {
+			t.Helper()
+			_, err := NewTape(opts).Parse(s)
+			if !errors.Is(err, expectedErr) {
+				t.Fatalf("unexpected error when parsing %q; got %v; want %v", s, err, expectedErr)
+			}
+		}
+
+		f(ParserOptions{MaxDepth: 2}, `[[[]]]`, ErrMaxDepth)
+		f(ParserOptions{MaxBytes: 2}, `[1]`, ErrMaxBytes)
+		f(ParserOptions{MaxNodes: 2}, `[1, 2]`, ErrMaxNodes)
+		f(ParserOptions{MaxObjectMembers: 1}, `{"a": 1, "b": 2}`, ErrMaxObjectMembers)
+		f(ParserOptions{MaxArrayLength: 1}, `[1, 2]`, ErrMaxArrayLength)
+		f(ParserOptions{MaxStringLength: 2}, `["abc"]`, ErrMaxStringLength)
+		f(ParserOptions{MaxNumberLength: 2}, `[123]`, ErrMaxNumberLength)
+		f(ParserOptions{DuplicateKeys: DuplicateKeysError}, `{"a": {"b": 1, "b": 2}}`, ErrDuplicateKey)
+		f(ParserOptions{DuplicateKeys: DuplicateKeysError}, `{"a": {"b": 1}, "b": 2}`, nil)
+
+		_, err := NewTape(ParserOptions{Strict: true}).Parse(`["\x"]`)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for invalid escape sequence in Strict mode")
+		}
+	})
+
+	t.Run("path", func(t *testing.T) {
+		_, err := tp.Parse(`{"a": [1, {"b": x}]}`)
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("expecting ParseError; got %v", err)
+		}
+		if s, expected := pe.PathString(), "$.a[1].b"; s != expected {
+			t.Fatalf("unexpected path; got %s; want %s", s, expected)
+		}
+	})
+
+	t.Run("no allocs", func(t *testing.T) {
+		if _, err := tp.Parse(twitterFixture); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		n := testing.AllocsPerRun(10, func() {
+			c, err := tp.Parse(twitterFixture)
+			if err != nil {
+				panic(err)
+			}
+			_ = c.GetStringBytes("search_metadata", "max_id_str")
+		})
+		if n > 0 {
+			t.Fatalf("unexpected allocations; got %v; want 0", n)
+		}
+	})
+}
+
+func BenchmarkTapeParseAndGet(b *testing.B) {
+	fileData := getFromFile("testdata/twitter.json")
+	b.Run("value", func(b *testing.B) {
+		var p Parser
+		b.SetBytes(int64(len(fileData)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			v, err := p.Parse(fileData)
+			if err != nil {
+				b.Fatalf("cannot parse json: %s", err)
+			}
+			_ = v.GetStringBytes("search_metadata", "max_id_str")
+			_ = v.GetInt("search_metadata", "count")
+			_ = v.GetStringBytes("statuses", "99", "text")
+		}
+	})
+	b.Run("tape", func(b *testing.B) {
+		var tp Tape
+		b.SetBytes(int64(len(fileData)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c, err := tp.Parse(fileData)
+			if err != nil {
+				b.Fatalf("cannot parse json: %s", err)
+			}
+			_ = c.GetStringBytes("search_metadata", "max_id_str")
+			_ = c.GetInt("search_metadata", "count")
+			_ = c.GetStringBytes("statuses", "99", "text")
+		}
+	})
+}
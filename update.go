@@ -8,10 +8,20 @@ import (
 )
 
 // Del deletes the entry with the given key from o.
+//
+// Del takes O(n) time for o with n members even if o is indexed,
+// since the following members are shifted in order to preserve their order.
+// Build a new object instead of deleting many members from a large object.
 func (o *Object) Del(key string) {
 	if o == nil {
 		return
 	}
+	if o.ix != nil {
+		if i := o.lookup(key); i >= 0 {
+			o.delIndex(i)
+		}
+		return
+	}
 	if strings.IndexByte(key, '\\') < 0 {
 		// Fast path - try searching for the key without unescaping
 		for i, kv := range o.kvs {
@@ -39,16 +49,19 @@ func (v *Value) Del(key string) {
 	if v == nil {
 		return
 	}
+	if v.lazy() && v.materialize() != nil {
+		return
+	}
 	if v.t == TypeObject {
-		v.o.Del(key)
+		v.x.o.Del(key)
 		return
 	}
 	if v.t == TypeArray {
 		n, err := strconv.Atoi(key)
-		if err != nil || n < 0 || n >= len(v.a) {
+		if err != nil || n < 0 || n >= len(v.x.a) {
 			return
 		}
-		v.a = append(v.a[:n], v.a[n+1:]...)
+		v.x.a = append(v.x.a[:n], v.x.a[n+1:]...)
 	}
 }
 
@@ -63,15 +76,23 @@ func (o *Object) Set(a arena.Arena, key string, value *Value) {
 		value = valueNull
 	}
 
-	// Try substituting already existing entry with the given key.
-	for i := range o.kvs {
-		if !o.kvs[i].keyUnescaped {
-			o.unescapeKey(a, o.kvs[i])
-		}
-		if o.kvs[i].k == key {
+	if o.ix != nil {
+		// Fast path - look up the key via the hash index.
+		if i := o.lookup(key); i >= 0 {
 			o.kvs[i].v = value
 			return
 		}
+	} else {
+		// Try substituting already existing entry with the given key.
+		for i := range o.kvs {
+			if !o.kvs[i].keyUnescaped {
+				o.unescapeKey(a, o.kvs[i])
+			}
+			if o.kvs[i].k == key {
+				o.kvs[i].v = value
+				return
+			}
+		}
 	}
 
 	// Add new entry.
@@ -79,6 +100,7 @@ func (o *Object) Set(a arena.Arena, key string, value *Value) {
 	kv.k = key
 	kv.v = value
 	kv.keyUnescaped = true // New keys are already unescaped since they come from user input
+	o.addIndex(a)
 }
 
 // Set sets (key, value) entry in the array or object v.
@@ -88,8 +110,11 @@ func (v *Value) Set(a arena.Arena, key string, value *Value) {
 	if v == nil {
 		return
 	}
+	if v.lazy() && v.materialize() != nil {
+		return
+	}
 	if v.t == TypeObject {
-		v.o.Set(a, key, value)
+		v.x.o.Set(a, key, value)
 		return
 	}
 	if v.t == TypeArray {
@@ -108,8 +133,11 @@ func (v *Value) SetArrayItem(a arena.Arena, idx int, value *Value) {
 	if v == nil || v.t != TypeArray {
 		return
 	}
-	for idx >= len(v.a) {
-		v.a = arena.SliceAppend(a, v.a, valueNull)
+	if v.lazy() && v.materialize() != nil {
+		return
+	}
+	for idx >= len(v.x.a) {
+		v.x.a = arena.SliceAppend(a, v.x.a, valueNull)
 	}
-	v.a[idx] = value
+	v.x.a[idx] = value
 }
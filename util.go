@@ -66,13 +66,23 @@ func (v *Value) AppendArrayItems(right *Value) {
 	if v.t != TypeArray || right.t != TypeArray {
 		return
 	}
-	v.a = append(v.a, right.a...)
+	if v.lazy() && v.materialize() != nil {
+		return
+	}
+	if right.lazy() && right.materialize() != nil {
+		return
+	}
+	v.x.a = append(v.x.a, right.x.a...)
 }
 
 func ValueIsNull(v *Value) bool {
 	return !ValueIsNonNull(v)
 }
 
+// DeduplicateObjectKeysRecursively removes all but the first members
+// with duplicate keys from the objects in v.
+//
+// Use ParserOptions.DuplicateKeys for handling duplicate keys at parse time.
 func DeduplicateObjectKeysRecursively(v *Value) {
 	if v.Type() == TypeArray {
 		a := v.GetArray()
@@ -83,7 +93,10 @@ func DeduplicateObjectKeysRecursively(v *Value) {
 	if v.Type() != TypeObject {
 		return
 	}
-	o, _ := v.Object()
+	o, err := v.Object()
+	if err != nil {
+		return
+	}
 	seen := make(map[string]struct{})
 	o.Visit(func(k []byte, v *Value) {
 		key := string(k)
@@ -7,53 +7,79 @@ import (
 )
 
 // Validate validates JSON s.
+//
+// Validate doesn't limit the input. Use ValidateWithOptions for untrusted input.
 func Validate(s string) error {
-	s = skipWS(s)
+	v := validator{
+		l: limits{
+			opts: noLimits,
+		},
+	}
+	return v.validate(s)
+}
+
+// ValidateBytes validates JSON b.
+func ValidateBytes(b []byte) error {
+	return Validate(b2s(b))
+}
+
+// validator validates JSON with the given limits.
+type validator struct {
+	l limits
 
-	tail, err := validateValue(s)
+	// path collects the path to the failure point.
+	path errorPath
+}
+
+func (v *validator) validate(s string) error {
+	if err := v.l.checkBytes(s); err != nil {
+		return err
+	}
+	if v.l.opts.JSON5 {
+		return v.validateJSON5(s)
+	}
+	tail, err := v.validateValue(skipWS(s), 0)
 	if err != nil {
-		return fmt.Errorf("cannot parse JSON: %s; unparsed tail: %q", err, startEndString(tail))
+		return newParseError(s, tail, err, v.path)
 	}
 	tail = skipWS(tail)
 	if len(tail) > 0 {
-		return fmt.Errorf("unexpected tail: %q", startEndString(tail))
+		return newParseError(s, tail, errUnexpectedTail, nil)
 	}
 	return nil
 }
 
-// ValidateBytes validates JSON b.
-func ValidateBytes(b []byte) error {
-	return Validate(b2s(b))
-}
-
-func validateValue(s string) (string, error) {
+func (v *validator) validateValue(s string, depth int) (string, error) {
 	if len(s) == 0 {
-		return s, fmt.Errorf("cannot parse empty string")
+		return s, errEmptyString
+	}
+	depth++
+	if err := v.l.enter(depth); err != nil {
+		return s, err
 	}
 
 	if s[0] == '{' {
-		tail, err := validateObject(s[1:])
-		if err != nil {
-			return tail, fmt.Errorf("cannot parse object: %s", err)
-		}
-		return tail, nil
+		return v.validateObject(s[1:], depth)
 	}
 	if s[0] == '[' {
-		tail, err := validateArray(s[1:])
-		if err != nil {
-			return tail, fmt.Errorf("cannot parse array: %s", err)
-		}
-		return tail, nil
+		return v.validateArray(s[1:], depth)
 	}
 	if s[0] == '"' {
 		sv, tail, err := validateString(s[1:])
 		if err != nil {
-			return tail, fmt.Errorf("cannot parse string: %s", err)
+			if len(tail) > 0 {
+				// Point to the start of the string with invalid escape sequences.
+				tail = s
+			}
+			return tail, fmt.Errorf("cannot parse string: %w", err)
+		}
+		if err := v.l.checkString(s[1 : len(s)-len(tail)-1]); err != nil {
+			return s, fmt.Errorf("cannot parse string: %w", err)
 		}
 		// Scan the string for control chars.
 		for i := 0; i < len(sv); i++ {
 			if sv[i] < 0x20 {
-				return tail, fmt.Errorf("string cannot contain control char 0x%02X", sv[i])
+				return tail, errorf(ErrorKindBadString, "string cannot contain control char 0x%02X", sv[i])
 			}
 		}
 		return tail, nil
@@ -79,32 +105,39 @@ func validateValue(s string) (string, error) {
 
 	tail, err := validateNumber(s)
 	if err != nil {
-		return tail, fmt.Errorf("cannot parse number: %s", err)
+		return tail, fmt.Errorf("cannot parse number: %w", err)
+	}
+	if err := v.l.checkNumber(s[:len(s)-len(tail)]); err != nil {
+		return s, fmt.Errorf("cannot parse number: %w", err)
 	}
 	return tail, nil
 }
 
-func validateArray(s string) (string, error) {
+func (v *validator) validateArray(s string, depth int) (string, error) {
 	s = skipWS(s)
 	if len(s) == 0 {
-		return s, fmt.Errorf("missing ']'")
+		return s, errMissingArrayEnd
 	}
 	if s[0] == ']' {
 		return s[1:], nil
 	}
 
-	for {
+	for n := 0; ; n++ {
 		var err error
 
 		s = skipWS(s)
-		s, err = validateValue(s)
+		if err = v.l.checkItems(n + 1); err != nil {
+			return s, err
+		}
+		s, err = v.validateValue(s, depth)
 		if err != nil {
-			return s, fmt.Errorf("cannot parse array value: %s", err)
+			v.path.pushIndex(n)
+			return s, err
 		}
 
 		s = skipWS(s)
 		if len(s) == 0 {
-			return s, fmt.Errorf("unexpected end of array")
+			return s, errArrayEnd
 		}
 		if s[0] == ',' {
 			s = s[1:]
@@ -114,63 +147,81 @@ func validateArray(s string) (string, error) {
 			s = s[1:]
 			return s, nil
 		}
-		return s, fmt.Errorf("missing ',' after array value")
+		return s, errMissingArrayComma
 	}
 }
 
-func validateObject(s string) (string, error) {
+func (v *validator) validateObject(s string, depth int) (string, error) {
 	s = skipWS(s)
 	if len(s) == 0 {
-		return s, fmt.Errorf("missing '}'")
+		return s, errMissingObjectEnd
 	}
 	if s[0] == '}' {
 		return s[1:], nil
 	}
 
-	for {
+	start := len(v.l.dk.keys)
+	for n := 0; ; n++ {
 		var err error
+		if err = v.l.checkMembers(n + 1); err != nil {
+			return s, err
+		}
 
 		// Parse key.
 		s = skipWS(s)
 		if len(s) == 0 || s[0] != '"' {
-			return s, fmt.Errorf(`cannot find opening '"" for object key`)
+			return s, errMissingKeyQuote
 		}
 
+		ks := s
 		var key string
 		key, s, err = validateKey(s[1:])
 		if err != nil {
-			return s, fmt.Errorf("cannot parse object key: %s", err)
+			if len(s) > 0 {
+				// Point to the start of the key with invalid escape sequences.
+				s = ks
+			}
+			return s, fmt.Errorf("cannot parse object key: %w", err)
 		}
+		rk := ks[1 : len(ks)-len(s)-1]
+		if err = v.l.checkString(rk); err != nil {
+			return ks, fmt.Errorf("cannot parse object key: %w", err)
+		}
+		v.l.pushKey(rk)
 		// Scan the key for control chars.
 		for i := 0; i < len(key); i++ {
 			if key[i] < 0x20 {
-				return s, fmt.Errorf("object key cannot contain control char 0x%02X", key[i])
+				return s, errorf(ErrorKindBadString, "object key cannot contain control char 0x%02X", key[i])
 			}
 		}
 		s = skipWS(s)
 		if len(s) == 0 || s[0] != ':' {
-			return s, fmt.Errorf("missing ':' after object key")
+			return s, errMissingColon
 		}
 		s = s[1:]
 
 		// Parse value
 		s = skipWS(s)
-		s, err = validateValue(s)
+		s, err = v.validateValue(s, depth)
 		if err != nil {
-			return s, fmt.Errorf("cannot parse object value: %s", err)
+			v.path.pushRawKey(rk)
+			return s, err
 		}
 		s = skipWS(s)
 		if len(s) == 0 {
-			return s, fmt.Errorf("unexpected end of object")
+			return s, errObjectEnd
 		}
 		if s[0] == ',' {
 			s = s[1:]
 			continue
 		}
 		if s[0] == '}' {
+			if err = v.l.checkKeys(start); err != nil {
+				return s, err
+			}
 			return s[1:], nil
 		}
-		return s, fmt.Errorf("missing ',' after object value")
+		return s, errMissingObjectComma
 	}
 }
 
@@ -187,7 +238,7 @@ func validateKey(s string) (string, string, error) {
 			return validateString(s)
 		}
 	}
-	return "", s, fmt.Errorf(`missing closing '"'`)
+	return "", "", errMissingQuote
 }
 
 func validateString(s string) (string, string, error) {
@@ -218,28 +269,31 @@ func validateString(s string) (string, string, error) {
 			continue
 		case 'u':
 			if len(rs) < 4 {
-				return rs, tail, fmt.Errorf(`too short escape sequence: \u%s`, rs)
+				return rs, tail, errorf(ErrorKindBadEscape, `too short escape sequence: \u%s`, rs)
 			}
 			xs := rs[:4]
 			_, err := strconv.ParseUint(xs, 16, 16)
 			if err != nil {
-				return rs, tail, fmt.Errorf(`invalid escape sequence \u%s: %s`, xs, err)
+				return rs, tail, errorf(ErrorKindBadEscape, `invalid escape sequence \u%s: %s`, xs, err)
 			}
 			rs = rs[4:]
 		default:
-			return rs, tail, fmt.Errorf(`unknown escape sequence \%c`, ch)
+			return rs, tail, errorf(ErrorKindBadEscape, `unknown escape sequence \%c`, ch)
 		}
 	}
 }
 
 func validateNumber(s string) (string, error) {
 	if len(s) == 0 {
-		return s, fmt.Errorf("zero-length number")
+		return s, errorf(ErrorKindBadNumber, "zero-length number")
 	}
+	// Input without the leading digit or minus isn't a number at all.
+	kind := ErrorKindSyntax
 	if s[0] == '-' {
+		kind = ErrorKindBadNumber
 		s = s[1:]
 		if len(s) == 0 {
-			return s, fmt.Errorf("missing number after minus")
+			return s, errorf(ErrorKindBadNumber, "missing number after minus")
 		}
 	}
 	i := 0
@@ -250,10 +304,10 @@ func validateNumber(s string) (string, error) {
 		i++
 	}
 	if i <= 0 {
-		return s, fmt.Errorf("expecting 0..9 digit, got %c", s[0])
+		return s, errorf(kind, "expecting 0..9 digit, got %c", s[0])
 	}
 	if s[0] == '0' && i != 1 {
-		return s, fmt.Errorf("unexpected number starting from 0")
+		return s, errorf(ErrorKindBadNumber, "unexpected number starting from 0")
 	}
 	if i >= len(s) {
 		return "", nil
@@ -262,7 +316,7 @@ func validateNumber(s string) (string, error) {
 		// Validate fractional part
 		s = s[i+1:]
 		if len(s) == 0 {
-			return s, fmt.Errorf("missing fractional part")
+			return s, errorf(ErrorKindBadNumber, "missing fractional part")
 		}
 		i = 0
 		for i < len(s) {
@@ -272,7 +326,7 @@ func validateNumber(s string) (string, error) {
 			i++
 		}
 		if i == 0 {
-			return s, fmt.Errorf("expecting 0..9 digit in fractional part, got %c", s[0])
+			return s, errorf(ErrorKindBadNumber, "expecting 0..9 digit in fractional part, got %c", s[0])
 		}
 		if i >= len(s) {
 			return "", nil
@@ -282,12 +336,12 @@ func validateNumber(s string) (string, error) {
 		// Validate exponent part
 		s = s[i+1:]
 		if len(s) == 0 {
-			return s, fmt.Errorf("missing exponent part")
+			return s, errorf(ErrorKindBadNumber, "missing exponent part")
 		}
 		if s[0] == '-' || s[0] == '+' {
 			s = s[1:]
 			if len(s) == 0 {
-				return s, fmt.Errorf("missing exponent part")
+				return s, errorf(ErrorKindBadNumber, "missing exponent part")
 			}
 		}
 		i = 0
@@ -298,7 +352,7 @@ func validateNumber(s string) (string, error) {
 			i++
 		}
 		if i == 0 {
-			return s, fmt.Errorf("expecting 0..9 digit in exponent part, got %c", s[0])
+			return s, errorf(ErrorKindBadNumber, "expecting 0..9 digit in exponent part, got %c", s[0])
 		}
 		if i >= len(s) {
 			return "", nil
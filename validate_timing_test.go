@@ -34,6 +34,9 @@ func benchmarkValidate(b *testing.B, s string) {
 	b.Run("fastjson", func(b *testing.B) {
 		benchmarkValidateFastJSON(b, s)
 	})
+	b.Run("fastjson-indexed", func(b *testing.B) {
+		benchmarkValidateFastJSONIndexed(b, s)
+	})
 }
 
 func benchmarkValidateStdJSON(b *testing.B, s string) {
@@ -60,3 +63,15 @@ func benchmarkValidateFastJSON(b *testing.B, s string) {
 		}
 	})
 }
+
+func benchmarkValidateFastJSONIndexed(b *testing.B, s string) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(s)))
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := ValidateIndexed(s); err != nil {
+				panic(fmt.Errorf("unexpected error: %s", err))
+			}
+		}
+	})
+}
@@ -56,11 +56,13 @@ func FalseValue(a arena.Arena) *Value {
 func ObjectValue(a arena.Arena) *Value {
 	v := arena.Allocate[Value](a)
 	v.t = TypeObject
+	v.x = arena.Allocate[node](a)
 	return v
 }
 
 func ArrayValue(a arena.Arena) *Value {
 	v := arena.Allocate[Value](a)
 	v.t = TypeArray
+	v.x = arena.Allocate[node](a)
 	return v
 }